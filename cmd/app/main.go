@@ -1,7 +1,17 @@
 package main
 
-import "todolist_api/internal/app"
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	// база часовых поясов внутри бинарника: в образе alpine нет tzdata, а сводки считаются в поясе пользователя
+	_ "time/tzdata"
+	"todolist_api/internal/app"
+)
 
 func main() {
-	app.Run()
+	if err := app.Execute(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Fatal(err)
+	}
 }
@@ -2,43 +2,214 @@ package config
 
 import (
 	"fmt"
-	"github.com/ilyakaznacheev/cleanenv"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Config параметры приложения. Параметры с тегом reload применяются без перезапуска: по SIGHUP
+// или POST /api/v1/admin/config/reload, остальные - только при старте
 type Config struct {
-	HTTP   HTTP
-	Log    Log
-	PG     PG
-	JWT    JWT
-	Hasher Hasher
+	HTTP      HTTP      `yaml:"http"`
+	Log       Log       `yaml:"log"`
+	PG        PG        `yaml:"pg"`
+	JWT       JWT       `yaml:"jwt"`
+	Hasher    Hasher    `yaml:"hasher"`
+	SMTP      SMTP      `yaml:"smtp"`
+	Webhook   Webhook   `yaml:"webhook"`
+	Reminder  Reminder  `yaml:"reminder"`
+	Digest    Digest    `yaml:"digest"`
+	Export    Export    `yaml:"export"`
+	Account   Account   `yaml:"account"`
+	Lockout   Lockout   `yaml:"lockout"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Metrics   Metrics   `yaml:"metrics"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Health    Health    `yaml:"health"`
+	OIDC      OIDC      `yaml:"oidc"`
+	Features  Features  `yaml:"features"`
 }
 
 type (
+	// HTTP TrustProxy - приложение работает за прокси, IP клиента берется из X-Forwarded-For.
+	// Без прокси заголовок можно подделать, поэтому по умолчанию IP берется из соединения.
+	// ShutdownTimeout - сколько при остановке ждать завершения начатых запросов.
+	// CORSOrigins - источники браузерных клиентов (https://todo.example.com или *), пустой список выключает CORS
 	HTTP struct {
-		Port string `env-required:"true" env:"HTTP_PORT"`
+		Port            string        `env-required:"true" env:"HTTP_PORT" yaml:"port"`
+		TrustProxy      bool          `env:"HTTP_TRUST_PROXY" env-default:"false" yaml:"trust_proxy"`
+		ReadTimeout     time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"5s" yaml:"read_timeout"`
+		WriteTimeout    time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"5s" yaml:"write_timeout"`
+		ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" env-default:"3s" yaml:"shutdown_timeout"`
+		CORSOrigins     []string      `env:"HTTP_CORS_ORIGINS" env-separator:"," yaml:"cors_origins" reload:"true"`
 	}
 	Log struct {
-		Level  string `env-required:"true" env:"LOG_LEVEL"`
-		Output string `env-required:"true" env:"LOG_OUTPUT"`
+		Level  string `env-required:"true" env:"LOG_LEVEL" yaml:"level" reload:"true"`
+		Output string `env-required:"true" env:"LOG_OUTPUT" yaml:"output" reload:"true"`
 	}
+	// PG ConnAttempts - сколько раз при старте пытаться подключиться к базе, ConnTimeout - пауза между попытками
 	PG struct {
-		MaxPoolSize int    `env-required:"true" env:"PG_MAX_POOL_SIZE"`
-		Url         string `env-required:"true" env:"PG_URL"`
+		MaxPoolSize  int           `env-required:"true" env:"PG_MAX_POOL_SIZE" yaml:"max_pool_size"`
+		Url          string        `env-required:"true" env:"PG_URL" yaml:"url" secret:"url"`
+		ConnAttempts int           `env:"PG_CONN_ATTEMPTS" env-default:"10" yaml:"conn_attempts"`
+		ConnTimeout  time.Duration `env:"PG_CONN_TIMEOUT" env-default:"2s" yaml:"conn_timeout"`
 	}
+	// JWT PrivateKeyFile - PEM файл активного ключа RSA или Ed25519 для подписи токенов доступа, без него токены
+	// подписываются HS256 ключом SignKey. RetiredKeyFiles - PEM файлы прежних ключей, которыми токены только проверяются.
+	// AcceptLegacy разрешает принимать токены HS256, выданные до перехода на асимметричный ключ (до запуска с PrivateKeyFile)
 	JWT struct {
-		SignKey  string        `env-required:"true" env:"JWT_SIGN_KEY"`
-		TokenTTL time.Duration `env-required:"true" env:"TOKEN_TTL"`
+		SignKey         string        `env-required:"true" env:"JWT_SIGN_KEY" yaml:"sign_key" secret:"true"`
+		TokenTTL        time.Duration `env-required:"true" env:"TOKEN_TTL" yaml:"token_ttl"`
+		PrivateKeyFile  string        `env:"JWT_PRIVATE_KEY_FILE" yaml:"private_key_file"`
+		RetiredKeyFiles []string      `env:"JWT_RETIRED_KEY_FILES" env-separator:"," yaml:"retired_key_files" reload:"true"`
+		AcceptLegacy    bool          `env:"JWT_ACCEPT_LEGACY" env-default:"false" yaml:"accept_legacy" reload:"true"`
 	}
 	Hasher struct {
-		Secret string `env-required:"true" env:"HASHER_SECRET"`
+		Secret string `env-required:"true" env:"HASHER_SECRET" yaml:"secret" secret:"true"`
+	}
+	// SMTP пустой Host отключает отправку напоминаний, сводок и писем подтверждения email и сброса пароля
+	SMTP struct {
+		Host     string `env:"SMTP_HOST" yaml:"host"`
+		Port     string `env:"SMTP_PORT" env-default:"587" yaml:"port"`
+		Username string `env:"SMTP_USERNAME" yaml:"username"`
+		Password string `env:"SMTP_PASSWORD" yaml:"password" secret:"true"`
+		From     string `env:"SMTP_FROM" yaml:"from"`
+	}
+	Webhook struct {
+		Secret  string        `env:"WEBHOOK_SECRET" yaml:"secret" secret:"true"`
+		Timeout time.Duration `env:"WEBHOOK_TIMEOUT" env-default:"5s" yaml:"timeout"`
+	}
+	Reminder struct {
+		Interval time.Duration `env:"REMINDER_INTERVAL" env-default:"30s" yaml:"interval"`
+	}
+	Digest struct {
+		Interval time.Duration `env:"DIGEST_INTERVAL" env-default:"1m" yaml:"interval"`
+	}
+	// Export TTL - сколько готовый архив с данными пользователя доступен для скачивания
+	// Export LinkKey подписывает ссылки на скачивание выгрузок, отдельно от ключа токенов
+	Export struct {
+		Interval time.Duration `env:"EXPORT_INTERVAL" env-default:"30s" yaml:"interval"`
+		TTL      time.Duration `env:"EXPORT_TTL" env-default:"24h" yaml:"ttl"`
+		LinkKey  string        `env-required:"true" env:"EXPORT_LINK_KEY" yaml:"link_key" secret:"true"`
+	}
+	// Account DeletionGrace - через сколько после запроса аккаунт удаляется окончательно.
+	// AppURL - адрес клиента для ссылок подтверждения email и сброса пароля в письмах (письма отправляются через SMTP)
+	Account struct {
+		DeletionGrace  time.Duration `env:"ACCOUNT_DELETION_GRACE" env-default:"168h" yaml:"deletion_grace"`
+		Interval       time.Duration `env:"ACCOUNT_ERASE_INTERVAL" env-default:"10m" yaml:"interval"`
+		AppURL         string        `env:"ACCOUNT_APP_URL" env-default:"http://localhost:8080" yaml:"app_url"`
+		VerifyTokenTTL time.Duration `env:"ACCOUNT_VERIFY_TOKEN_TTL" env-default:"48h" yaml:"verify_token_ttl"`
+		ResetTokenTTL  time.Duration `env:"ACCOUNT_RESET_TOKEN_TTL" env-default:"1h" yaml:"reset_token_ttl"`
+		// TOTPIssuer название сервиса, под которым аккаунт показывается в приложении-аутентификаторе
+		TOTPIssuer string `env:"ACCOUNT_TOTP_ISSUER" env-default:"todolist" yaml:"totp_issuer"`
+		// UsernameMinLength, UsernameMaxLength допустимая длина имени пользователя при регистрации и смене имени
+		UsernameMinLength int `env:"ACCOUNT_USERNAME_MIN_LENGTH" env-default:"3" yaml:"username_min_length"`
+		UsernameMaxLength int `env:"ACCOUNT_USERNAME_MAX_LENGTH" env-default:"32" yaml:"username_max_length"`
+	}
+	// Lockout после MaxAttempts неудачных входов подряд под одним именем (IPMaxAttempts с одного IP) вход блокируется
+	// на Base, каждая следующая неудача удваивает блокировку до Max. Неудачи считаются за Window, история входов хранится HistoryTTL
+	Lockout struct {
+		MaxAttempts   int           `env:"LOCKOUT_MAX_ATTEMPTS" env-default:"5" yaml:"max_attempts"`
+		IPMaxAttempts int           `env:"LOCKOUT_IP_MAX_ATTEMPTS" env-default:"30" yaml:"ip_max_attempts"`
+		Base          time.Duration `env:"LOCKOUT_BASE" env-default:"1m" yaml:"base"`
+		Max           time.Duration `env:"LOCKOUT_MAX" env-default:"1h" yaml:"max"`
+		Window        time.Duration `env:"LOCKOUT_WINDOW" env-default:"24h" yaml:"window"`
+		HistoryTTL    time.Duration `env:"LOCKOUT_HISTORY_TTL" env-default:"720h" yaml:"history_ttl"`
+	}
+	// RateLimit ограничения частоты запросов вида "<запросов>/<период>", пустое значение снимает ограничение с группы.
+	// Auth - вход и регистрация (по IP), API - /api/v1 (по пользователю), Public - скачивание выгрузок и ключи (по IP).
+	// Store - memory (в памяти процесса) или postgres (общие для всех реплик), CleanupInterval - как часто из базы удаляются старые корзины
+	RateLimit struct {
+		Store           string        `env:"RATE_LIMIT_STORE" env-default:"memory" yaml:"store"`
+		Auth            string        `env:"RATE_LIMIT_AUTH" env-default:"20/1m" yaml:"auth" reload:"true"`
+		API             string        `env:"RATE_LIMIT_API" env-default:"600/1m" yaml:"api" reload:"true"`
+		Public          string        `env:"RATE_LIMIT_PUBLIC" env-default:"60/1m" yaml:"public" reload:"true"`
+		CleanupInterval time.Duration `env:"RATE_LIMIT_CLEANUP_INTERVAL" env-default:"10m" yaml:"cleanup_interval"`
+	}
+	// Metrics Port - порт отдельного listener'а с /metrics в формате Prometheus, пустое значение отключает метрики.
+	// Метрики не отдаются на основном порту, чтобы их не было видно снаружи
+	Metrics struct {
+		Port string `env:"METRICS_PORT" env-default:"9090" yaml:"port"`
+	}
+	// Tracing Exporter - куда отправлять spans: none, stdout или otlp. OTLPEndpoint - адрес коллектора OTLP/HTTP (host:port),
+	// OTLPInsecure - без TLS. SampleRatio - доля трассируемых запросов без входящего traceparent
+	Tracing struct {
+		Exporter     string  `env:"TRACING_EXPORTER" env-default:"none" yaml:"exporter"`
+		OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" env-default:"localhost:4318" yaml:"otlp_endpoint"`
+		OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" env-default:"true" yaml:"otlp_insecure"`
+		ServiceName  string  `env:"TRACING_SERVICE_NAME" env-default:"todolist_api" yaml:"service_name"`
+		SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1" yaml:"sample_ratio"`
+	}
+	// Health Timeout - сколько ждать все проверки /readyz. ShutdownDelay - сколько /readyz отвечает 503 перед остановкой
+	// http сервера, чтобы балансировщик успел перестать слать запросы
+	Health struct {
+		Timeout       time.Duration `env:"HEALTH_TIMEOUT" env-default:"2s" yaml:"timeout"`
+		ShutdownDelay time.Duration `env:"HEALTH_SHUTDOWN_DELAY" env-default:"5s" yaml:"shutdown_delay"`
+	}
+	// OIDC ProviderNames - имена провайдеров OpenID Connect через запятую, настройки каждого провайдера
+	// задаются переменными OIDC_<ИМЯ>_*, например OIDC_CORP_ISSUER. LoginTTL - сколько действует начатый вход
+	OIDC struct {
+		ProviderNames []string       `env:"OIDC_PROVIDERS" env-separator:"," yaml:"provider_names"`
+		LoginTTL      time.Duration  `env:"OIDC_LOGIN_TTL" env-default:"10m" yaml:"login_ttl"`
+		Providers     []OIDCProvider `yaml:"providers"`
+	}
+	// Features включенные флаги функциональности, список отдается клиентам на GET /features
+	Features struct {
+		Enabled []string `env:"FEATURES" env-separator:"," yaml:"enabled" reload:"true"`
+	}
+	// OIDCProvider RedirectURL - адрес /auth/oidc/<имя>/callback, зарегистрированный у провайдера.
+	// AutoProvision разрешает создавать пользователя при первом входе через провайдера
+	OIDCProvider struct {
+		Name          string   `yaml:"name"`
+		Issuer        string   `yaml:"issuer"`
+		ClientID      string   `yaml:"client_id"`
+		ClientSecret  string   `yaml:"client_secret" secret:"true"`
+		RedirectURL   string   `yaml:"redirect_url"`
+		Scopes        []string `yaml:"scopes"`
+		AutoProvision bool     `yaml:"auto_provision"`
 	}
 )
 
-func NewConfig() (*Config, error) {
-	c := &Config{}
-	if err := cleanenv.ReadEnv(c); err != nil {
-		return nil, fmt.Errorf("error reading config env: %w", err)
+// readProviders читает настройки провайдеров из OIDC_PROVIDERS. Имя провайдера в названии переменных
+// записывается в верхнем регистре, дефисы заменяются подчеркиваниями
+func (o *OIDC) readProviders(lookup func(string) (string, bool)) error {
+	o.Providers = nil
+	for _, name := range o.ProviderNames {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		get := func(key string) string {
+			v, _ := lookup(prefix + key)
+			return v
+		}
+		p := OIDCProvider{
+			Name:         name,
+			Issuer:       get("ISSUER"),
+			ClientID:     get("CLIENT_ID"),
+			ClientSecret: get("CLIENT_SECRET"),
+			RedirectURL:  get("REDIRECT_URL"),
+			Scopes:       []string{"email", "profile"},
+		}
+		for _, v := range []struct{ key, value string }{
+			{"ISSUER", p.Issuer}, {"CLIENT_ID", p.ClientID}, {"REDIRECT_URL", p.RedirectURL},
+		} {
+			if v.value == "" {
+				return fmt.Errorf("field %s%s is required for oidc provider %s", prefix, v.key, name)
+			}
+		}
+		if scopes, ok := lookup(prefix + "SCOPES"); ok {
+			p.Scopes = strings.Split(scopes, ",")
+		}
+		if v, ok := lookup(prefix + "AUTO_PROVISION"); ok {
+			autoProvision, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("field %sAUTO_PROVISION must be a boolean: %w", prefix, err)
+			}
+			p.AutoProvision = autoProvision
+		}
+		o.Providers = append(o.Providers, p)
 	}
-	return c, nil
+	return nil
 }
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change изменение параметра при перечитывании конфигурации. Значения секретов скрыты
+type Change struct {
+	Key string `json:"key" example:"LOG_LEVEL"`
+	Old string `json:"old" example:"info"`
+	New string `json:"new" example:"debug"`
+}
+
+// Diff изменения параметров от old к new. reloadable можно применить без перезапуска, restart - нет
+func Diff(old, new *Config) (reloadable, restart []Change) {
+	// пустые, а не nil списки, чтобы в ответе API были [], а не null
+	reloadable, restart = []Change{}, []Change{}
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for _, f := range fields() {
+		a, b := oldValue.FieldByIndex(f.index), newValue.FieldByIndex(f.index)
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			continue
+		}
+		change := Change{Key: f.env, Old: f.format(a), New: f.format(b)}
+		if f.reload {
+			reloadable = append(reloadable, change)
+		} else {
+			restart = append(restart, change)
+		}
+	}
+	return reloadable, restart
+}
+
+// Reloaded копия c, в которой параметры с тегом reload взяты из next: конфигурация, действующая после перечитывания
+func (c *Config) Reloaded(next *Config) *Config {
+	result := *c
+	value, nextValue := reflect.ValueOf(&result).Elem(), reflect.ValueOf(next).Elem()
+	for _, f := range fields() {
+		if f.reload {
+			value.FieldByIndex(f.index).Set(nextValue.FieldByIndex(f.index))
+		}
+	}
+	return &result
+}
+
+func (f field) format(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		separator := f.separator
+		if separator == "" {
+			separator = ","
+		}
+		return strings.Join(v.Interface().([]string), separator)
+	}
+	return valueNode(v, f.secret).Value
+}
+
+// ReloadResult итог перечитывания конфигурации: примененные изменения и изменения, которые вступят в силу после перезапуска
+type ReloadResult struct {
+	Applied         []Change `json:"applied"`
+	RestartRequired []Change `json:"restart_required"`
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEnvFile .env в рабочем каталоге, читается, если существует
+const DefaultEnvFile = ".env"
+
+// Options источники конфигурации. Каждый параметр берется из первого источника, где он задан:
+// Flags, переменные окружения, EnvFile, File (YAML), значение по умолчанию из тега env-default
+type Options struct {
+	File    string
+	EnvFile string
+	// Flags значения из командной строки по имени переменной окружения
+	Flags map[string]string
+}
+
+func NewOptions() *Options {
+	return &Options{
+		File:    os.Getenv("CONFIG_FILE"),
+		EnvFile: DefaultEnvFile,
+		Flags:   make(map[string]string),
+	}
+}
+
+// BindFlags регистрирует в fs флаги -config, -env-file и по флагу на каждый параметр: имя переменной окружения
+// в нижнем регистре через дефис, например -http-port или -log-level. Несколько наборов флагов можно связать с одними Options
+func BindFlags(fs *flag.FlagSet, o *Options) {
+	fs.StringVar(&o.File, "config", o.File, "path to YAML config file, CONFIG_FILE by default")
+	fs.StringVar(&o.EnvFile, "env-file", o.EnvFile, "path to .env file, the default one is skipped if missing")
+	for _, f := range fields() {
+		env := f.env
+		usage := env
+		if f.def != nil {
+			usage += fmt.Sprintf(" (default %q)", *f.def)
+		}
+		fs.Func(flagName(env), usage, func(s string) error {
+			o.Flags[env] = s
+			return nil
+		})
+	}
+}
+
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// Load собирает конфигурацию из источников и проверяет ее. Ошибки всех параметров возвращаются вместе
+func Load(o *Options) (*Config, error) {
+	file := map[string]string{}
+	if o.File != "" {
+		var err error
+		if file, err = readFile(o.File); err != nil {
+			return nil, err
+		}
+	}
+	dotenv := map[string]string{}
+	if o.EnvFile != "" {
+		var err error
+		dotenv, err = godotenv.Read(o.EnvFile)
+		if err != nil && !(errors.Is(err, fs.ErrNotExist) && o.EnvFile == DefaultEnvFile) {
+			return nil, fmt.Errorf("read env file %s: %w", o.EnvFile, err)
+		}
+	}
+	lookup := func(name string) (string, bool) {
+		if v, ok := o.Flags[name]; ok {
+			return v, true
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		if v, ok := dotenv[name]; ok {
+			return v, true
+		}
+		v, ok := file[name]
+		return v, ok
+	}
+
+	c := &Config{}
+	value := reflect.ValueOf(c).Elem()
+	var errs []error
+	for _, f := range fields() {
+		raw, ok := lookup(f.env)
+		if f.required && (!ok || raw == "") {
+			errs = append(errs, fmt.Errorf("%s is required", f))
+			continue
+		}
+		if !ok {
+			if f.def == nil {
+				continue
+			}
+			raw = *f.def
+		}
+		if err := setValue(value.FieldByIndex(f.index), raw, f.separator); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := c.OIDC.readProviders(lookup); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// field параметр конфигурации: поле секции Config с тегом env
+type field struct {
+	index     []int
+	path      string // секция.ключ в YAML файле
+	env       string
+	def       *string
+	required  bool
+	separator string
+	secret    string
+	// reload параметр можно применить без перезапуска
+	reload bool
+}
+
+func (f field) String() string {
+	return fmt.Sprintf("%s (%s)", f.env, f.path)
+}
+
+func fields() []field {
+	var result []field
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		section := t.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			leaf := section.Type.Field(j)
+			env, ok := leaf.Tag.Lookup("env")
+			if !ok {
+				continue
+			}
+			f := field{
+				index:     []int{i, j},
+				path:      section.Tag.Get("yaml") + "." + leaf.Tag.Get("yaml"),
+				env:       env,
+				separator: leaf.Tag.Get("env-separator"),
+				secret:    leaf.Tag.Get("secret"),
+			}
+			if def, ok := leaf.Tag.Lookup("env-default"); ok {
+				f.def = &def
+			}
+			_, f.required = leaf.Tag.Lookup("env-required")
+			f.reload = leaf.Tag.Get("reload") == "true"
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw, separator string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected e.g. 30s, 5m or 1h30m", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, expected true or false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if separator == "" {
+			separator = ","
+		}
+		var items []string
+		for _, item := range strings.Split(raw, separator) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// readFile читает YAML файл вида секция: {ключ: значение} и возвращает значения по именам переменных окружения.
+// Неизвестные ключи считаются ошибкой, чтобы опечатка не оставляла параметр со значением по умолчанию
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var sections map[string]map[string]any
+	if err = yaml.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	byPath := make(map[string]field)
+	for _, f := range fields() {
+		byPath[f.path] = f
+	}
+	values := make(map[string]string)
+	var unknown []string
+	for section, keys := range sections {
+		for key, v := range keys {
+			f, ok := byPath[section+"."+key]
+			if !ok {
+				unknown = append(unknown, section+"."+key)
+				continue
+			}
+			values[f.env] = yamlString(v, f.separator)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+func yamlString(v any, separator string) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []any:
+		if separator == "" {
+			separator = ","
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, separator)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+// Print выводит действующую конфигурацию в YAML. Значения полей с тегом secret скрываются,
+// у адреса базы (secret:"url") скрывается только пароль
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(structNode(reflect.ValueOf(*c))); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func structNode(v reflect.Value) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("yaml")
+		if key == "" || key == "-" {
+			continue
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			valueNode(v.Field(i), f.Tag.Get("secret")),
+		)
+	}
+	return node
+}
+
+func valueNode(v reflect.Value, secret string) *yaml.Node {
+	if v.Type() == durationType {
+		return scalar(time.Duration(v.Int()).String(), "!!str")
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structNode(v)
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < v.Len(); i++ {
+			item := valueNode(v.Index(i), "")
+			if item.Kind == yaml.MappingNode {
+				node.Style = 0
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node
+	case reflect.Bool:
+		return scalar(strconv.FormatBool(v.Bool()), "!!bool")
+	case reflect.Int:
+		return scalar(strconv.FormatInt(v.Int(), 10), "!!int")
+	case reflect.Float64:
+		return scalar(strconv.FormatFloat(v.Float(), 'g', -1, 64), "!!float")
+	default:
+		return scalar(redact(v.String(), secret), "!!str")
+	}
+}
+
+func scalar(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func redact(value, secret string) string {
+	if value == "" || secret == "" {
+		return value
+	}
+	if secret == "url" {
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+	"todolist_api/pkg/ratelimit"
+	"todolist_api/pkg/tracing"
+)
+
+var featureNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// maxUsernameLength ограничение сверху для ACCOUNT_USERNAME_MAX_LENGTH, чтобы имя помещалось в письма и логи
+const maxUsernameLength = 255
+
+// Validate проверяет значения параметров и связи между ними, возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	v := &validation{}
+
+	v.port("HTTP_PORT", c.HTTP.Port, false)
+	for _, origin := range c.HTTP.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			v.add("HTTP_CORS_ORIGINS must contain * or origins like https://todo.example.com, got %q", origin)
+		}
+	}
+	v.positive("HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout)
+	v.positive("HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout)
+	v.positive("HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout)
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		v.add("LOG_LEVEL must be one of panic, fatal, error, warn, info, debug, trace, got %q", c.Log.Level)
+	}
+
+	v.atLeast("PG_MAX_POOL_SIZE", c.PG.MaxPoolSize, 1)
+	v.atLeast("PG_CONN_ATTEMPTS", c.PG.ConnAttempts, 1)
+	v.positive("PG_CONN_TIMEOUT", c.PG.ConnTimeout)
+
+	v.between("TOKEN_TTL", c.JWT.TokenTTL, time.Minute, 30*24*time.Hour)
+
+	if c.SMTP.Host != "" {
+		v.port("SMTP_PORT", c.SMTP.Port, false)
+		if c.SMTP.From == "" {
+			v.add("SMTP_FROM is required when SMTP_HOST is set")
+		}
+	}
+
+	v.positive("WEBHOOK_TIMEOUT", c.Webhook.Timeout)
+	v.positive("REMINDER_INTERVAL", c.Reminder.Interval)
+	v.positive("DIGEST_INTERVAL", c.Digest.Interval)
+	v.positive("EXPORT_INTERVAL", c.Export.Interval)
+	v.between("EXPORT_TTL", c.Export.TTL, time.Minute, 30*24*time.Hour)
+	if c.Export.LinkKey == c.JWT.SignKey {
+		v.add("EXPORT_LINK_KEY must differ from JWT_SIGN_KEY")
+	}
+
+	v.between("ACCOUNT_DELETION_GRACE", c.Account.DeletionGrace, 0, 365*24*time.Hour)
+	v.positive("ACCOUNT_ERASE_INTERVAL", c.Account.Interval)
+	if u, err := url.Parse(c.Account.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.add("ACCOUNT_APP_URL must be an absolute url like https://todo.example.com, got %q", c.Account.AppURL)
+	}
+	v.between("ACCOUNT_VERIFY_TOKEN_TTL", c.Account.VerifyTokenTTL, time.Minute, 30*24*time.Hour)
+	v.between("ACCOUNT_RESET_TOKEN_TTL", c.Account.ResetTokenTTL, time.Minute, 24*time.Hour)
+	v.atLeast("ACCOUNT_USERNAME_MIN_LENGTH", c.Account.UsernameMinLength, 1)
+	if c.Account.UsernameMaxLength < c.Account.UsernameMinLength || c.Account.UsernameMaxLength > maxUsernameLength {
+		v.add("ACCOUNT_USERNAME_MAX_LENGTH must be between ACCOUNT_USERNAME_MIN_LENGTH (%d) and %d, got %d",
+			c.Account.UsernameMinLength, maxUsernameLength, c.Account.UsernameMaxLength)
+	}
+
+	v.atLeast("LOCKOUT_MAX_ATTEMPTS", c.Lockout.MaxAttempts, 1)
+	v.atLeast("LOCKOUT_IP_MAX_ATTEMPTS", c.Lockout.IPMaxAttempts, 1)
+	v.positive("LOCKOUT_BASE", c.Lockout.Base)
+	if c.Lockout.Max < c.Lockout.Base {
+		v.add("LOCKOUT_MAX must not be less than LOCKOUT_BASE (%s), got %s", c.Lockout.Base, c.Lockout.Max)
+	}
+	v.positive("LOCKOUT_WINDOW", c.Lockout.Window)
+	if c.Lockout.HistoryTTL < c.Lockout.Window {
+		v.add("LOCKOUT_HISTORY_TTL must not be less than LOCKOUT_WINDOW (%s), got %s", c.Lockout.Window, c.Lockout.HistoryTTL)
+	}
+
+	v.oneOf("RATE_LIMIT_STORE", c.RateLimit.Store, "memory", "postgres")
+	for _, limit := range []struct{ name, value string }{
+		{"RATE_LIMIT_AUTH", c.RateLimit.Auth},
+		{"RATE_LIMIT_API", c.RateLimit.API},
+		{"RATE_LIMIT_PUBLIC", c.RateLimit.Public},
+	} {
+		if _, err := ratelimit.ParseLimit(limit.value); err != nil {
+			v.add("%s: %s", limit.name, err)
+		}
+	}
+	v.positive("RATE_LIMIT_CLEANUP_INTERVAL", c.RateLimit.CleanupInterval)
+
+	v.port("METRICS_PORT", c.Metrics.Port, true)
+	if c.Metrics.Port != "" && c.Metrics.Port == c.HTTP.Port {
+		v.add("METRICS_PORT must differ from HTTP_PORT, got %s", c.Metrics.Port)
+	}
+
+	v.oneOf("TRACING_EXPORTER", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	v.positive("HEALTH_TIMEOUT", c.Health.Timeout)
+	v.between("HEALTH_SHUTDOWN_DELAY", c.Health.ShutdownDelay, 0, time.Minute)
+
+	v.between("OIDC_LOGIN_TTL", c.OIDC.LoginTTL, time.Minute, time.Hour)
+
+	for _, name := range c.Features.Enabled {
+		if !featureNameRe.MatchString(name) {
+			v.add("FEATURES must contain names of lowercase letters, digits, dots, dashes and underscores, got %q", name)
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validation struct {
+	errs []error
+}
+
+func (v *validation) add(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validation) port(name, port string, optional bool) {
+	if port == "" && optional {
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		v.add("%s must be a port number between 1 and 65535, got %q", name, port)
+	}
+}
+
+func (v *validation) positive(name string, d time.Duration) {
+	if d <= 0 {
+		v.add("%s must be positive, got %s", name, d)
+	}
+}
+
+func (v *validation) between(name string, d, min, max time.Duration) {
+	if d < min || d > max {
+		v.add("%s must be between %s and %s, got %s", name, min, max, d)
+	}
+}
+
+func (v *validation) atLeast(name string, n, min int) {
+	if n < min {
+		v.add("%s must be at least %d, got %d", name, min, n)
+	}
+}
+
+func (v *validation) oneOf(name, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.add("%s must be one of %v, got %q", name, allowed, value)
+	}
+}
@@ -0,0 +1,246 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+)
+
+const adminUsersDefaultLimit = 50
+
+type adminRouter struct {
+	admin    service.Admin
+	attempts service.SignIn
+}
+
+func newAdminRouter(g *echo.Group, admin service.Admin, attempts service.SignIn) {
+	r := &adminRouter{
+		admin:    admin,
+		attempts: attempts,
+	}
+	read, manage := requirePermission(service.PermissionUsersRead), requirePermission(service.PermissionUsersManage)
+
+	g.GET("/users", r.findUsers, read)
+	g.GET("/users/:username", r.findUser, read)
+	g.GET("/users/:username/sign-ins", r.signIns, read)
+	g.POST("/users/:username/disable", r.disable, manage)
+	g.POST("/users/:username/enable", r.enable, manage)
+	g.PUT("/users/:username/roles", r.setRoles, manage)
+	g.POST("/users/:username/password-reset", r.passwordReset, manage)
+}
+
+type adminUsersInput struct {
+	Query  string `query:"q" validate:"max=100"`
+	Limit  int    `query:"limit" validate:"min=0,max=100"`
+	Offset int    `query:"offset" validate:"min=0"`
+}
+
+//	@Summary		Find users
+//	@Description	List users ordered by username. q searches username, display name and email. Requires users:read permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	false	"search query"
+//	@Param			limit	query		int		false	"page size, default 50, max 100"
+//	@Param			offset	query		int		false	"page offset"
+//	@Success		200		{array}		service.AdminUserOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users [get]
+func (r *adminRouter) findUsers(c echo.Context) error {
+	var input adminUsersInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+	if input.Limit == 0 {
+		input.Limit = adminUsersDefaultLimit
+	}
+
+	users, err := r.admin.FindUsers(c.Request().Context(), service.AdminUserSearchInput{
+		Query:  input.Query,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	})
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+//	@Summary		Get user
+//	@Description	Get user profile, roles, account state and task counts by status. Requires users:read permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string	true	"username"
+//	@Success		200			{object}	service.AdminUserOutput
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username} [get]
+func (r *adminRouter) findUser(c echo.Context) error {
+	user, err := r.admin.FindUser(c.Request().Context(), c.Param("username"))
+	if err != nil {
+		return r.adminError(c, err)
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+//	@Summary		User sign in history
+//	@Description	Get recent sign in attempts with the username, newest first. Requires users:read permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string	true	"username"
+//	@Param			limit		query		int		false	"number of attempts, default 50, max 100"
+//	@Success		200			{array}		service.SignInAttemptOutput
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username}/sign-ins [get]
+func (r *adminRouter) signIns(c echo.Context) error {
+	return listSignInAttempts(c, r.attempts, c.Param("username"))
+}
+
+//	@Summary		Disable user
+//	@Description	Disable account: the user cannot sign in and all their tokens are rejected until the account is enabled.
+//	@Description	Requires users:manage permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string	true	"username"
+//	@Success		200			{object}	service.AdminUserOutput
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username}/disable [post]
+func (r *adminRouter) disable(c echo.Context) error {
+	return r.setDisabled(c, true)
+}
+
+//	@Summary		Enable user
+//	@Description	Enable previously disabled account. Requires users:manage permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string	true	"username"
+//	@Success		200			{object}	service.AdminUserOutput
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username}/enable [post]
+func (r *adminRouter) enable(c echo.Context) error {
+	return r.setDisabled(c, false)
+}
+
+func (r *adminRouter) setDisabled(c echo.Context, disabled bool) error {
+	actor, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	user, err := r.admin.SetDisabled(c.Request().Context(), actor, c.Param("username"), disabled)
+	if err != nil {
+		return r.adminError(c, err)
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+type adminRolesInput struct {
+	Roles []string `json:"roles" validate:"required"`
+}
+
+//	@Summary		Set user roles
+//	@Description	Replace user roles (user, admin). Tokens issued to the user are revoked so that new roles apply immediately.
+//	@Description	Requires users:manage permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string			true	"username"
+//	@Param			input		body		adminRolesInput	true	"input"
+//	@Success		200			{object}	service.AdminUserOutput
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username}/roles [put]
+func (r *adminRouter) setRoles(c echo.Context) error {
+	var input adminRolesInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	actor, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	user, err := r.admin.SetRoles(c.Request().Context(), service.AdminRolesInput{
+		Actor:    actor,
+		Username: c.Param("username"),
+		Roles:    input.Roles,
+	})
+	if err != nil {
+		return r.adminError(c, err)
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+//	@Summary		Force password reset
+//	@Description	Replace user password with a random one and revoke all their tokens, including personal access tokens. If the user email is verified,
+//	@Description	a password reset link is sent to it. Requires users:manage permission
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string	true	"username"
+//	@Success		200			{object}	service.AdminPasswordResetOutput
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/users/{username}/password-reset [post]
+func (r *adminRouter) passwordReset(c echo.Context) error {
+	output, err := r.admin.ForcePasswordReset(c.Request().Context(), c.Param("username"))
+	if err != nil {
+		return r.adminError(c, err)
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+func (r *adminRouter) adminError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrUserNotFound) {
+		errorResponse(c, http.StatusNotFound, err)
+		return nil
+	}
+	if errors.Is(err, service.ErrUnknownRole) || errors.Is(err, service.ErrAdminSelf) {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+	errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+	return err
+}
@@ -8,26 +8,36 @@ import (
 )
 
 type authRouter struct {
-	auth service.Auth
-	user service.User
+	auth      service.Auth
+	user      service.User
+	twoFactor service.TwoFactor
+	attempts  service.SignIn
 }
 
-func newAuthRouter(g *echo.Group, auth service.Auth, user service.User) {
+func newAuthRouter(g *echo.Group, auth service.Auth, user service.User, twoFactor service.TwoFactor, attempts service.SignIn) {
 	r := &authRouter{
-		auth: auth,
-		user: user,
+		auth:      auth,
+		user:      user,
+		twoFactor: twoFactor,
+		attempts:  attempts,
 	}
 	g.POST("/sign-up", r.signUp)
 	g.POST("/sign-in", r.signIn)
+	g.POST("/sign-in/2fa", r.signInTwoFactor)
+	g.POST("/verify-email", r.verifyEmail)
+	g.POST("/forgot-password", r.forgotPassword)
+	g.POST("/reset-password", r.resetPassword)
 }
 
 type signUpInput struct {
 	Username string `json:"username" validate:"username,required"`
 	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"omitempty,email"`
 }
 
 // @Summary		Sign up
-// @Description	Create account
+// @Description	Create account. If email is set, a verification link is sent to it.
+// @Description	If another account has already verified the email, the account is created without email and the owner of the email is notified
 // @Tags			auth
 // @Accept			json
 // @Produce		json
@@ -51,6 +61,7 @@ func (r *authRouter) signUp(c echo.Context) error {
 	err := r.user.Create(c.Request().Context(), service.UserInput{
 		Username: input.Username,
 		Password: input.Password,
+		Email:    input.Email,
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrUserAlreadyExists) {
@@ -68,18 +79,26 @@ type signInInput struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// signInResponse если у пользователя включена двухфакторная аутентификация, вместо Token возвращается MFAToken,
+// который вместе с кодом второго фактора обменивается на токен в /auth/sign-in/2fa
 type signInResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
 // @Summary		Sign in
-// @Description	Sign in to account for getting token
+// @Description	Sign in to account for getting token. If two-factor authentication is enabled, mfa_token is returned instead of token,
+// @Description	exchange it together with a code at /auth/sign-in/2fa. Unknown username and wrong password get the same error.
+// @Description	After several failed attempts sign in is blocked for a while, the response is 429 with Retry-After header
 // @Tags			auth
 // @Accept			json
 // @Produce		json
 // @Param			input	body		signInInput	true	"input"
 // @Success		200		{object}	signInResponse
 // @Failure		400		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		429		{object}	echo.HTTPError
 // @Failure		500		{object}	echo.HTTPError
 // @Router			/auth/sign-in [post]
 func (r *authRouter) signIn(c echo.Context) error {
@@ -94,28 +113,266 @@ func (r *authRouter) signIn(c echo.Context) error {
 		return nil
 	}
 
+	attemptId, locked, err := signInLocked(c, r.attempts, input.Username, service.SignInMethodPassword)
+	if locked {
+		return err
+	}
+
 	ok, err := r.user.VerifyPassword(c.Request().Context(), service.UserInput{
 		Username: input.Username,
 		Password: input.Password,
 	})
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			errorResponse(c, http.StatusBadRequest, err)
-			return nil
-		}
+		r.attempts.Cancel(c.Request().Context(), attemptId)
 		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
 		return err
 	}
 	if !ok {
-		errorResponse(c, http.StatusForbidden, echo.ErrForbidden)
+		r.attempts.Record(c.Request().Context(), attemptId, signInAttempt(c, input.Username, service.SignInMethodPassword, false))
+		errorResponse(c, http.StatusForbidden, ErrInvalidCredentials)
+		return nil
+	}
+
+	return signInSuccess(c, r.auth, r.twoFactor, r.attempts, attemptId, input.Username, service.SignInMethodPassword)
+}
+
+// signInLocked резервирует попытку входа под username с IP клиента и отвечает 429 с Retry-After, если вход временно
+// заблокирован после неудачных попыток. Пока исход не передан в Record или Cancel, зарезервированная попытка считается неудачей
+func signInLocked(c echo.Context, attempts service.SignIn, username, method string) (int, bool, error) {
+	id, wait, err := attempts.Check(c.Request().Context(), signInAttempt(c, username, method, false))
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return 0, true, err
+	}
+	if wait > 0 {
+		c.Response().Header().Set(echo.HeaderRetryAfter, ceilSeconds(wait))
+		errorResponse(c, http.StatusTooManyRequests, ErrSignInLocked)
+		return 0, true, nil
+	}
+	return id, false, nil
+}
+
+func signInAttempt(c echo.Context, username, method string, success bool) service.SignInAttemptInput {
+	return service.SignInAttemptInput{
+		Username:  username,
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+		Method:    method,
+		Success:   success,
+	}
+}
+
+// signInSuccess завершает вход пользователя, личность которого подтверждена: выдает токен доступа
+// или, если включена двухфакторная аутентификация, токен для ввода второго фактора.
+// Успешным вход записывается, только когда выдан токен доступа: до этого счетчик неудач не сбрасывается,
+// а зарезервированная попытка attemptId (0 - попытка не резервировалась) снимается
+func signInSuccess(c echo.Context, auth service.Auth, twoFactor service.TwoFactor, attempts service.SignIn, attemptId int, username, method string) error {
+	required, err := twoFactor.Required(c.Request().Context(), username)
+	if err != nil {
+		attempts.Cancel(c.Request().Context(), attemptId)
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	if required {
+		attempts.Cancel(c.Request().Context(), attemptId)
+		mfaToken, err := auth.CreateMFAToken(c.Request().Context(), username)
+		if err != nil {
+			return createTokenError(c, err)
+		}
+		return c.JSON(http.StatusOK, signInResponse{MFARequired: true, MFAToken: mfaToken})
+	}
+
+	token, err := auth.CreateToken(c.Request().Context(), username)
+	if err != nil {
+		attempts.Cancel(c.Request().Context(), attemptId)
+		return createTokenError(c, err)
+	}
+	attempts.Record(c.Request().Context(), attemptId, signInAttempt(c, username, method, true))
+	return c.JSON(http.StatusOK, signInResponse{Token: token})
+}
+
+// createTokenError отвечает на ошибку выдачи токена: отключенному пользователю токен не выдается
+func createTokenError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrUserDisabled) {
+		errorResponse(c, http.StatusForbidden, err)
+		return nil
+	}
+	errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+	return err
+}
+
+type signInTwoFactorInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// @Summary		Sign in with second factor
+// @Description	Exchange mfa_token from sign in and a code from authenticator app (or a recovery code) for token.
+// @Description	Wrong codes count as failed sign in attempts
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body		signInTwoFactorInput	true	"input"
+// @Success		200		{object}	signInResponse
+// @Failure		400		{object}	echo.HTTPError
+// @Failure		401		{object}	echo.HTTPError
+// @Failure		403		{object}	echo.HTTPError
+// @Failure		429		{object}	echo.HTTPError
+// @Failure		500		{object}	echo.HTTPError
+// @Router			/auth/sign-in/2fa [post]
+func (r *authRouter) signInTwoFactor(c echo.Context) error {
+	var input signInTwoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
 		return nil
 	}
 
-	token, err := r.auth.CreateToken(input.Username)
+	claims, err := r.auth.ParseMFAToken(input.MFAToken)
 	if err != nil {
+		errorResponse(c, http.StatusUnauthorized, service.ErrInvalidToken)
+		return nil
+	}
+
+	attemptId, locked, err := signInLocked(c, r.attempts, claims.Username, service.SignInMethodTwoFactor)
+	if locked {
+		return err
+	}
+
+	if err = r.twoFactor.Verify(c.Request().Context(), claims.Username, input.Code); err != nil {
+		if errors.Is(err, service.ErrTwoFactorCode) {
+			r.attempts.Record(c.Request().Context(), attemptId, signInAttempt(c, claims.Username, service.SignInMethodTwoFactor, false))
+			errorResponse(c, http.StatusForbidden, err)
+			return nil
+		}
+		r.attempts.Cancel(c.Request().Context(), attemptId)
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrTwoFactorDisabled) {
+			errorResponse(c, http.StatusUnauthorized, service.ErrInvalidToken)
+			return nil
+		}
 		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
 		return err
 	}
 
+	token, err := r.auth.CreateToken(c.Request().Context(), claims.Username)
+	if err != nil {
+		r.attempts.Cancel(c.Request().Context(), attemptId)
+		return createTokenError(c, err)
+	}
+	r.attempts.Record(c.Request().Context(), attemptId, signInAttempt(c, claims.Username, service.SignInMethodTwoFactor, true))
 	return c.JSON(http.StatusOK, signInResponse{Token: token})
 }
+
+type verifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// @Summary		Verify email
+// @Description	Confirm email with the token from verification email. The token can be used once.
+// @Description	An email can be verified by one account only
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	verifyEmailInput	true	"input"
+// @Success		204
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/verify-email [post]
+func (r *authRouter) verifyEmail(c echo.Context) error {
+	var input verifyEmailInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	if err := r.user.VerifyEmail(c.Request().Context(), input.Token); err != nil {
+		if errors.Is(err, service.ErrUserTokenInvalid) || errors.Is(err, service.ErrUserEmailTaken) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type forgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// @Summary		Forgot password
+// @Description	Send password reset link to the email if it belongs to an account and is verified.
+// @Description	The response is the same whether the account exists or not
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	forgotPasswordInput	true	"input"
+// @Success		202
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/forgot-password [post]
+func (r *authRouter) forgotPassword(c echo.Context) error {
+	var input forgotPasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	if err := r.user.ForgotPassword(c.Request().Context(), input.Email); err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+type resetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// @Summary		Reset password
+// @Description	Set a new password with the token from password reset email. All issued tokens are revoked
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			input	body	resetPasswordInput	true	"input"
+// @Success		204
+// @Failure		400	{object}	echo.HTTPError
+// @Failure		500	{object}	echo.HTTPError
+// @Router			/auth/reset-password [post]
+func (r *authRouter) resetPassword(c echo.Context) error {
+	var input resetPasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	if err := r.user.ResetPassword(c.Request().Context(), input.Token, input.Password); err != nil {
+		if errors.Is(err, service.ErrUserTokenInvalid) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
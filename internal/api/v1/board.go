@@ -0,0 +1,514 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"todolist_api/internal/service"
+)
+
+type boardRouter struct {
+	board service.Board
+}
+
+func newBoardRouter(g *echo.Group, board service.Board) {
+	r := &boardRouter{board: board}
+	read, write := requireScope(service.ScopeBoardsRead), requireScope(service.ScopeBoardsWrite)
+
+	g.POST("", r.create, write)
+	g.GET("", r.list, read)
+	g.GET("/:id", r.findById, read)
+	g.PUT("/:id", r.update, write)
+	g.DELETE("/:id", r.delete, write)
+
+	g.POST("/:id/columns", r.createColumn, write)
+	g.POST("/:id/columns/reorder", r.reorderColumns, write)
+	g.PUT("/:id/columns/:column_id", r.updateColumn, write)
+	g.DELETE("/:id/columns/:column_id", r.deleteColumn, write)
+
+	g.POST("/:id/tasks/:task_id/move", r.moveTask, write)
+}
+
+type boardInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+//	@Summary		Create board
+//	@Description	Create kanban board
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		boardInput	true	"input"
+//	@Success		201		{object}	service.BoardOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards [post]
+func (r *boardRouter) create(c echo.Context) error {
+	var input boardInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	board, err := r.board.Create(c.Request().Context(), service.BoardInput{
+		Username: username,
+		Name:     input.Name,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, board)
+}
+
+//	@Summary		Get boards
+//	@Description	Get list of all user boards
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		service.BoardOutput
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards [get]
+func (r *boardRouter) list(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	boards, err := r.board.Find(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, boards)
+}
+
+//	@Summary		Get board
+//	@Description	Get whole board: columns in order with tasks grouped by column
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"id"
+//	@Success		200	{object}	service.BoardViewOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id} [get]
+func (r *boardRouter) findById(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	board, err := r.board.FindById(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrBoardNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
+//	@Summary		Update board
+//	@Description	Rename board
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int			true	"id"
+//	@Param			input	body		boardInput	true	"input"
+//	@Success		200		{object}	service.BoardOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id} [put]
+func (r *boardRouter) update(c echo.Context) error {
+	var input boardInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	board, err := r.board.Update(c.Request().Context(), service.BoardInput{
+		Id:       id,
+		Username: username,
+		Name:     input.Name,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrBoardNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
+//	@Summary		Delete board
+//	@Description	Delete board with its columns. Tasks are kept and detached from the board
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id} [delete]
+func (r *boardRouter) delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+	if err = r.board.Delete(c.Request().Context(), id, username); err != nil {
+		if errors.Is(err, service.ErrBoardNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type boardColumnInput struct {
+	Name     string `json:"name" validate:"required"`
+	WipLimit int    `json:"wip_limit" validate:"min=0"`
+	Status   string `json:"status" validate:"omitempty,oneof=todo in_progress done"`
+}
+
+//	@Summary		Create board column
+//	@Description	Add column to the end of the board. wip_limit = 0 means no limit, empty status means free-form column
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"board id"
+//	@Param			input	body		boardColumnInput	true	"input"
+//	@Success		201		{object}	service.BoardColumnOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id}/columns [post]
+func (r *boardRouter) createColumn(c echo.Context) error {
+	var input boardColumnInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	boardId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	column, err := r.board.CreateColumn(c.Request().Context(), service.BoardColumnInput{
+		BoardId:  boardId,
+		Username: username,
+		Name:     input.Name,
+		WipLimit: input.WipLimit,
+		Status:   input.Status,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrBoardNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, column)
+}
+
+//	@Summary		Update board column
+//	@Description	Update column name, WIP limit and status
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"board id"
+//	@Param			column_id	path		int					true	"column id"
+//	@Param			input		body		boardColumnInput	true	"input"
+//	@Success		200			{object}	service.BoardColumnOutput
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id}/columns/{column_id} [put]
+func (r *boardRouter) updateColumn(c echo.Context) error {
+	var input boardColumnInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	boardId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	columnId, err := strconv.Atoi(c.Param("column_id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	column, err := r.board.UpdateColumn(c.Request().Context(), service.BoardColumnInput{
+		Id:       columnId,
+		BoardId:  boardId,
+		Username: username,
+		Name:     input.Name,
+		WipLimit: input.WipLimit,
+		Status:   input.Status,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrBoardColumnNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, column)
+}
+
+//	@Summary		Delete board column
+//	@Description	Delete column. Its tasks are kept and detached from the board
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int	true	"board id"
+//	@Param			column_id	path	int	true	"column id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id}/columns/{column_id} [delete]
+func (r *boardRouter) deleteColumn(c echo.Context) error {
+	boardId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	columnId, err := strconv.Atoi(c.Param("column_id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+	if err = r.board.DeleteColumn(c.Request().Context(), boardId, columnId, username); err != nil {
+		if errors.Is(err, service.ErrBoardColumnNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type boardReorderInput struct {
+	ColumnIds []int `json:"column_ids" validate:"required"`
+}
+
+//	@Summary		Reorder board columns
+//	@Description	Set new order of board columns. column_ids must contain every board column exactly once
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"board id"
+//	@Param			input	body		boardReorderInput	true	"input"
+//	@Success		200		{array}		service.BoardColumnOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id}/columns/reorder [post]
+func (r *boardRouter) reorderColumns(c echo.Context) error {
+	var input boardReorderInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	boardId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	columns, err := r.board.ReorderColumns(c.Request().Context(), service.BoardReorderInput{
+		BoardId:   boardId,
+		Username:  username,
+		ColumnIds: input.ColumnIds,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrBoardColumnsMismatch) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrBoardNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, columns)
+}
+
+type boardMoveTaskInput struct {
+	ColumnId int `json:"column_id" validate:"required"`
+}
+
+//	@Summary		Move task to column
+//	@Description	Move user task to the board column. Task gets the column status if it is set
+//	@Tags			board
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"board id"
+//	@Param			task_id	path		int					true	"task id"
+//	@Param			input	body		boardMoveTaskInput	true	"input"
+//	@Success		200		{object}	service.TaskOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		409		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/boards/{id}/tasks/{task_id}/move [post]
+func (r *boardRouter) moveTask(c echo.Context) error {
+	var input boardMoveTaskInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	boardId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	taskId, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	task, err := r.board.MoveTask(c.Request().Context(), service.BoardMoveTaskInput{
+		BoardId:  boardId,
+		ColumnId: input.ColumnId,
+		TaskId:   taskId,
+		Username: username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) || errors.Is(err, service.ErrBoardColumnNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrWIPLimitExceeded) {
+			errorResponse(c, http.StatusConflict, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, task)
+}
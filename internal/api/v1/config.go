@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/config"
+	"todolist_api/internal/service"
+)
+
+// ConfigReloader перечитывает конфигурацию и применяет параметры, которые можно менять без перезапуска
+type ConfigReloader interface {
+	Reload(ctx context.Context) (config.ReloadResult, error)
+}
+
+type configRouter struct {
+	reloader ConfigReloader
+}
+
+func newConfigRouter(g *echo.Group, reloader ConfigReloader) {
+	r := &configRouter{
+		reloader: reloader,
+	}
+
+	g.POST("/reload", r.reload, requirePermission(service.PermissionConfigReload))
+}
+
+//	@Summary		Reload config
+//	@Description	Re-read config file, .env and environment like on SIGHUP and apply log level and output, rate limits,
+//	@Description	CORS origins, feature flags and JWT verification keys. Nothing is applied if the new config is invalid.
+//	@Description	Other changed parameters are listed in restart_required. Requires config:reload permission
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	config.ReloadResult
+//	@Failure		403	{object}	echo.HTTPError
+//	@Failure		422	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/admin/config/reload [post]
+func (r *configRouter) reload(c echo.Context) error {
+	result, err := r.reloader.Reload(c.Request().Context())
+	if err != nil {
+		errorResponse(c, http.StatusUnprocessableEntity, err)
+		return nil
+	}
+	return c.JSON(http.StatusOK, result)
+}
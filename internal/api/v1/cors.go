@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// CORS источники, с которых браузеру разрешено обращаться к API. Список можно заменить во время работы через SetOrigins,
+// "*" разрешает любой источник, пустой список - ни одного
+type CORS struct {
+	origins atomic.Pointer[[]string]
+}
+
+func NewCORS(origins []string) *CORS {
+	c := &CORS{}
+	c.SetOrigins(origins)
+	return c
+}
+
+// SetOrigins атомарно заменяет список разрешенных источников
+func (c *CORS) SetOrigins(origins []string) {
+	// браузер присылает Origin без завершающего слэша
+	normalized := make([]string, len(origins))
+	for i, origin := range origins {
+		normalized[i] = strings.TrimSuffix(origin, "/")
+	}
+	c.origins.Store(&normalized)
+}
+
+func (c *CORS) allowOrigin(origin string) (bool, error) {
+	origins := *c.origins.Load()
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin), nil
+}
+
+// CORSMiddleware отвечает на preflight запросы и добавляет заголовки CORS для разрешенных источников.
+// Клиенту открываются заголовки ограничения частоты запросов и X-Request-ID
+func CORSMiddleware(h *echo.Echo, cors *CORS) {
+	h.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: cors.allowOrigin,
+		ExposeHeaders: []string{
+			echo.HeaderXRequestID, echo.HeaderRetryAfter,
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+		},
+		MaxAge: 600,
+	}))
+}
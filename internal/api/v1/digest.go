@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+)
+
+type digestRouter struct {
+	digest service.Digest
+}
+
+func newDigestRouter(g *echo.Group, digest service.Digest) {
+	r := &digestRouter{digest: digest}
+
+	g.GET("", r.find)
+	g.PUT("", r.save)
+}
+
+//	@Summary		Get digest settings
+//	@Description	Get settings of user digest email with overdue tasks, tasks due today and due this week
+//	@Tags			digest
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	service.DigestSettingOutput
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/digest [get]
+func (r *digestRouter) find(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	setting, err := r.digest.FindSetting(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, setting)
+}
+
+type digestSettingInput struct {
+	Enabled   bool   `json:"enabled"`
+	Email     string `json:"email" validate:"omitempty,email"`
+	Frequency string `json:"frequency" validate:"omitempty,oneof=daily weekly"`
+	SendHour  int    `json:"send_hour" validate:"min=0,max=23"`
+	Weekday   int    `json:"weekday" validate:"min=0,max=6"`
+}
+
+//	@Summary		Update digest settings
+//	@Description	Opt in or out of digest email and set its schedule. send_hour and weekday (0 - Sunday, weekly digest only)
+//	@Description	are in the timezone of the user profile (PATCH /api/v1/me). Digest without any due or overdue tasks is not sent.
+//	@Description	Digest is sent only to the verified email of the user, which is used when email is empty
+//	@Tags			digest
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		digestSettingInput	true	"input"
+//	@Success		200		{object}	service.DigestSettingOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/digest [put]
+func (r *digestRouter) save(c echo.Context) error {
+	var input digestSettingInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	setting, err := r.digest.SaveSetting(c.Request().Context(), service.DigestSettingInput{
+		Username:  username,
+		Enabled:   input.Enabled,
+		Email:     input.Email,
+		Frequency: input.Frequency,
+		SendHour:  input.SendHour,
+		Weekday:   input.Weekday,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrDigestEmail) ||
+			errors.Is(err, service.ErrDigestUnavailable) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, setting)
+}
@@ -7,6 +7,13 @@ import (
 
 var (
 	ErrInvalidAuthHeader = errors.New("invalid authorization header")
+	ErrInsufficientScope = errors.New("personal access token does not have the required scope")
+	ErrSessionRequired   = errors.New("personal access tokens cannot be used for this endpoint, sign in instead")
+	ErrPermissionDenied  = errors.New("you do not have permission to access this endpoint")
+	// ErrInvalidCredentials одна ошибка для неизвестного имени и неверного пароля, чтобы по ответу нельзя было перебирать имена
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrSignInLocked       = errors.New("too many failed sign in attempts, try again later")
+	ErrRateLimited        = errors.New("too many requests, try again later")
 )
 
 func errorResponse(c echo.Context, status int, err error) {
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"todolist_api/internal/service"
+)
+
+type exportRouter struct {
+	export service.Export
+}
+
+func newExportRouter(g *echo.Group, export service.Export) {
+	r := &exportRouter{
+		export: export,
+	}
+
+	g.POST("", r.create)
+	g.GET("/:id", r.findById)
+}
+
+// newExportDownloadRouter скачивание по подписанной ссылке, доступно без токена
+func newExportDownloadRouter(g *echo.Group, export service.Export) {
+	r := &exportRouter{
+		export: export,
+	}
+
+	g.GET("/:id", r.download)
+}
+
+//	@Summary		Request data export
+//	@Description	Start building a ZIP archive with all user data (profile, tasks, boards, time entries, reminders, digest settings) as JSON.
+//	@Description	The archive is built asynchronously, poll the export until status is ready and download it by download_url
+//	@Tags			export
+//	@Accept			json
+//	@Produce		json
+//	@Success		202	{object}	service.DataExportOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		409	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/export [post]
+func (r *exportRouter) create(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	export, err := r.export.Create(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			errorResponse(c, http.StatusConflict, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusAccepted, export)
+}
+
+//	@Summary		Get data export
+//	@Description	Get status of user data export. When status is ready, download_url is a signed link valid until expires_at
+//	@Tags			export
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"id"
+//	@Success		200	{object}	service.DataExportOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/export/{id} [get]
+func (r *exportRouter) findById(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	export, err := r.export.FindById(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, export)
+}
+
+type exportDownloadInput struct {
+	Expires   int64  `query:"expires" validate:"required"`
+	Signature string `query:"signature" validate:"required"`
+}
+
+//	@Summary		Download data export
+//	@Description	Download ZIP archive of user data by signed link from download_url, no token is required
+//	@Tags			export
+//	@Produce		application/zip
+//	@Param			id			path		int		true	"id"
+//	@Param			expires		query		int		true	"unix time the link expires at"
+//	@Param			signature	query		string	true	"link signature"
+//	@Success		200			{file}		binary
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		410			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Router			/exports/{id} [get]
+func (r *exportRouter) download(c echo.Context) error {
+	var input exportDownloadInput
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err = (&echo.DefaultBinder{}).BindQueryParams(c, &input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err = c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	archive, err := r.export.Download(c.Request().Context(), id, input.Expires, input.Signature)
+	if err != nil {
+		if errors.Is(err, service.ErrExportLinkInvalid) {
+			errorResponse(c, http.StatusForbidden, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrExportLinkExpired) {
+			errorResponse(c, http.StatusGone, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrExportNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="export-%d.zip"`, id))
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
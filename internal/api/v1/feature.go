@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/pkg/feature"
+)
+
+type featureRouter struct {
+	flags *feature.Flags
+}
+
+func newFeatureRouter(g *echo.Group, flags *feature.Flags) {
+	r := &featureRouter{
+		flags: flags,
+	}
+
+	g.GET("", r.features)
+}
+
+type featuresResponse struct {
+	Enabled []string `json:"enabled" example:"kanban_v2"`
+}
+
+//	@Summary		Feature flags
+//	@Description	Enabled feature flags. Clients may show or hide functionality by them, the list changes on config reload
+//	@Tags			features
+//	@Produce		json
+//	@Success		200	{object}	featuresResponse
+//	@Router			/features [get]
+func (r *featureRouter) features(c echo.Context) error {
+	return c.JSON(http.StatusOK, featuresResponse{Enabled: r.flags.List()})
+}
@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/pkg/health"
+)
+
+type healthRouter struct {
+	checker *health.Checker
+}
+
+func newHealthRouter(h *echo.Echo, checker *health.Checker) {
+	r := &healthRouter{
+		checker: checker,
+	}
+
+	h.GET("/healthz", r.healthz)
+	h.GET("/readyz", r.readyz)
+}
+
+//	@Summary		Liveness check
+//	@Description	Always 200 while the process is running and serving HTTP
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Component
+//	@Router			/healthz [get]
+func (r *healthRouter) healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, health.Component{Status: health.StatusOK})
+}
+
+//	@Summary		Readiness check
+//	@Description	Checks database connectivity and migration version. Returns 503 if any check fails or the application
+//	@Description	is shutting down. Only the overall status is returned, the report with components is served on the metrics port
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Component
+//	@Failure		503	{object}	health.Component
+//	@Router			/readyz [get]
+func (r *healthRouter) readyz(c echo.Context) error {
+	// ошибки компонентов раскрывают внутреннее устройство, они пишутся в лог и отдаются только на порту метрик
+	report := r.checker.Ready(c.Request().Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, health.Component{Status: report.Status})
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+	"todolist_api/pkg/jwtkeys"
+)
+
+// jwksCacheControl клиенты кешируют набор ключей на 5 минут. При ротации новый ключ подписи нужно
+// опубликовать в наборе заранее, хотя бы на это время
+const jwksCacheControl = "public, max-age=300"
+
+type jwksRouter struct {
+	auth service.Auth
+}
+
+func newJWKSRouter(g *echo.Group, auth service.Auth) {
+	r := &jwksRouter{
+		auth: auth,
+	}
+
+	g.GET("/jwks.json", r.jwks)
+}
+
+//	@Summary		JSON Web Key Set
+//	@Description	Public keys for verifying access tokens signed with RS256 or EdDSA, the token header kid selects the key.
+//	@Description	The set is empty while tokens are signed with the shared HS256 secret
+//	@Tags			auth
+//	@Produce		json
+//	@Success		200	{object}	jwtkeys.Set
+//	@Router			/.well-known/jwks.json [get]
+func (r *jwksRouter) jwks(c echo.Context) error {
+	var set jwtkeys.Set = r.auth.JWKS()
+
+	c.Response().Header().Set(echo.HeaderCacheControl, jwksCacheControl)
+	return c.JSON(http.StatusOK, set)
+}
@@ -2,24 +2,42 @@ package v1
 
 import (
 	"errors"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"io"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"todolist_api/internal/service"
+	"todolist_api/pkg/logger"
 )
 
 const (
 	bearerPrefix = "Bearer "
 	usernameCtx  = "username"
+	// scopesCtx права персонального токена. Не задан, если запрос аутентифицирован JWT
+	scopesCtx = "scopes"
+	// rolesCtx роли из JWT. У запросов с персональным токеном ролей нет
+	rolesCtx = "roles"
 )
 
 type authMiddleware struct {
-	auth service.Auth
+	auth          service.Auth
+	user          service.User
+	personalToken service.PersonalToken
 }
 
+// authHandler принимает JWT или персональный токен доступа, который отличается по префиксу service.PersonalTokenPrefix
 func (r *authMiddleware) authHandler(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		token, ok := parseToken(c.Request())
@@ -27,21 +45,94 @@ func (r *authMiddleware) authHandler(next echo.HandlerFunc) echo.HandlerFunc {
 			errorResponse(c, http.StatusUnauthorized, ErrInvalidAuthHeader)
 			return nil
 		}
+		if strings.HasPrefix(token, service.PersonalTokenPrefix) {
+			return r.personalTokenHandler(c, next, token)
+		}
+		// истекший, поддельный или подписанный неизвестным ключом токен не аутентифицирует запрос
 		claims, err := r.auth.ParseToken(token)
 		if err != nil {
-			if errors.Is(err, service.ErrCannotParseToken) {
+			errorResponse(c, http.StatusUnauthorized, err)
+			return nil
+		}
+		// токен мог быть отозван сменой пароля, имени или ролей, а пользователь - удален или отключен
+		err = r.user.VerifySession(c.Request().Context(), claims.Username, time.Unix(claims.IssuedAt, 0))
+		if err != nil {
+			if errors.Is(err, service.ErrSessionExpired) {
 				errorResponse(c, http.StatusUnauthorized, err)
 				return nil
-			} else {
+			}
+			if errors.Is(err, service.ErrUserDisabled) {
 				errorResponse(c, http.StatusForbidden, err)
 				return nil
 			}
+			errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+			return err
+		}
+		setUsername(c, claims.Username)
+		c.Set(rolesCtx, claims.Roles)
+		return next(c)
+	}
+}
+
+func (r *authMiddleware) personalTokenHandler(c echo.Context, next echo.HandlerFunc, token string) error {
+	identity, err := r.personalToken.Authenticate(c.Request().Context(), token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			errorResponse(c, http.StatusUnauthorized, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrUserDisabled) {
+			errorResponse(c, http.StatusForbidden, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	setUsername(c, identity.Username)
+	c.Set(scopesCtx, identity.Scopes)
+	return next(c)
+}
+
+// requireScope пропускает запрос с персональным токеном, только если у токена есть право scope
+func requireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, ok := c.Get(scopesCtx).([]string)
+			if ok && !slices.Contains(scopes, scope) {
+				errorResponse(c, http.StatusForbidden, ErrInsufficientScope)
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
+// requireSession закрывает маршруты управления аккаунтом для персональных токенов: они доступны только с JWT
+func requireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := c.Get(scopesCtx).([]string); ok {
+			errorResponse(c, http.StatusForbidden, ErrSessionRequired)
+			return nil
 		}
-		c.Set(usernameCtx, claims.Username)
 		return next(c)
 	}
 }
 
+// requirePermission пропускает запрос, только если право permission дает одна из ролей пользователя.
+// Персональные токены ролей не несут, поэтому такие маршруты доступны только с JWT
+func requirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			roles, _ := c.Get(rolesCtx).([]string)
+			if !service.HasPermission(roles, permission) {
+				errorResponse(c, http.StatusForbidden, ErrPermissionDenied)
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
 func parseToken(r *http.Request) (string, bool) {
 	header := r.Header.Get(echo.HeaderAuthorization)
 	if header == "" {
@@ -54,18 +145,145 @@ func parseToken(r *http.Request) (string, bool) {
 	return token[1], true
 }
 
-func LoggingMiddleware(h *echo.Echo, output string) {
-	cfg := middleware.LoggerConfig{
-		Format: `{"time":"${time_rfc3339}", "method":"${method}","uri":"${uri}", "status":${status}, "error":"${error}"}` + "\n",
+func LoggingMiddleware(h *echo.Echo, output io.Writer) {
+	h.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		// latency в наносекундах, id - X-Request-ID из RequestIDMiddleware
+		Format: `{"time":"${time_rfc3339}", "request_id":"${id}", "method":"${method}","uri":"${uri}", "route":"${route}", ` +
+			`"status":${status}, "latency":${latency}, "latency_human":"${latency_human}", "bytes_in":${bytes_in}, ` +
+			`"bytes_out":${bytes_out}, "error":"${error}"}` + "\n",
+		Output: output,
+	}))
+}
+
+// MetricsMiddleware считает запросы и их длительность по методу, шаблону маршрута и статусу ответа.
+// Берется шаблон (/api/v1/tasks/:id), а не URI, чтобы число рядов не росло с каждым id в пути
+func MetricsMiddleware(h *echo.Echo, reg prometheus.Registerer) {
+	labels := []string{"method", "route", "status"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status",
+		Buckets: prometheus.DefBuckets,
+	}, labels)
+	reg.MustRegister(requests, duration)
+
+	h.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			values := []string{c.Request().Method, routePattern(c), strconv.Itoa(responseStatus(c, err))}
+			requests.WithLabelValues(values...).Inc()
+			duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	})
+}
+
+// TracingMiddleware начинает серверный span запроса. Если клиент прислал заголовок traceparent, span становится
+// продолжением его трассы. Span передается дальше через контекст запроса, в нем же создаются spans сервисов и запросов к базе
+func TracingMiddleware(h *echo.Echo) {
+	tracer := otel.Tracer("todolist_api/internal/api/v1")
+
+	h.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			route := routePattern(c)
+
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", req.URL.Path),
+					attribute.String("client.address", c.RealIP()),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			// имя пользователя появляется только после authHandler, внутри next
+			if username, ok := c.Get(usernameCtx).(string); ok {
+				span.SetAttributes(attribute.String("enduser.id", username))
+			}
+			if status >= http.StatusInternalServerError {
+				if err != nil {
+					span.RecordError(err)
+				}
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	})
+}
+
+// routePattern шаблон маршрута запроса, для запросов мимо маршрутов - unmatched
+func routePattern(c echo.Context) string {
+	if route := c.Path(); route != "" {
+		return route
 	}
-	if output == "stdout" {
-		cfg.Output = os.Stdout
-	} else {
-		file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0755)
-		if err != nil {
-			log.Fatal(err)
+	return "unmatched"
+}
+
+// responseStatus статус ответа. Ошибку в ответ превращает обработчик ошибок echo уже после middleware, поэтому
+// при ошибке статус берется из нее
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// maxRequestIDLength длиннее X-Request-ID клиента не принимается, вместо него генерируется свой
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware берет X-Request-ID из запроса или генерирует новый и возвращает его в ответе.
+// Id и маршрут кладутся в контекст запроса, чтобы логи сервисов можно было связать со строкой access log
+func RequestIDMiddleware(h *echo.Echo) {
+	h.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+				req.Header.Set(echo.HeaderXRequestID, id)
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			ctx := logger.With(req.Context(), logrus.Fields{"request_id": id, "route": routePattern(c)})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
 		}
-		cfg.Output = file
+	})
+}
+
+// validRequestID id клиента попадает в логи как есть, поэтому допускаются только печатные ASCII символы без пробелов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
 	}
-	h.Use(middleware.LoggerWithConfig(cfg))
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// setUsername сохраняет имя аутентифицированного пользователя для обработчиков и для логов сервисов
+func setUsername(c echo.Context, username string) {
+	c.Set(usernameCtx, username)
+	c.SetRequest(c.Request().WithContext(logger.With(c.Request().Context(), logrus.Fields{"user": username})))
 }
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todolist_api/internal/service"
+	"todolist_api/pkg/validator"
+)
+
+// newAPITestServer маршруты собираются так же, как в приложении, чтобы проверялись права на каждом маршруте
+func newAPITestServer(t *testing.T) *echo.Echo {
+	v, err := validator.NewValidator()
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Validator = v
+	NewRouter(e, &service.Services{
+		Auth:          &stubAuth{},
+		User:          &stubUser{},
+		PersonalToken: &stubPersonalToken{},
+		Task:          &stubTask{},
+	}, NewRateLimiter(RateLimits{}), nil, nil, nil)
+	return e
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		method       string
+		path         string
+		token        string
+		expectStatus int
+		expectError  error
+	}{
+		{
+			testName:     "No token",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			expectStatus: http.StatusUnauthorized,
+			expectError:  ErrInvalidAuthHeader,
+		},
+		{
+			testName:     "JWT",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubJWT,
+			expectStatus: http.StatusOK,
+		},
+		{
+			testName:     "Expired JWT",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubJWTExpired,
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:     "Revoked JWT",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubJWTRevoked,
+			expectStatus: http.StatusUnauthorized,
+			expectError:  service.ErrSessionExpired,
+		},
+		{
+			testName:     "JWT of disabled user",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubJWTDisabled,
+			expectStatus: http.StatusForbidden,
+			expectError:  service.ErrUserDisabled,
+		},
+		{
+			testName:     "Personal token with the scope",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubTokenTasksRead,
+			expectStatus: http.StatusOK,
+		},
+		{
+			testName:     "Read-only personal token on write route",
+			method:       http.MethodPost,
+			path:         "/api/v1/tasks",
+			token:        stubTokenTasksRead,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrInsufficientScope,
+		},
+		{
+			testName:     "Tasks personal token on boards route",
+			method:       http.MethodGet,
+			path:         "/api/v1/boards",
+			token:        stubTokenTasksRead,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrInsufficientScope,
+		},
+		{
+			testName:     "Read-only personal token on reminders write route",
+			method:       http.MethodDelete,
+			path:         "/api/v1/reminders/1",
+			token:        stubTokenTasksRead,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrInsufficientScope,
+		},
+		{
+			testName:     "Personal token on account route",
+			method:       http.MethodGet,
+			path:         "/api/v1/me",
+			token:        stubTokenAll,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrSessionRequired,
+		},
+		{
+			testName:     "Personal token on personal tokens route",
+			method:       http.MethodPost,
+			path:         "/api/v1/me/tokens",
+			token:        stubTokenAll,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrSessionRequired,
+		},
+		{
+			testName:     "Personal token on admin route",
+			method:       http.MethodGet,
+			path:         "/api/v1/admin/users",
+			token:        stubTokenAll,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrSessionRequired,
+		},
+		{
+			testName:     "JWT without admin role on admin route",
+			method:       http.MethodGet,
+			path:         "/api/v1/admin/users",
+			token:        stubJWT,
+			expectStatus: http.StatusForbidden,
+			expectError:  ErrPermissionDenied,
+		},
+		{
+			testName:     "Expired or revoked personal token",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubTokenRevoked,
+			expectStatus: http.StatusUnauthorized,
+			expectError:  service.ErrInvalidToken,
+		},
+		{
+			testName:     "Personal token of disabled user",
+			method:       http.MethodGet,
+			path:         "/api/v1/tasks",
+			token:        stubTokenDisabled,
+			expectStatus: http.StatusForbidden,
+			expectError:  service.ErrUserDisabled,
+		},
+	}
+
+	e := newAPITestServer(t)
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader("{}"))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tc.token != "" {
+				req.Header.Set(echo.HeaderAuthorization, bearerPrefix+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectError != nil {
+				assert.Contains(t, rec.Body.String(), tc.expectError.Error())
+			}
+		})
+	}
+}
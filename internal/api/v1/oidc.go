@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+	"todolist_api/internal/service"
+)
+
+// oidcStateCookie привязывает начатый вход к браузеру: callback принимается, только если state из адреса совпадает
+// с cookie, иначе можно было бы подсунуть жертве адрес возврата со своим code и войти ею в чужой аккаунт (login CSRF)
+const oidcStateCookie = "oidc_state"
+
+type oidcRouter struct {
+	oidc      service.OIDC
+	auth      service.Auth
+	twoFactor service.TwoFactor
+	attempts  service.SignIn
+}
+
+func newOIDCRouter(g *echo.Group, oidc service.OIDC, auth service.Auth, twoFactor service.TwoFactor, attempts service.SignIn) {
+	r := &oidcRouter{
+		oidc:      oidc,
+		auth:      auth,
+		twoFactor: twoFactor,
+		attempts:  attempts,
+	}
+
+	g.GET("", r.providers)
+	g.GET("/:provider", r.begin)
+	g.GET("/:provider/callback", r.callback)
+}
+
+type oidcProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+//	@Summary		Identity providers
+//	@Description	Get names of configured OpenID Connect providers for sign in
+//	@Tags			auth
+//	@Produce		json
+//	@Success		200	{object}	oidcProvidersResponse
+//	@Router			/auth/oidc [get]
+func (r *oidcRouter) providers(c echo.Context) error {
+	return c.JSON(http.StatusOK, oidcProvidersResponse{Providers: r.oidc.Providers()})
+}
+
+//	@Summary		Sign in with identity provider
+//	@Description	Redirect to sign in page of OpenID Connect provider (authorization code flow with PKCE).
+//	@Description	After sign in provider redirects back to /auth/oidc/{provider}/callback. The login is bound to the browser by oidc_state cookie
+//	@Tags			auth
+//	@Param			provider	path	string	true	"provider name"
+//	@Success		302
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		502	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Router			/auth/oidc/{provider} [get]
+func (r *oidcRouter) begin(c echo.Context) error {
+	login, err := r.oidc.Begin(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCProviderNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrOIDCProviderUnavailable) {
+			errorResponse(c, http.StatusBadGateway, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	c.SetCookie(oidcCookie(c, login.State, login.ExpiresAt))
+	return c.Redirect(http.StatusFound, login.AuthURL)
+}
+
+// oidcCookie cookie со state начатого входа. SameSite=Lax: cookie отправляется при переходе с сайта провайдера на callback.
+// Пустой state удаляет cookie
+func oidcCookie(c echo.Context, state string, expiresAt time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if state == "" {
+		cookie.MaxAge = -1
+		cookie.Expires = time.Time{}
+	}
+	return cookie
+}
+
+//	@Summary		Identity provider callback
+//	@Description	Complete sign in with OpenID Connect provider. External account is linked to the user with the same verified email
+//	@Description	or a new user is created if provider allows it. Response is the same as for sign in.
+//	@Description	state must match oidc_state cookie set by /auth/oidc/{provider}
+//	@Tags			auth
+//	@Produce		json
+//	@Param			provider	path		string	true	"provider name"
+//	@Param			code		query		string	true	"authorization code"
+//	@Param			state		query		string	true	"state"
+//	@Success		200			{object}	signInResponse
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		401			{object}	echo.HTTPError
+//	@Failure		403			{object}	echo.HTTPError
+//	@Failure		404			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Router			/auth/oidc/{provider}/callback [get]
+func (r *oidcRouter) callback(c echo.Context) error {
+	// провайдер сообщает об отказе пользователя или ошибке параметром error вместо code
+	if providerErr := c.QueryParam("error"); providerErr != "" {
+		errorResponse(c, http.StatusBadRequest, fmt.Errorf("identity provider error: %s", providerErr))
+		return nil
+	}
+	code, state := c.QueryParam("code"), c.QueryParam("state")
+	if code == "" || state == "" {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		errorResponse(c, http.StatusBadRequest, service.ErrOIDCLoginInvalid)
+		return nil
+	}
+	c.SetCookie(oidcCookie(c, "", time.Time{}))
+
+	username, err := r.oidc.Complete(c.Request().Context(), service.OIDCCallbackInput{
+		Provider: c.Param("provider"),
+		State:    state,
+		Code:     code,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCProviderNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrOIDCLoginInvalid) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrOIDCAuthFailed) {
+			errorResponse(c, http.StatusUnauthorized, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrOIDCNotLinked) {
+			errorResponse(c, http.StatusForbidden, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return signInSuccess(c, r.auth, r.twoFactor, r.attempts, 0, username, service.SignInMethodOIDC)
+}
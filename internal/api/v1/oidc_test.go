@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todolist_api/internal/service"
+)
+
+type stubOIDC struct {
+	service.OIDC
+	completed []service.OIDCCallbackInput
+}
+
+func (s *stubOIDC) Begin(_ context.Context, provider string) (service.OIDCLoginOutput, error) {
+	return service.OIDCLoginOutput{
+		AuthURL:   "https://idp.example.com/authorize?state=state-" + provider,
+		State:     "state-" + provider,
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}, nil
+}
+
+func (s *stubOIDC) Complete(_ context.Context, input service.OIDCCallbackInput) (string, error) {
+	s.completed = append(s.completed, input)
+	return "vasya", nil
+}
+
+func newOIDCTestServer() (*echo.Echo, *stubOIDC) {
+	oidc := &stubOIDC{}
+	e := echo.New()
+	newOIDCRouter(e.Group("/auth/oidc"), oidc, &stubAuth{}, &stubTwoFactor{}, &stubSignIn{})
+	return e, oidc
+}
+
+func TestOIDCRouter_Begin(t *testing.T) {
+	e, _ := newOIDCTestServer()
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/corp", nil))
+
+	require.Equal(t, http.StatusFound, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, oidcStateCookie, cookies[0].Name)
+	assert.Equal(t, "state-corp", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+}
+
+func TestOIDCRouter_Callback(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		cookie       *http.Cookie
+		expectStatus int
+	}{
+		{
+			testName:     "State matches cookie",
+			cookie:       &http.Cookie{Name: oidcStateCookie, Value: "state-corp"},
+			expectStatus: http.StatusOK,
+		},
+		{
+			testName:     "No cookie",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			testName:     "Cookie from another login",
+			cookie:       &http.Cookie{Name: oidcStateCookie, Value: "state-other"},
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			e, oidc := newOIDCTestServer()
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/corp/callback?code=abc&state=state-corp", nil)
+			if tc.cookie != nil {
+				req.AddCookie(tc.cookie)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectStatus != http.StatusOK {
+				// чужой state не доходит до обмена code на токен
+				assert.Empty(t, oidc.completed)
+				return
+			}
+			assert.Len(t, oidc.completed, 1)
+			assert.Contains(t, rec.Body.String(), "token-vasya")
+		})
+	}
+}
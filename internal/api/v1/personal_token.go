@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"todolist_api/internal/service"
+)
+
+type personalTokenRouter struct {
+	personalToken service.PersonalToken
+}
+
+func newPersonalTokenRouter(g *echo.Group, personalToken service.PersonalToken) {
+	r := &personalTokenRouter{personalToken: personalToken}
+
+	g.POST("", r.create)
+	g.GET("", r.list)
+	g.DELETE("/:id", r.delete)
+}
+
+type personalTokenInput struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+//	@Summary		Create personal access token
+//	@Description	Create named personal access token for scripts. Token is returned only once, use it as "Authorization: Bearer tdl_...".
+//	@Description	Allowed scopes: tasks:read, tasks:write (tasks, time entries, reminders), boards:read, boards:write. Without expires_at token does not expire
+//	@Tags			personal-token
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		personalTokenInput	true	"input"
+//	@Success		201		{object}	service.PersonalTokenCreateOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		409		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/tokens [post]
+func (r *personalTokenRouter) create(c echo.Context) error {
+	var input personalTokenInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	token, err := r.personalToken.Create(c.Request().Context(), service.PersonalTokenInput{
+		Username:  username,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPersonalTokenScope) || errors.Is(err, service.ErrPersonalTokenExpiry) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrPersonalTokenAlreadyExists) {
+			errorResponse(c, http.StatusConflict, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, token)
+}
+
+//	@Summary		List personal access tokens
+//	@Description	Get all personal access tokens of user with last usage time. Tokens themselves are not returned
+//	@Tags			personal-token
+//	@Produce		json
+//	@Success		200	{array}		service.PersonalTokenOutput
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/tokens [get]
+func (r *personalTokenRouter) list(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	tokens, err := r.personalToken.Find(c.Request().Context(), username)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+//	@Summary		Revoke personal access token
+//	@Description	Delete personal access token, requests with it are rejected immediately
+//	@Tags			personal-token
+//	@Param			id	path	int	true	"token id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/tokens/{id} [delete]
+func (r *personalTokenRouter) delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	if err = r.personalToken.Delete(c.Request().Context(), id, username); err != nil {
+		if errors.Is(err, service.ErrPersonalTokenNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
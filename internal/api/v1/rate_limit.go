@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"todolist_api/pkg/ratelimit"
+)
+
+// Группы маршрутов с отдельными ограничениями частоты запросов
+const (
+	rateLimitGroupAuth   = "auth"
+	rateLimitGroupAPI    = "api"
+	rateLimitGroupPublic = "public"
+)
+
+// RateLimits ограничения частоты запросов по группам маршрутов. Нулевой ratelimit.Limit снимает ограничение с группы,
+// без Store ограничений нет совсем
+type RateLimits struct {
+	Store  ratelimit.Store
+	Auth   ratelimit.Limit
+	API    ratelimit.Limit
+	Public ratelimit.Limit
+}
+
+// RateLimiter действующие ограничения частоты запросов. Ограничения можно заменить во время работы через Set,
+// хранилище задается при создании и не меняется
+type RateLimiter struct {
+	store  ratelimit.Store
+	limits atomic.Pointer[RateLimits]
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	l := &RateLimiter{store: limits.Store}
+	l.limits.Store(&limits)
+	return l
+}
+
+// Set атомарно заменяет ограничения групп, Store из limits не используется
+func (l *RateLimiter) Set(limits RateLimits) {
+	limits.Store = l.store
+	l.limits.Store(&limits)
+}
+
+func (l *RateLimiter) limit(group string) ratelimit.Limit {
+	limits := l.limits.Load()
+	switch group {
+	case rateLimitGroupAuth:
+		return limits.Auth
+	case rateLimitGroupAPI:
+		return limits.API
+	default:
+		return limits.Public
+	}
+}
+
+// rateLimit ограничивает частоту запросов группы group: аутентифицированных - по имени пользователя, поэтому для
+// /api/v1 должен стоять после authHandler, остальных - по IP. Если хранилище недоступно, запрос пропускается:
+// из-за ограничителя API падать не должно
+func rateLimit(limiter *RateLimiter, group string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limiter.store == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			// ограничение читается на каждый запрос, чтобы перечитанная конфигурация действовала сразу
+			limit := limiter.limit(group)
+			if !limit.Enabled() {
+				return next(c)
+			}
+			key := group + ":ip:" + c.RealIP()
+			if username, ok := c.Get(usernameCtx).(string); ok {
+				key = group + ":user:" + username
+			}
+			result, err := limiter.store.Take(c.Request().Context(), key, limit)
+			if err != nil {
+				c.Logger().Errorf("/api/v1/rateLimit error take token %s: %s", key, err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds())))
+			if !result.Allowed {
+				header.Set(echo.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+				errorResponse(c, http.StatusTooManyRequests, ErrRateLimited)
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
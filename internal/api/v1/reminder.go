@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"todolist_api/internal/service"
+)
+
+type reminderRouter struct {
+	reminder service.Reminder
+}
+
+func newReminderRouter(g *echo.Group, reminder service.Reminder) {
+	r := &reminderRouter{reminder: reminder}
+	read, write := requireScope(service.ScopeTasksRead), requireScope(service.ScopeTasksWrite)
+
+	g.POST("/tasks/:id/reminders", r.create, write)
+	g.GET("/tasks/:id/reminders", r.listByTask, read)
+	g.DELETE("/reminders/:id", r.delete, write)
+}
+
+type reminderInput struct {
+	RemindAt      time.Time `json:"remind_at"`
+	OffsetMinutes *int      `json:"offset_minutes" validate:"omitempty,min=0"`
+	Channel       string    `json:"channel" validate:"required,oneof=email webhook"`
+	Target        string    `json:"target" validate:"required,max=2048"`
+}
+
+//	@Summary		Create reminder
+//	@Description	Create reminder on user task. Exactly one of remind_at (absolute time) and offset_minutes (minutes before due date) must be set.
+//	@Description	Reminders relative to due date are rescheduled when due date changes. Target is the verified email of the user for email channel and url for webhook channel
+//	@Tags			reminder
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"task id"
+//	@Param			input	body		reminderInput	true	"input"
+//	@Success		201		{object}	service.ReminderOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/reminders [post]
+func (r *reminderRouter) create(c echo.Context) error {
+	var input reminderInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	reminder, err := r.reminder.Create(c.Request().Context(), service.ReminderInput{
+		TaskId:        id,
+		Username:      username,
+		RemindAt:      input.RemindAt,
+		OffsetMinutes: input.OffsetMinutes,
+		Channel:       input.Channel,
+		Target:        input.Target,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrReminderTime) || errors.Is(err, service.ErrReminderChannelUnavailable) ||
+			errors.Is(err, service.ErrReminderTarget) || errors.Is(err, service.ErrReminderEmailNotVerified) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, reminder)
+}
+
+//	@Summary		Get reminders
+//	@Description	Get reminders of user task, including already sent
+//	@Tags			reminder
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"task id"
+//	@Success		200	{array}		service.ReminderOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/reminders [get]
+func (r *reminderRouter) listByTask(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	reminders, err := r.reminder.FindByTask(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, reminders)
+}
+
+//	@Summary		Delete reminder
+//	@Description	Delete user reminder by id
+//	@Tags			reminder
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/reminders/{id} [delete]
+func (r *reminderRouter) delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+	if err = r.reminder.Delete(c.Request().Context(), id, username); err != nil {
+		if errors.Is(err, service.ErrReminderNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
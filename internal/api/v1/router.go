@@ -6,18 +6,44 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 	_ "todolist_api/docs"
 	"todolist_api/internal/service"
+	"todolist_api/pkg/feature"
+	"todolist_api/pkg/health"
 )
 
-func NewRouter(h *echo.Echo, services *service.Services) {
+func NewRouter(h *echo.Echo, services *service.Services, limiter *RateLimiter, checker *health.Checker, features *feature.Flags, reloader ConfigReloader) {
 	h.Use(middleware.Recover())
 	h.GET("/ping", ping)
+	newHealthRouter(h, checker)
 	h.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	newAuthRouter(h.Group("/auth"), services.Auth, services.User)
-	auth := &authMiddleware{auth: services.Auth}
+	// вход через пароль и OIDC делят одну корзину на IP
+	authLimit := rateLimit(limiter, rateLimitGroupAuth)
+	publicLimit := rateLimit(limiter, rateLimitGroupPublic)
+	newAuthRouter(h.Group("/auth", authLimit), services.Auth, services.User, services.TwoFactor, services.SignIn)
+	newOIDCRouter(h.Group("/auth/oidc", authLimit), services.OIDC, services.Auth, services.TwoFactor, services.SignIn)
+	newExportDownloadRouter(h.Group("/exports", publicLimit), services.Export)
+	newJWKSRouter(h.Group("/.well-known", publicLimit), services.Auth)
+	newFeatureRouter(h.Group("/features", publicLimit), features)
+	auth := &authMiddleware{auth: services.Auth, user: services.User, personalToken: services.PersonalToken}
 
-	v1 := h.Group("/api/v1", auth.authHandler)
+	v1 := h.Group("/api/v1", auth.authHandler, rateLimit(limiter, rateLimitGroupAPI))
 	newTaskRouter(v1.Group("/tasks"), services.Task)
+	newBoardRouter(v1.Group("/boards"), services.Board)
+	newTimeEntryRouter(v1, services.TimeEntry)
+	newReminderRouter(v1, services.Reminder)
+
+	// управление аккаунтом доступно только с JWT, персональные токены дают доступ лишь к задачам и доскам
+	me := v1.Group("/me", requireSession)
+	newUserRouter(me, services.Auth, services.User)
+	newDigestRouter(me.Group("/digest"), services.Digest)
+	newTwoFactorRouter(me.Group("/2fa"), services.TwoFactor)
+	newExportRouter(me.Group("/export"), services.Export)
+	newPersonalTokenRouter(me.Group("/tokens"), services.PersonalToken)
+	newSignInRouter(me.Group("/sign-ins"), services.SignIn)
+
+	// администрирование пользователей, права проверяются по ролям из JWT
+	newAdminRouter(v1.Group("/admin", requireSession), services.Admin, services.SignIn)
+	newConfigRouter(v1.Group("/admin/config", requireSession), reloader)
 }
 
 func ping(c echo.Context) error {
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+)
+
+const signInAttemptsDefaultLimit = 50
+
+type signInRouter struct {
+	attempts service.SignIn
+}
+
+func newSignInRouter(g *echo.Group, attempts service.SignIn) {
+	r := &signInRouter{
+		attempts: attempts,
+	}
+
+	g.GET("", r.list)
+}
+
+type signInAttemptsInput struct {
+	Limit int `query:"limit" validate:"min=0,max=100"`
+}
+
+//	@Summary		Sign in history
+//	@Description	Get recent sign in attempts with the user username, newest first: ip, user agent, method (password, 2fa, oidc) and result
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"number of attempts, default 50, max 100"
+//	@Success		200		{array}		service.SignInAttemptOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/sign-ins [get]
+func (r *signInRouter) list(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+	return listSignInAttempts(c, r.attempts, username)
+}
+
+// listSignInAttempts отвечает историей входов пользователя username, общая для пользователя и администратора
+func listSignInAttempts(c echo.Context, attempts service.SignIn, username string) error {
+	var input signInAttemptsInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+	if input.Limit == 0 {
+		input.Limit = signInAttemptsDefaultLimit
+	}
+
+	output, err := attempts.FindByUser(c.Request().Context(), username, input.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt"
+	"time"
+	"todolist_api/internal/service"
+)
+
+// Заглушки сервисов для тестов обработчиков. Встроенный интерфейс остается nil: вызов метода, который заглушка
+// не переопределяет, роняет тест
+
+type stubAuth struct {
+	service.Auth
+}
+
+func (s *stubAuth) CreateToken(_ context.Context, username string) (string, error) {
+	return "token-" + username, nil
+}
+
+type stubTwoFactor struct {
+	service.TwoFactor
+}
+
+func (s *stubTwoFactor) Required(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+type stubSignIn struct {
+	service.SignIn
+	recorded []service.SignInAttemptInput
+}
+
+func (s *stubSignIn) Record(_ context.Context, _ int, input service.SignInAttemptInput) {
+	s.recorded = append(s.recorded, input)
+}
+
+func (s *stubSignIn) Cancel(context.Context, int) {}
+
+// Токены, которые принимают заглушки stubAuth и stubPersonalToken
+const (
+	stubJWT         = "jwt-valid"
+	stubJWTExpired  = "jwt-expired"
+	stubJWTRevoked  = "jwt-revoked"
+	stubJWTDisabled = "jwt-disabled"
+
+	stubTokenTasksRead = service.PersonalTokenPrefix + "tasks-read"
+	stubTokenAll       = service.PersonalTokenPrefix + "all"
+	stubTokenRevoked   = service.PersonalTokenPrefix + "revoked"
+	stubTokenDisabled  = service.PersonalTokenPrefix + "disabled"
+)
+
+func (s *stubAuth) ParseToken(token string) (*service.TokenClaims, error) {
+	switch token {
+	case stubJWT, stubJWTRevoked, stubJWTDisabled:
+		return &service.TokenClaims{Username: token}, nil
+	case stubJWTExpired:
+		return nil, jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired)
+	}
+	return nil, service.ErrCannotParseToken
+}
+
+type stubUser struct {
+	service.User
+}
+
+func (s *stubUser) VerifySession(_ context.Context, username string, _ time.Time) error {
+	switch username {
+	case stubJWTRevoked:
+		return service.ErrSessionExpired
+	case stubJWTDisabled:
+		return service.ErrUserDisabled
+	}
+	return nil
+}
+
+type stubPersonalToken struct {
+	service.PersonalToken
+}
+
+func (s *stubPersonalToken) Authenticate(_ context.Context, token string) (service.PersonalTokenIdentity, error) {
+	switch token {
+	case stubTokenTasksRead:
+		return service.PersonalTokenIdentity{Username: "vasya", Scopes: []string{service.ScopeTasksRead}}, nil
+	case stubTokenAll:
+		return service.PersonalTokenIdentity{Username: "vasya", Scopes: service.PersonalTokenScopes}, nil
+	case stubTokenDisabled:
+		return service.PersonalTokenIdentity{}, service.ErrUserDisabled
+	}
+	return service.PersonalTokenIdentity{}, service.ErrInvalidToken
+}
+
+type stubTask struct {
+	service.Task
+}
+
+func (s *stubTask) Find(context.Context, string, string) ([]service.TaskOutput, error) {
+	return []service.TaskOutput{}, nil
+}
@@ -15,22 +15,35 @@ type taskRouter struct {
 
 func newTaskRouter(g *echo.Group, task service.Task) {
 	r := &taskRouter{task: task}
+	read, write := requireScope(service.ScopeTasksRead), requireScope(service.ScopeTasksWrite)
 
-	g.POST("", r.create)
-	g.GET("", r.list)
-	g.GET("/:id", r.findById)
-	g.PUT("/:id", r.update)
-	g.DELETE("/:id", r.delete)
+	g.POST("", r.create, write)
+	g.POST("/quick", r.quickAdd, write)
+	g.GET("", r.list, read)
+	g.GET("/:id", r.findById, read)
+	g.PUT("/:id", r.update, write)
+	g.DELETE("/:id", r.delete, write)
+	g.POST("/:id/move", r.move, write)
+	g.POST("/:id/blocked-by", r.addDependency, write)
+	g.DELETE("/:id/blocked-by/:blocker_id", r.removeDependency, write)
+	g.GET("/:id/critical-path", r.criticalPath, read)
 }
 
 type taskCreateInput struct {
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description" validate:"required"`
-	DueDate     time.Time `json:"due_date" validate:"required"`
+	Title            string    `json:"title" validate:"required"`
+	Description      string    `json:"description" validate:"required"`
+	DueDate          time.Time `json:"due_date" validate:"required"`
+	AllDay           bool      `json:"all_day"`
+	Priority         int       `json:"priority" validate:"omitempty,min=1,max=4"`
+	Status           string    `json:"status" validate:"omitempty,oneof=todo in_progress done"`
+	Tags             []string  `json:"tags" validate:"max=20,dive,required,max=64"`
+	EstimatedMinutes int       `json:"estimated_minutes" validate:"min=0"`
+	Recurrence       string    `json:"recurrence" validate:"omitempty,rrule"`
 }
 
 //	@Summary		Create task
-//	@Description	Create task
+//	@Description	Create task. For all-day task (all_day) only the date of due_date is kept, without time.
+//	@Description	Task dates in response are in user timezone
 //	@Tags			task
 //	@Accept			json
 //	@Produce		json
@@ -59,10 +72,16 @@ func (r *taskRouter) create(c echo.Context) error {
 	}
 
 	task, err := r.task.Create(c.Request().Context(), service.TaskCreateInput{
-		Username:    username,
-		Title:       input.Title,
-		Description: input.Description,
-		DueDate:     input.DueDate,
+		Username:         username,
+		Title:            input.Title,
+		Description:      input.Description,
+		DueDate:          input.DueDate,
+		AllDay:           input.AllDay,
+		Priority:         input.Priority,
+		Status:           input.Status,
+		Tags:             input.Tags,
+		EstimatedMinutes: input.EstimatedMinutes,
+		Recurrence:       input.Recurrence,
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
@@ -75,24 +94,107 @@ func (r *taskRouter) create(c echo.Context) error {
 	return c.JSON(http.StatusCreated, task)
 }
 
+type taskQuickAddInput struct {
+	Text    string `json:"text" validate:"required,max=1024"`
+	Preview bool   `json:"preview"`
+}
+
+//	@Summary		Quick add task
+//	@Description	Create task from a single line like "Pay rent tomorrow 9am #finance !p1 every month": due date
+//	@Description	(in user timezone), #tags, priority !p1 - !p4 and recurrence are parsed out, the rest is the title.
+//	@Description	With preview the parsed task is returned (200) and nothing is created
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		taskQuickAddInput	true	"input"
+//	@Success		200		{object}	service.TaskPreviewOutput
+//	@Success		201		{object}	service.TaskOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/quick [post]
+func (r *taskRouter) quickAdd(c echo.Context) error {
+	var input taskQuickAddInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	quick := service.TaskQuickAddInput{
+		Username: username,
+		Text:     input.Text,
+	}
+	if input.Preview {
+		preview, err := r.task.PreviewQuick(c.Request().Context(), quick)
+		if err != nil {
+			if errors.Is(err, service.ErrQuickAddTitle) || errors.Is(err, service.ErrUserNotFound) {
+				errorResponse(c, http.StatusBadRequest, err)
+				return nil
+			}
+			errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+			return err
+		}
+		return c.JSON(http.StatusOK, preview)
+	}
+
+	task, err := r.task.CreateQuick(c.Request().Context(), quick)
+	if err != nil {
+		if errors.Is(err, service.ErrQuickAddTitle) || errors.Is(err, service.ErrQuickAddDueDate) ||
+			errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, task)
+}
+
+type taskListInput struct {
+	Sort string `query:"sort" validate:"omitempty,oneof=priority position"`
+}
+
 //	@Summary		Get tasks
-//	@Description	Get list of all user tasks
+//	@Description	Get list of all user tasks. By default tasks are sorted by creation order
 //	@Tags			task
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{array}		service.TaskOutput
-//	@Failure		400	{object}	echo.HTTPError
-//	@Failure		500	{object}	echo.HTTPError
+//	@Param			sort	query		string	false	"sort order"	Enums(priority, position)
+//	@Success		200		{array}		service.TaskOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
 //	@Security		JWT
 //	@Router			/api/v1/tasks [get]
 func (r *taskRouter) list(c echo.Context) error {
+	var input taskListInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
 	username, ok := c.Get(usernameCtx).(string)
 	if !ok {
 		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
 		return nil
 	}
 
-	tasks, err := r.task.Find(c.Request().Context(), username)
+	tasks, err := r.task.Find(c.Request().Context(), username, input.Sort)
 	if err != nil {
 		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
 		return err
@@ -136,13 +238,19 @@ func (r *taskRouter) findById(c echo.Context) error {
 }
 
 type taskUpdateInput struct {
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description" validate:"required"`
-	DueDate     time.Time `json:"due_date" validate:"required"`
+	Title            string    `json:"title" validate:"required"`
+	Description      string    `json:"description" validate:"required"`
+	DueDate          time.Time `json:"due_date" validate:"required"`
+	AllDay           bool      `json:"all_day"`
+	Priority         int       `json:"priority" validate:"omitempty,min=1,max=4"`
+	Status           string    `json:"status" validate:"omitempty,oneof=todo in_progress done"`
+	Tags             []string  `json:"tags" validate:"max=20,dive,required,max=64"`
+	EstimatedMinutes int       `json:"estimated_minutes" validate:"min=0"`
+	Recurrence       string    `json:"recurrence" validate:"omitempty,rrule"`
 }
 
 //	@Summary		Update task
-//	@Description	Update user task by id
+//	@Description	Update user task by id. Omitted status keeps the current one
 //	@Tags			task
 //	@Accept			json
 //	@Produce		json
@@ -178,11 +286,17 @@ func (r *taskRouter) update(c echo.Context) error {
 	}
 
 	task, err := r.task.Update(c.Request().Context(), service.TaskUpdateInput{
-		Id:          id,
-		Username:    username,
-		Title:       input.Title,
-		Description: input.Description,
-		DueDate:     input.DueDate,
+		Id:               id,
+		Username:         username,
+		Title:            input.Title,
+		Description:      input.Description,
+		DueDate:          input.DueDate,
+		AllDay:           input.AllDay,
+		Priority:         input.Priority,
+		Status:           input.Status,
+		Tags:             input.Tags,
+		EstimatedMinutes: input.EstimatedMinutes,
+		Recurrence:       input.Recurrence,
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrTaskNotFound) {
@@ -228,3 +342,209 @@ func (r *taskRouter) delete(c echo.Context) error {
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+type taskMoveInput struct {
+	BeforeId int `json:"before_id"`
+	AfterId  int `json:"after_id"`
+}
+
+//	@Summary		Move task
+//	@Description	Move user task directly before or after another task. Exactly one of before_id, after_id must be set
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"id"
+//	@Param			input	body		taskMoveInput	true	"input"
+//	@Success		200		{object}	service.TaskOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/move [post]
+func (r *taskRouter) move(c echo.Context) error {
+	var input taskMoveInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	task, err := r.task.Move(c.Request().Context(), service.TaskMoveInput{
+		Id:       id,
+		Username: username,
+		BeforeId: input.BeforeId,
+		AfterId:  input.AfterId,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTaskMoveTarget) || errors.Is(err, service.ErrTaskMoveItself) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+type taskDependencyInput struct {
+	TaskId int `json:"task_id" validate:"required"`
+}
+
+//	@Summary		Add blocker
+//	@Description	Mark task as blocked by another user task. Dependencies must not form a cycle
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"id"
+//	@Param			input	body		taskDependencyInput	true	"input"
+//	@Success		200		{object}	service.TaskOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		409		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/blocked-by [post]
+func (r *taskRouter) addDependency(c echo.Context) error {
+	var input taskDependencyInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	task, err := r.task.AddDependency(c.Request().Context(), service.TaskDependencyInput{
+		TaskId:      id,
+		BlockedById: input.TaskId,
+		Username:    username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrDependencyItself) || errors.Is(err, service.ErrDependencyAlreadyExists) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrDependencyCycle) {
+			errorResponse(c, http.StatusConflict, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+//	@Summary		Remove blocker
+//	@Description	Remove "blocked by" link between user tasks
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int	true	"id"
+//	@Param			blocker_id	path	int	true	"blocker task id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/blocked-by/{blocker_id} [delete]
+func (r *taskRouter) removeDependency(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	blockerId, err := strconv.Atoi(c.Param("blocker_id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	err = r.task.RemoveDependency(c.Request().Context(), service.TaskDependencyInput{
+		TaskId:      id,
+		BlockedById: blockerId,
+		Username:    username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrDependencyNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+//	@Summary		Get critical path
+//	@Description	Get the longest chain of unfinished tasks blocking the task, from the first task to do to the task itself.
+//	@Description	Among chains of equal length the one with the later due date of the nearest blocker wins
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"id"
+//	@Success		200	{array}		service.TaskOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/critical-path [get]
+func (r *taskRouter) criticalPath(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	tasks, err := r.task.CriticalPath(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, tasks)
+}
@@ -0,0 +1,347 @@
+package v1
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"todolist_api/internal/service"
+)
+
+type timeEntryRouter struct {
+	timeEntry service.TimeEntry
+}
+
+func newTimeEntryRouter(g *echo.Group, timeEntry service.TimeEntry) {
+	r := &timeEntryRouter{timeEntry: timeEntry}
+	read, write := requireScope(service.ScopeTasksRead), requireScope(service.ScopeTasksWrite)
+
+	g.POST("/tasks/:id/timer/start", r.startTimer, write)
+	g.POST("/tasks/:id/timer/stop", r.stopTimer, write)
+	g.GET("/timer", r.running, read)
+
+	g.POST("/tasks/:id/time-entries", r.create, write)
+	g.GET("/tasks/:id/time-entries", r.listByTask, read)
+	g.DELETE("/time-entries/:id", r.delete, write)
+
+	g.GET("/time-report", r.report, read)
+}
+
+//	@Summary		Start timer
+//	@Description	Start timer on user task. Only one timer per user can run at a time
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"task id"
+//	@Success		201	{object}	service.TimeEntryOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		409	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/timer/start [post]
+func (r *timeEntryRouter) startTimer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	entry, err := r.timeEntry.StartTimer(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrTimerAlreadyRunning) {
+			errorResponse(c, http.StatusConflict, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, entry)
+}
+
+//	@Summary		Stop timer
+//	@Description	Stop running timer on user task
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"task id"
+//	@Success		200	{object}	service.TimeEntryOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/timer/stop [post]
+func (r *timeEntryRouter) stopTimer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	entry, err := r.timeEntry.StopTimer(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrTimerNotRunning) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+//	@Summary		Get running timer
+//	@Description	Get currently running user timer
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	service.TimeEntryOutput
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/timer [get]
+func (r *timeEntryRouter) running(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	entry, err := r.timeEntry.FindRunning(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, service.ErrTimerNotRunning) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+type timeEntryInput struct {
+	StartedAt time.Time `json:"started_at" validate:"required"`
+	EndedAt   time.Time `json:"ended_at" validate:"required"`
+	Note      string    `json:"note" validate:"max=1000"`
+}
+
+//	@Summary		Create time entry
+//	@Description	Log time spent on user task manually
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"task id"
+//	@Param			input	body		timeEntryInput	true	"input"
+//	@Success		201		{object}	service.TimeEntryOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/time-entries [post]
+func (r *timeEntryRouter) create(c echo.Context) error {
+	var input timeEntryInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	entry, err := r.timeEntry.Create(c.Request().Context(), service.TimeEntryInput{
+		TaskId:    id,
+		Username:  username,
+		StartedAt: input.StartedAt,
+		EndedAt:   input.EndedAt,
+		Note:      input.Note,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTimeEntryInvalidRange) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusCreated, entry)
+}
+
+//	@Summary		Get time entries
+//	@Description	Get time entries of user task, including running timer
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"task id"
+//	@Success		200	{array}		service.TimeEntryOutput
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/tasks/{id}/time-entries [get]
+func (r *timeEntryRouter) listByTask(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	entries, err := r.timeEntry.FindByTask(c.Request().Context(), id, username)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+//	@Summary		Delete time entry
+//	@Description	Delete user time entry by id
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"id"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/time-entries/{id} [delete]
+func (r *timeEntryRouter) delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+	if err = r.timeEntry.Delete(c.Request().Context(), id, username); err != nil {
+		if errors.Is(err, service.ErrTimeEntryNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type timeReportInput struct {
+	From    time.Time `query:"from" validate:"required"`
+	To      time.Time `query:"to" validate:"required"`
+	GroupBy string    `query:"group_by" validate:"omitempty,oneof=day project tag"`
+	Format  string    `query:"format" validate:"omitempty,oneof=json csv"`
+}
+
+//	@Summary		Get time report
+//	@Description	Get time tracked by user for entries started in [from, to), grouped by day, project (board) or tag.
+//	@Description	Entries of tasks without board or tags fall into the group with empty key
+//	@Tags			time
+//	@Accept			json
+//	@Produce		json,text/csv
+//	@Param			from		query		string	true	"RFC3339 start of the period"
+//	@Param			to			query		string	true	"RFC3339 end of the period"
+//	@Param			group_by	query		string	false	"grouping, day by default"	Enums(day, project, tag)
+//	@Param			format		query		string	false	"response format, json by default"	Enums(json, csv)
+//	@Success		200			{object}	service.TimeReportOutput
+//	@Failure		400			{object}	echo.HTTPError
+//	@Failure		500			{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/time-report [get]
+func (r *timeEntryRouter) report(c echo.Context) error {
+	var input timeReportInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	report, err := r.timeEntry.Report(c.Request().Context(), service.TimeReportInput{
+		Username: username,
+		From:     input.From,
+		To:       input.To,
+		GroupBy:  input.GroupBy,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTimeReportRange) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+
+	if input.Format != "csv" {
+		return c.JSON(http.StatusOK, report)
+	}
+	return writeTimeReportCSV(c, report)
+}
+
+func writeTimeReportCSV(c echo.Context, report service.TimeReportOutput) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="time-report-%s.csv"`, report.GroupBy))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	_ = w.Write([]string{report.GroupBy, "minutes", "entries"})
+	for _, row := range report.Rows {
+		_ = w.Write([]string{row.Key, strconv.Itoa(row.Minutes), strconv.Itoa(row.Entries)})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,235 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+)
+
+type twoFactorRouter struct {
+	twoFactor service.TwoFactor
+}
+
+func newTwoFactorRouter(g *echo.Group, twoFactor service.TwoFactor) {
+	r := &twoFactorRouter{
+		twoFactor: twoFactor,
+	}
+
+	g.POST("", r.enroll)
+	g.GET("/qr", r.qrCode)
+	g.POST("/confirm", r.confirm)
+	g.DELETE("", r.disable)
+	g.POST("/recovery-codes", r.regenerateCodes)
+}
+
+type twoFactorEnrollInput struct {
+	Password string `json:"password" validate:"required"`
+}
+
+//	@Summary		Start two-factor enrollment
+//	@Description	Generate TOTP secret for authenticator app. Two-factor authentication is enabled only after confirmation
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		twoFactorEnrollInput	true	"input"
+//	@Success		200		{object}	service.TwoFactorEnrollOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/2fa [post]
+func (r *twoFactorRouter) enroll(c echo.Context) error {
+	var input twoFactorEnrollInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	output, err := r.twoFactor.Enroll(c.Request().Context(), service.UserInput{
+		Username: username,
+		Password: input.Password,
+	})
+	if err != nil {
+		return r.twoFactorError(c, err)
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+//	@Summary		Two-factor QR code
+//	@Description	Get PNG QR code of the TOTP secret for authenticator app while enrollment is not confirmed
+//	@Tags			two-factor
+//	@Produce		image/png
+//	@Success		200	{file}		binary
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/2fa/qr [get]
+func (r *twoFactorRouter) qrCode(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	png, err := r.twoFactor.QRCode(c.Request().Context(), username)
+	if err != nil {
+		return r.twoFactorError(c, err)
+	}
+	// QR-код содержит секрет, поэтому не должен оседать в кешах
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Blob(http.StatusOK, "image/png", png)
+}
+
+type twoFactorConfirmInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+//	@Summary		Confirm two-factor enrollment
+//	@Description	Enable two-factor authentication with a code from authenticator app. Recovery codes are returned only once
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		twoFactorConfirmInput	true	"input"
+//	@Success		200		{object}	service.TwoFactorCodesOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/2fa/confirm [post]
+func (r *twoFactorRouter) confirm(c echo.Context) error {
+	var input twoFactorConfirmInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	codes, err := r.twoFactor.Confirm(c.Request().Context(), username, input.Code)
+	if err != nil {
+		return r.twoFactorError(c, err)
+	}
+	return c.JSON(http.StatusOK, codes)
+}
+
+type twoFactorInput struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+//	@Summary		Disable two-factor authentication
+//	@Description	Disable two-factor authentication. Password and a code from authenticator app (or a recovery code) are required
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body	twoFactorInput	true	"input"
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		403	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/2fa [delete]
+func (r *twoFactorRouter) disable(c echo.Context) error {
+	var input twoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	err := r.twoFactor.Disable(c.Request().Context(), service.TwoFactorInput{
+		Username: username,
+		Password: input.Password,
+		Code:     input.Code,
+	})
+	if err != nil {
+		return r.twoFactorError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+//	@Summary		Regenerate recovery codes
+//	@Description	Replace recovery codes with new ones. Password and a code from authenticator app (or a recovery code) are required
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		twoFactorInput	true	"input"
+//	@Success		200		{object}	service.TwoFactorCodesOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/2fa/recovery-codes [post]
+func (r *twoFactorRouter) regenerateCodes(c echo.Context) error {
+	var input twoFactorInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	codes, err := r.twoFactor.RegenerateCodes(c.Request().Context(), service.TwoFactorInput{
+		Username: username,
+		Password: input.Password,
+		Code:     input.Code,
+	})
+	if err != nil {
+		return r.twoFactorError(c, err)
+	}
+	return c.JSON(http.StatusOK, codes)
+}
+
+func (r *twoFactorRouter) twoFactorError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrUserWrongPassword) || errors.Is(err, service.ErrTwoFactorCode) {
+		errorResponse(c, http.StatusForbidden, err)
+		return nil
+	}
+	if errors.Is(err, service.ErrTwoFactorEnabled) || errors.Is(err, service.ErrTwoFactorDisabled) ||
+		errors.Is(err, service.ErrTwoFactorNotPending) || errors.Is(err, service.ErrUserNotFound) {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+	errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+	return err
+}
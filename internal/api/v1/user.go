@@ -0,0 +1,308 @@
+package v1
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"todolist_api/internal/service"
+)
+
+type userRouter struct {
+	auth service.Auth
+	user service.User
+}
+
+func newUserRouter(g *echo.Group, auth service.Auth, user service.User) {
+	r := &userRouter{
+		auth: auth,
+		user: user,
+	}
+
+	g.GET("", r.find)
+	g.PATCH("", r.update)
+	g.DELETE("", r.delete)
+	g.DELETE("/deletion", r.cancelDeletion)
+	g.POST("/password", r.changePassword)
+	g.POST("/username", r.changeUsername)
+}
+
+//	@Summary		Get profile
+//	@Description	Get profile of current user
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	service.UserOutput
+//	@Failure		404	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me [get]
+func (r *userRouter) find(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	user, err := r.user.FindByUsername(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+type userUpdateInput struct {
+	DisplayName *string        `json:"display_name" validate:"omitempty,max=64"`
+	Email       *string        `json:"email" validate:"omitempty,email"`
+	Timezone    *string        `json:"timezone"`
+	Preferences map[string]any `json:"preferences" validate:"omitempty,max=50"`
+}
+
+//	@Summary		Update profile
+//	@Description	Update profile of current user, omitted fields are kept. timezone is IANA name (for example Europe/Moscow),
+//	@Description	task dates are shown in it. preferences is an arbitrary object for client settings.
+//	@Description	A new email gets a verification link. If another account has already verified it, the email is not saved and its owner is notified
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		userUpdateInput	true	"input"
+//	@Success		200		{object}	service.UserOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		404		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me [patch]
+func (r *userRouter) update(c echo.Context) error {
+	var input userUpdateInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	user, err := r.user.Update(c.Request().Context(), service.UserUpdateInput{
+		Username:    username,
+		DisplayName: input.DisplayName,
+		Email:       input.Email,
+		Timezone:    input.Timezone,
+		Preferences: input.Preferences,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserTimezone) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+type userPasswordInput struct {
+	Password    string `json:"password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+//	@Summary		Change password
+//	@Description	Change password of current user. All issued tokens, including personal access tokens, are revoked, a new token for the current session is returned
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		userPasswordInput	true	"input"
+//	@Success		200		{object}	signInResponse
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/password [post]
+func (r *userRouter) changePassword(c echo.Context) error {
+	var input userPasswordInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	err := r.user.ChangePassword(c.Request().Context(), service.UserPasswordInput{
+		Username:    username,
+		Password:    input.Password,
+		NewPassword: input.NewPassword,
+	})
+	if err != nil {
+		return r.accountError(c, err)
+	}
+	return r.newToken(c, username)
+}
+
+type userRenameInput struct {
+	Username string `json:"username" validate:"username,required"`
+	Password string `json:"password" validate:"required"`
+}
+
+//	@Summary		Change username
+//	@Description	Rename current user, all user data is kept. Tokens with the old username are revoked, a new token is returned
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		userRenameInput	true	"input"
+//	@Success		200		{object}	signInResponse
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/username [post]
+func (r *userRouter) changeUsername(c echo.Context) error {
+	var input userRenameInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	err := r.user.ChangeUsername(c.Request().Context(), service.UserRenameInput{
+		Username:    username,
+		Password:    input.Password,
+		NewUsername: input.Username,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUserAlreadyExists) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		return r.accountError(c, err)
+	}
+	return r.newToken(c, input.Username)
+}
+
+type userDeleteInput struct {
+	Password string `json:"password" validate:"required"`
+}
+
+//	@Summary		Delete account
+//	@Description	Schedule deletion of current user with all tasks, boards and other data. Password confirmation is required.
+//	@Description	The account is erased at delete_at, until then deletion can be cancelled
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		userDeleteInput	true	"input"
+//	@Success		202		{object}	service.UserDeletionOutput
+//	@Failure		400		{object}	echo.HTTPError
+//	@Failure		403		{object}	echo.HTTPError
+//	@Failure		500		{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me [delete]
+func (r *userRouter) delete(c echo.Context) error {
+	var input userDeleteInput
+
+	if err := c.Bind(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, echo.ErrBadRequest)
+		return nil
+	}
+	if err := c.Validate(&input); err != nil {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	deletion, err := r.user.Delete(c.Request().Context(), service.UserInput{
+		Username: username,
+		Password: input.Password,
+	})
+	if err != nil {
+		return r.accountError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, deletion)
+}
+
+//	@Summary		Cancel account deletion
+//	@Description	Cancel scheduled deletion of current user
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		204
+//	@Failure		400	{object}	echo.HTTPError
+//	@Failure		500	{object}	echo.HTTPError
+//	@Security		JWT
+//	@Router			/api/v1/me/deletion [delete]
+func (r *userRouter) cancelDeletion(c echo.Context) error {
+	username, ok := c.Get(usernameCtx).(string)
+	if !ok {
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return nil
+	}
+
+	if err := r.user.CancelDeletion(c.Request().Context(), username); err != nil {
+		if errors.Is(err, service.ErrUserNotScheduled) || errors.Is(err, service.ErrUserNotFound) {
+			errorResponse(c, http.StatusBadRequest, err)
+			return nil
+		}
+		errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// accountError отвечает на ошибки операций, подтверждаемых паролем
+func (r *userRouter) accountError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrUserWrongPassword) {
+		errorResponse(c, http.StatusForbidden, err)
+		return nil
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		errorResponse(c, http.StatusBadRequest, err)
+		return nil
+	}
+	errorResponse(c, http.StatusInternalServerError, echo.ErrInternalServerError)
+	return err
+}
+
+func (r *userRouter) newToken(c echo.Context, username string) error {
+	token, err := r.auth.CreateToken(c.Request().Context(), username)
+	if err != nil {
+		return createTokenError(c, err)
+	}
+	return c.JSON(http.StatusOK, signInResponse{Token: token})
+}
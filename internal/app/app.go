@@ -1,20 +1,45 @@
 package app
 
 import (
-	"github.com/joho/godotenv"
+	"context"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 	"todolist_api/config"
 	v1 "todolist_api/internal/api/v1"
+	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo"
 	"todolist_api/internal/service"
+	"todolist_api/pkg/feature"
 	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/health"
 	"todolist_api/pkg/httpserver"
+	"todolist_api/pkg/jwtkeys"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/mailer"
+	"todolist_api/pkg/metrics"
+	"todolist_api/pkg/notifier"
+	"todolist_api/pkg/oidc"
 	"todolist_api/pkg/postgres"
+	"todolist_api/pkg/ratelimit"
+	"todolist_api/pkg/tracing"
 	"todolist_api/pkg/validator"
+	"todolist_api/pkg/worker"
+)
+
+const (
+	tracingShutdownTimeout = 5 * time.Second
+	// account emails (verification, password reset) are sent in the background through a bounded queue
+	mailQueueSize    = 100
+	mailQueueWorkers = 2
+	mailTimeout      = 30 * time.Second
 )
 
 //	@title			Api for tasks
@@ -27,34 +52,81 @@ import (
 //	@securityDefinitions.apikey	JWT
 //	@in							header
 //	@name						Authorization
-//	@description				JWT token
+//	@description				JWT token or personal access token (tdl_...) as "Bearer <token>"
 
-func Run() {
-	// config
-	cfg, err := config.NewConfig()
+func Run(cfg *config.Config, opts *config.Options) {
+	// set up json logger, output is shared with http request logs and reopened on config reload
+	logOutput, err := logger.OpenOutput(cfg.Log.Output)
 	if err != nil {
-		log.Fatalf("Config error: %s", err)
+		log.Fatalf("Opening log output error: %s", err)
+	}
+	setLogger(cfg.Log.Level, logOutput)
+
+	// tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Initializing tracing error: %s", err)
 	}
-	// set up json logger
-	setLogger(cfg.Log.Level, cfg.Log.Output)
 
 	// postgresql database
-	pg, err := postgres.NewPG(cfg.PG.Url, postgres.MaxPoolSize(cfg.PG.MaxPoolSize))
+	pgOpts := []postgres.Option{
+		postgres.MaxPoolSize(cfg.PG.MaxPoolSize),
+		postgres.ConnAttempts(cfg.PG.ConnAttempts),
+		postgres.ConnTimeout(cfg.PG.ConnTimeout),
+	}
+	if cfg.Tracing.Exporter != tracing.ExporterNone {
+		pgOpts = append(pgOpts, postgres.Tracer(tracing.NewQueryTracer(nil)))
+	}
+	pg, err := postgres.NewPG(cfg.PG.Url, pgOpts...)
 	if err != nil {
 		log.Fatalf("Initializing postgres error: %s", err)
 	}
 	defer pg.Close()
 
+	// prometheus metrics
+	var reg *prometheus.Registry
+	if cfg.Metrics.Port != "" {
+		reg = metrics.NewRegistry()
+		reg.MustRegister(metrics.NewPoolCollector(pg.Stat))
+	}
+
+	// background sender of account emails, drained on shutdown
+	mailQueue := worker.NewQueue(mailQueueSize, mailQueueWorkers, mailTimeout)
+
 	d := &service.ServicesDependencies{
-		Repos:    repo.NewRepositories(pg),
-		Hasher:   hasher.NewHasher(cfg.Hasher.Secret),
-		SignKey:  cfg.JWT.SignKey,
-		TokenTTL: cfg.JWT.TokenTTL,
+		Repos:          repo.NewRepositories(pg),
+		Hasher:         hasher.NewHasher(cfg.Hasher.Secret),
+		SignKey:        cfg.JWT.SignKey,
+		TokenTTL:       cfg.JWT.TokenTTL,
+		AuthKeys:       newAuthKeys(cfg),
+		Notifiers:      newNotifiers(cfg),
+		ExportTTL:      cfg.Export.TTL,
+		ExportLinkKey:  cfg.Export.LinkKey,
+		DeletionGrace:  cfg.Account.DeletionGrace,
+		Mailer:         newMailer(cfg),
+		MailQueue:      mailQueue,
+		AppURL:         cfg.Account.AppURL,
+		VerifyTokenTTL: cfg.Account.VerifyTokenTTL,
+		ResetTokenTTL:  cfg.Account.ResetTokenTTL,
+		TOTPIssuer:     cfg.Account.TOTPIssuer,
+		Lockout:        newLockoutPolicy(cfg),
+		OIDCProviders:  newOIDCProviders(cfg),
+		OIDCLoginTTL:   cfg.OIDC.LoginTTL,
+		Metrics:        newServiceMetrics(reg),
+
+		UsernameMinLength: cfg.Account.UsernameMinLength,
+		UsernameMaxLength: cfg.Account.UsernameMaxLength,
 	}
 	services := service.NewServices(d)
 
 	// validator for incoming messages
-	v, err := validator.NewValidator()
+	v, err := validator.NewValidator(validator.UsernameLength(cfg.Account.UsernameMinLength, cfg.Account.UsernameMaxLength))
 	if err != nil {
 		log.Fatalf("Initializing handler validator error: %s", err)
 	}
@@ -62,37 +134,333 @@ func Run() {
 	// handler
 	handler := echo.New()
 	handler.Validator = v
-	v1.LoggingMiddleware(handler, cfg.Log.Output)
-	v1.NewRouter(handler, services)
+	handler.IPExtractor = echo.ExtractIPDirect()
+	if cfg.HTTP.TrustProxy {
+		handler.IPExtractor = echo.ExtractIPFromXFFHeader()
+	}
+	v1.LoggingMiddleware(handler, logOutput)
+	v1.RequestIDMiddleware(handler)
+	if reg != nil {
+		v1.MetricsMiddleware(handler, reg)
+	}
+	v1.TracingMiddleware(handler)
+	cors := v1.NewCORS(cfg.HTTP.CORSOrigins)
+	v1.CORSMiddleware(handler, cors)
+	rateLimits, rateLimitWorker := newRateLimits(cfg, d.Repos)
+	limiter := v1.NewRateLimiter(rateLimits)
+	features := feature.NewFlags(cfg.Features.Enabled)
+	// reloads part of the config on SIGHUP and POST /api/v1/admin/config/reload
+	reloader := &reloader{
+		opts:       opts,
+		current:    cfg,
+		logOutput:  logOutput,
+		rateLimits: limiter,
+		cors:       cors,
+		features:   features,
+		auth:       services.Auth,
+	}
+	// readiness checks, workers are added below as they start
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.OnChange(logReadiness)
+	checker.Add("postgres", pg.Ping)
+	checker.Add("migrations", newMigrationCheck(d.Repos.Migration))
+	v1.NewRouter(handler, services, limiter, checker, features, reloader)
 
 	// http server
-	httpServer := httpserver.NewServer(handler, httpserver.Port(cfg.HTTP.Port))
+	httpServer := httpserver.NewServer(handler,
+		httpserver.Port(cfg.HTTP.Port),
+		httpserver.ReadTimeout(cfg.HTTP.ReadTimeout),
+		httpserver.WriteTimeout(cfg.HTTP.WriteTimeout),
+		httpserver.ShutdownTimeout(cfg.HTTP.ShutdownTimeout),
+	)
+	// metrics server, also serves the detailed readiness report that is not exposed on the public port
+	var metricsServer *httpserver.Server
+	if reg != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(reg))
+		mux.Handle("/readyz", checker.Handler())
+		metricsServer = httpserver.NewServer(mux, httpserver.Port(cfg.Metrics.Port))
+	}
+
+	// reminders scheduler
+	reminderWorker := worker.NewWorker(func(ctx context.Context) {
+		_ = services.Reminder.SendDue(ctx)
+	}, worker.Interval(cfg.Reminder.Interval))
+	// digest emails scheduler
+	digestWorker := worker.NewWorker(func(ctx context.Context) {
+		_ = services.Digest.SendDue(ctx)
+	}, worker.Interval(cfg.Digest.Interval))
+	// user data exports builder
+	exportWorker := worker.NewWorker(func(ctx context.Context) {
+		_ = services.Export.BuildPending(ctx)
+	}, worker.Interval(cfg.Export.Interval))
+	// scheduled account deletions
+	erasureWorker := worker.NewWorker(func(ctx context.Context) {
+		_ = services.User.EraseScheduled(ctx)
+	}, worker.Interval(cfg.Account.Interval))
+	// workers run on every replica, so a stuck job is reported but does not take the replica out of the load balancer
+	checker.AddNonCritical("worker:reminder", reminderWorker.Check)
+	checker.AddNonCritical("worker:digest", digestWorker.Check)
+	checker.AddNonCritical("worker:export", exportWorker.Check)
+	checker.AddNonCritical("worker:erasure", erasureWorker.Check)
+	if rateLimitWorker != nil {
+		checker.AddNonCritical("worker:rate_limit", rateLimitWorker.Check)
+	}
 
 	log.Infof("App started! Listening port %s", cfg.HTTP.Port)
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+wait:
+	for {
+		select {
+		case <-hangup:
+			log.Info("app run, signal hangup, reloading config")
+			// ошибка уже записана в лог, работа продолжается с прежней конфигурацией
+			_, _ = reloader.Reload(context.Background())
 
-	select {
-	case s := <-interrupt:
-		log.Info("app run, signal " + s.String())
+		case s := <-interrupt:
+			log.Info("app run, signal " + s.String())
+			// /readyz начинает отвечать 503, и балансировщик успевает убрать инстанс до остановки http сервера
+			checker.Drain()
+			time.Sleep(cfg.Health.ShutdownDelay)
+			break wait
 
-	case err = <-httpServer.Notify():
-		log.Errorf("/app/run http server notify error: %s", err)
+		case err = <-httpServer.Notify():
+			log.Errorf("/app/run http server notify error: %s", err)
+			break wait
+		case err = <-notify(metricsServer):
+			log.Errorf("/app/run metrics server notify error: %s", err)
+			break wait
+		}
 	}
 	// graceful shutdown
 	if err = httpServer.Shutdown(); err != nil {
 		log.Errorf("/app/run http server shutdown error: %s", err)
 	}
+	if metricsServer != nil {
+		if err = metricsServer.Shutdown(); err != nil {
+			log.Errorf("/app/run metrics server shutdown error: %s", err)
+		}
+	}
+	// after the http server so that requests in flight can still enqueue their emails
+	if err = mailQueue.Shutdown(); err != nil {
+		log.Errorf("/app/run mail queue shutdown error: %s", err)
+	}
+	if err = reminderWorker.Shutdown(); err != nil {
+		log.Errorf("/app/run reminder worker shutdown error: %s", err)
+	}
+	if err = digestWorker.Shutdown(); err != nil {
+		log.Errorf("/app/run digest worker shutdown error: %s", err)
+	}
+	if err = exportWorker.Shutdown(); err != nil {
+		log.Errorf("/app/run export worker shutdown error: %s", err)
+	}
+	if err = erasureWorker.Shutdown(); err != nil {
+		log.Errorf("/app/run erasure worker shutdown error: %s", err)
+	}
+	if rateLimitWorker != nil {
+		if err = rateLimitWorker.Shutdown(); err != nil {
+			log.Errorf("/app/run rate limit worker shutdown error: %s", err)
+		}
+	}
+	// отправляем spans, накопленные до остановки
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err = shutdownTracing(ctx); err != nil {
+		log.Errorf("/app/run tracing shutdown error: %s", err)
+	}
 
 	log.Infof("App shutdown with exit code 0")
 }
 
-// loading environment params from .env
-func init() {
-	if _, ok := os.LookupEnv("HTTP_PORT"); !ok {
-		if err := godotenv.Load(); err != nil {
-			log.Fatalf("load env file error: %s", err)
+// logReadiness пишет в лог изменения готовности с ошибками компонентов, которые не отдаются наружу
+func logReadiness(report health.Report) {
+	failed := make(log.Fields)
+	for name, component := range report.Components {
+		if component.Status != health.StatusOK {
+			failed[name] = component.Error
+		}
+	}
+	switch {
+	case report.Status != health.StatusOK:
+		log.WithFields(failed).Warn("app readiness check failed")
+	case len(failed) > 0:
+		log.WithFields(failed).Warn("app readiness check ok, non-critical components failed")
+	default:
+		log.Info("app readiness check ok")
+	}
+}
+
+// newMigrationCheck готовность требует, чтобы версия схемы в базе совпадала с последней миграцией приложения
+func newMigrationCheck(migration repo.Migration) health.Check {
+	expected, err := latestMigrationVersion()
+	if err != nil {
+		log.Fatalf("Reading migrations error: %s", err)
+	}
+	return func(ctx context.Context) error {
+		version, dirty, err := migration.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != expected {
+			return fmt.Errorf("schema version is %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
+
+// notify канал ошибок сервера. Для отключенного сервера - nil канал, из которого ничего не приходит
+func notify(s *httpserver.Server) <-chan error {
+	if s == nil {
+		return nil
+	}
+	return s.Notify()
+}
+
+// newServiceMetrics бизнес-счетчики регистрируются, только если метрики включены
+func newServiceMetrics(reg *prometheus.Registry) *service.Metrics {
+	if reg == nil {
+		return nil
+	}
+	return service.NewMetrics(reg)
+}
+
+func newOIDCProviders(cfg *config.Config) map[string]service.OIDCProvider {
+	providers := make(map[string]service.OIDCProvider, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		providers[p.Name] = service.OIDCProvider{
+			Client: oidc.NewProvider(oidc.Config{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  p.RedirectURL,
+				Scopes:       p.Scopes,
+			}),
+			AutoProvision: p.AutoProvision,
+		}
+	}
+	return providers
+}
+
+func newLockoutPolicy(cfg *config.Config) service.LockoutPolicy {
+	return service.LockoutPolicy{
+		MaxAttempts:   cfg.Lockout.MaxAttempts,
+		IPMaxAttempts: cfg.Lockout.IPMaxAttempts,
+		Base:          cfg.Lockout.Base,
+		Max:           cfg.Lockout.Max,
+		Window:        cfg.Lockout.Window,
+		HistoryTTL:    cfg.Lockout.HistoryTTL,
+	}
+}
+
+// newRateLimits разбирает ограничения частоты запросов. Для хранилища в базе возвращает еще и воркер,
+// удаляющий корзины, к которым не обращались дольше самого длинного периода: они уже наполнились
+func newRateLimits(cfg *config.Config, repos *repo.Repositories) (v1.RateLimits, *worker.Worker) {
+	limits, err := parseRateLimits(cfg)
+	if err != nil {
+		log.Fatalf("Config error: %s", err)
+	}
+
+	switch cfg.RateLimit.Store {
+	case "memory":
+		limits.Store = ratelimit.NewMemory()
+		return limits, nil
+	case "postgres":
+		limits.Store = repos.RateLimit
+	default:
+		log.Fatalf("Config error: RATE_LIMIT_STORE must be memory or postgres, got %q", cfg.RateLimit.Store)
+	}
+
+	idle := max(limits.Auth.Period, limits.API.Period, limits.Public.Period)
+	w := worker.NewWorker(func(ctx context.Context) {
+		if _, err := repos.RateLimit.DeleteIdle(ctx, time.Now().Add(-idle)); err != nil {
+			log.Errorf("/app/rateLimitWorker error delete idle buckets: %s", err)
+		}
+	}, worker.Interval(cfg.RateLimit.CleanupInterval))
+	return limits, w
+}
+
+// parseRateLimits ограничения частоты запросов по группам, без хранилища
+func parseRateLimits(cfg *config.Config) (v1.RateLimits, error) {
+	var (
+		limits v1.RateLimits
+		err    error
+	)
+	for _, l := range []struct {
+		name  string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_AUTH", cfg.RateLimit.Auth, &limits.Auth},
+		{"RATE_LIMIT_API", cfg.RateLimit.API, &limits.API},
+		{"RATE_LIMIT_PUBLIC", cfg.RateLimit.Public, &limits.Public},
+	} {
+		if *l.limit, err = ratelimit.ParseLimit(l.value); err != nil {
+			return v1.RateLimits{}, fmt.Errorf("%s: %w", l.name, err)
+		}
+	}
+	return limits, nil
+}
+
+// newAuthKeys читает ключи подписи токенов из PEM файлов. Выведенным ключам закрытая часть не нужна
+func newAuthKeys(cfg *config.Config) service.AuthKeys {
+	keys := service.AuthKeys{AcceptLegacy: cfg.JWT.AcceptLegacy}
+	if cfg.JWT.PrivateKeyFile != "" {
+		key, err := jwtkeys.LoadFile(cfg.JWT.PrivateKeyFile)
+		if err != nil {
+			log.Fatalf("Loading jwt signing key error: %s", err)
 		}
+		if key.Private == nil {
+			log.Fatalf("Loading jwt signing key error: %s is not a private key", cfg.JWT.PrivateKeyFile)
+		}
+		keys.Signing = &key
+	}
+	retired, err := loadRetiredKeys(cfg.JWT.RetiredKeyFiles)
+	if err != nil {
+		log.Fatalf("Loading jwt retired key error: %s", err)
+	}
+	keys.Retired = retired
+	return keys
+}
+
+// loadRetiredKeys читает выведенные ключи, которыми токены только проверяются
+func loadRetiredKeys(paths []string) ([]jwtkeys.Key, error) {
+	var keys []jwtkeys.Key
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		key, err := jwtkeys.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func newNotifiers(cfg *config.Config) map[string]notifier.Notifier {
+	notifiers := map[string]notifier.Notifier{
+		dbmodel.ReminderChannelWebhook: notifier.NewWebhook(cfg.Webhook.Secret, cfg.Webhook.Timeout),
+	}
+	if cfg.SMTP.Host != "" {
+		notifiers[dbmodel.ReminderChannelEmail] = notifier.NewSMTP(
+			cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	}
+	return notifiers
+}
+
+// newMailer возвращает nil, если SMTP не настроен
+func newMailer(cfg *config.Config) mailer.Mailer {
+	if cfg.SMTP.Host == "" {
+		return nil
 	}
+	return mailer.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
 }
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"todolist_api/config"
+)
+
+const usage = `Usage: app [config flags] <command> [arguments]
+
+Commands:
+  serve [-migrate]   start http server, -migrate applies new migrations before start
+  migrate            manage database migrations, see "app migrate help"
+  config print       print effective config with secrets redacted
+
+Without a command serve is started. Config is read from flags, environment variables, .env file,
+YAML file (-config) and defaults, in that order of precedence. Config flags:
+`
+
+// Execute разбирает подкоманду из args (без имени программы) и запускает ее
+func Execute(args []string) error {
+	opts := config.NewOptions()
+	flags := newFlagSet("app", opts)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		return serve(opts, nil)
+	}
+	switch args[0] {
+	case "serve":
+		return serve(opts, args[1:])
+	case "migrate":
+		return runMigrate(opts, args[1:])
+	case "config":
+		return runConfig(opts, args[1:])
+	case "help":
+		flags.SetOutput(os.Stdout)
+		flags.Usage()
+		return nil
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// newFlagSet набор флагов с параметрами конфигурации, их можно указывать и до, и после команды
+func newFlagSet(name string, opts *config.Options) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	config.BindFlags(flags, opts)
+	return flags
+}
+
+func serve(opts *config.Options, args []string) error {
+	flags := newFlagSet("serve", opts)
+	autoMigrate := flags.Bool("migrate", false, "apply new migrations before start")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return errors.New("serve: unexpected arguments")
+	}
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	if *autoMigrate {
+		if err = migrateUp(cfg); err != nil {
+			return err
+		}
+	}
+	Run(cfg, opts)
+	return nil
+}
+
+func runConfig(opts *config.Options, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(`config: unknown command, expected "config print"`)
+	}
+	flags := newFlagSet("config print", opts)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	return cfg.Print(os.Stdout)
+}
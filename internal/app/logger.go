@@ -2,11 +2,10 @@ package app
 
 import (
 	"github.com/sirupsen/logrus"
-	"log"
-	"os"
+	"io"
 )
 
-func setLogger(level, output string) {
+func setLogger(level string, output io.Writer) {
 	logLevel, err := logrus.ParseLevel(level)
 	if err != nil {
 		logrus.SetLevel(logrus.DebugLevel)
@@ -16,13 +15,5 @@ func setLogger(level, output string) {
 	logrus.SetFormatter(&logrus.JSONFormatter{
 		TimestampFormat: "2006/01/02 15:04:05",
 	})
-	if output == "stdout" {
-		logrus.SetOutput(os.Stdout)
-	} else {
-		file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0755)
-		if err != nil {
-			log.Fatal(err)
-		}
-		logrus.SetOutput(file)
-	}
+	logrus.SetOutput(output)
 }
@@ -2,53 +2,272 @@ package app
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"todolist_api/config"
+	"todolist_api/migrations"
 )
 
-const (
-	defaultAttempts = 20
-	defaultTimeout  = time.Second
-)
+// migrationsDir каталог с файлами миграций в исходниках, туда пишет migrate create
+const migrationsDir = "migrations"
+
+const migrateUsage = `Usage: app migrate <command> [arguments]
+
+Commands:
+  up [N]          apply all or N up migrations
+  down [N]        roll back N migrations, 1 by default
+  down -all       roll back all migrations
+  goto V          migrate up or down to version V
+  version         print current schema version
+  force V         set version V without running migrations, use to clean a dirty state (-1 for no version)
+  create NAME     create empty up and down migrations in the migrations directory
+`
+
+var migrationNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// runMigrate подкоманда migrate. Миграции берутся из migrations.FS, встроенного в бинарник
+func runMigrate(opts *config.Options, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return errors.New("migrate: command is required")
+	}
+	command, args := args[0], args[1:]
+	if command == "create" {
+		return createMigration(args)
+	}
+	if command == "help" || command == "-h" || command == "--help" {
+		fmt.Fprint(os.Stdout, migrateUsage)
+		return nil
+	}
+
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	m, err := newMigrate(cfg.PG)
+	if err != nil {
+		return err
+	}
+	defer func() { _, _ = m.Close() }()
 
-func init() {
-	dbUrl, ok := os.LookupEnv("PG_URL")
-	if !ok {
-		log.Fatal("migration error: PG url is not declared")
+	switch command {
+	case "up":
+		n, err := optionalSteps(args)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(n)
+		}
+		return noChange(err)
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		all := flags.Bool("all", false, "roll back all migrations")
+		if err = flags.Parse(args); err != nil {
+			return err
+		}
+		if *all {
+			if flags.NArg() > 0 {
+				return errors.New("migrate down: -all and N are mutually exclusive")
+			}
+			return noChange(m.Down())
+		}
+		n, err := optionalSteps(flags.Args())
+		if err != nil {
+			return err
+		}
+		return noChange(m.Steps(-max(n, 1)))
+	case "goto":
+		if len(args) != 1 {
+			return errors.New("migrate goto: version is required")
+		}
+		v, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate goto: invalid version %q", args[0])
+		}
+		return noChange(m.Migrate(uint(v)))
+	case "version":
+		v, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", v)
+		} else {
+			fmt.Println(v)
+		}
+		return nil
+	case "force":
+		if len(args) != 1 {
+			return errors.New("migrate force: version is required")
+		}
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < -1 {
+			return fmt.Errorf("migrate force: invalid version %q", args[0])
+		}
+		return m.Force(v)
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("migrate: unknown command %q", command)
+	}
+}
+
+// migrateUp применяет все новые миграции, используется при serve -migrate
+func migrateUp(cfg *config.Config) error {
+	m, err := newMigrate(cfg.PG)
+	if err != nil {
+		return err
+	}
+	defer func() { _, _ = m.Close() }()
+	return noChange(m.Up())
+}
+
+func newMigrate(pg config.PG) (*migrate.Migrate, error) {
+	dbUrl := pg.Url
+	if !strings.Contains(dbUrl, "sslmode=") {
+		if strings.Contains(dbUrl, "?") {
+			dbUrl += "&sslmode=disable"
+		} else {
+			dbUrl += "?sslmode=disable"
+		}
+	}
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migration source error: %w", err)
 	}
-	dbUrl += "?sslmode=disable"
 	var (
-		attempts = defaultAttempts
-		err      error
+		attempts = pg.ConnAttempts
 		m        *migrate.Migrate
 	)
 	for attempts > 0 {
-		m, err = migrate.New("file://migrations", dbUrl)
+		m, err = migrate.NewWithSourceInstance("iofs", src, dbUrl)
 		if err == nil {
 			break
 		}
 		log.Printf("migration trying to connect, attempts left: %d", attempts)
-		time.Sleep(defaultTimeout)
+		time.Sleep(pg.ConnTimeout)
 		attempts--
 	}
 	if err != nil {
-		log.Fatalf("migration db connect error: %s", err)
+		return nil, fmt.Errorf("migration db connect error: %w", err)
 	}
-	err = m.Up()
-
-	defer func() { _, _ = m.Close() }()
+	m.Log = migrateLogger{}
+	return m, nil
+}
 
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		log.Fatalf("migration up error: %s", err)
-	}
+// noChange отсутствие новых миграций не ошибка
+func noChange(err error) error {
 	if errors.Is(err, migrate.ErrNoChange) {
 		log.Printf("migrations without change")
-		return
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migration error: %w", err)
+	}
+	log.Printf("migration success")
+	return nil
+}
+
+func optionalSteps(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("migrate: N must be a positive integer, got %q", args[0])
+		}
+		return n, nil
+	default:
+		return 0, errors.New("migrate: too many arguments")
+	}
+}
+
+// createMigration создает пустые up и down миграции со следующим порядковым номером
+func createMigration(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrationsDir, "migrations directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("migrate create: name is required")
+	}
+	name := flags.Arg(0)
+	if !migrationNameRe.MatchString(name) {
+		return fmt.Errorf("migrate create: name %q must contain only lowercase letters, digits and underscores", name)
+	}
+	latest, err := latestVersion(os.DirFS(*dir))
+	if err != nil && !errors.Is(err, errNoMigrations) {
+		return err
 	}
-	log.Printf("migration up success")
+	base := filepath.Join(*dir, fmt.Sprintf("%d_%s", latest+1, name))
+	for _, direction := range []string{"up", "down"} {
+		path := base + "." + direction + ".sql"
+		// O_EXCL: не перезаписываем существующую миграцию
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+var errNoMigrations = errors.New("no migrations found")
+
+// latestMigrationVersion последняя версия среди встроенных миграций
+func latestMigrationVersion() (uint, error) {
+	return latestVersion(migrations.FS)
+}
+
+func latestVersion(fsys fs.FS) (uint, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, e := range entries {
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			continue
+		}
+		latest = max(latest, m.Version)
+	}
+	if latest == 0 {
+		return 0, errNoMigrations
+	}
+	return latest, nil
+}
+
+// migrateLogger печатает каждую примененную миграцию
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
 
+func (migrateLogger) Verbose() bool {
+	return false
 }
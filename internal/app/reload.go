@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"todolist_api/config"
+	v1 "todolist_api/internal/api/v1"
+	"todolist_api/internal/service"
+	"todolist_api/pkg/feature"
+	"todolist_api/pkg/logger"
+)
+
+// reloader перечитывает конфигурацию из тех же источников, что и при старте, и применяет параметры с тегом reload:
+// уровень и вывод логов, ограничения частоты запросов, источники CORS, флаги функциональности и выведенные ключи JWT.
+// Все, что может не получиться, готовится до применения, поэтому при ошибке действующая конфигурация не меняется
+type reloader struct {
+	mu         sync.Mutex
+	opts       *config.Options
+	current    *config.Config
+	logOutput  *logger.Output
+	rateLimits *v1.RateLimiter
+	cors       *v1.CORS
+	features   *feature.Flags
+	auth       service.Auth
+}
+
+func (r *reloader) Reload(ctx context.Context) (config.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log := logger.From(ctx)
+
+	next, err := r.apply()
+	if err != nil {
+		log.Errorf("/app/reloader/Reload error, config is not changed: %s", err)
+		return config.ReloadResult{}, err
+	}
+
+	applied, restart := config.Diff(r.current, next)
+	r.current = r.current.Reloaded(next)
+
+	for _, c := range applied {
+		log.WithFields(logrus.Fields{"key": c.Key, "old": c.Old, "new": c.New}).Info("config parameter reloaded")
+	}
+	for _, c := range restart {
+		log.WithFields(logrus.Fields{"key": c.Key, "old": c.Old, "new": c.New}).Warn("config parameter changed, restart required to apply")
+	}
+	log.Infof("config reloaded: %d applied, %d require restart", len(applied), len(restart))
+	return config.ReloadResult{Applied: applied, RestartRequired: restart}, nil
+}
+
+// apply читает и проверяет новую конфигурацию, затем применяет ее. Ошибка возможна только до первой замены
+func (r *reloader) apply() (*config.Config, error) {
+	next, err := config.Load(r.opts)
+	if err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+	limits, err := parseRateLimits(next)
+	if err != nil {
+		return nil, err
+	}
+	retired, err := loadRetiredKeys(next.JWT.RetiredKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+	// файл логов открывается заново и без изменений, чтобы после ротации писать в новый файл.
+	// Это последнее, что может не получиться, дальше только атомарные замены
+	if err = r.logOutput.Reopen(next.Log.Output); err != nil {
+		return nil, fmt.Errorf("open log output %s: %w", next.Log.Output, err)
+	}
+
+	level, _ := logrus.ParseLevel(next.Log.Level)
+	logrus.SetLevel(level)
+	r.rateLimits.Set(limits)
+	r.cors.SetOrigins(next.HTTP.CORSOrigins)
+	r.features.Set(next.Features.Enabled)
+	r.auth.SetVerifyKeys(retired, next.JWT.AcceptLegacy)
+	return next, nil
+}
@@ -0,0 +1,20 @@
+package dbmodel
+
+import "time"
+
+type Board struct {
+	Id        int       `db:"id"`
+	Username  string    `db:"username"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type BoardColumn struct {
+	Id        int       `db:"id"`
+	BoardId   int       `db:"board_id"`
+	Name      string    `db:"name"`
+	WipLimit  int       `db:"wip_limit"`
+	Status    string    `db:"status"`
+	Position  int       `db:"position"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,21 @@
+package dbmodel
+
+import "time"
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+// DataExport выгрузка данных пользователя. Archive заполняется только при скачивании,
+// ExpiresAt задается, когда архив готов
+type DataExport struct {
+	Id        int        `db:"id"`
+	Username  string     `db:"username"`
+	Status    string     `db:"status"`
+	Archive   []byte     `db:"archive"`
+	LastError string     `db:"last_error"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
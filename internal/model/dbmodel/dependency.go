@@ -0,0 +1,10 @@
+package dbmodel
+
+import "time"
+
+// TaskDependency задача TaskId заблокирована задачей BlockedById
+type TaskDependency struct {
+	TaskId      int       `db:"task_id"`
+	BlockedById int       `db:"blocked_by_id"`
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -0,0 +1,24 @@
+package dbmodel
+
+import "time"
+
+const (
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// DigestSetting настройки сводки пользователя. SendHour и Weekday (time.Weekday, только для weekly)
+// заданы в часовом поясе Timezone - это пояс из профиля пользователя, в настройках сводки он не хранится.
+// NextSendAt == nil, пока сводка выключена
+type DigestSetting struct {
+	Username   string     `db:"username"`
+	Enabled    bool       `db:"enabled"`
+	Email      string     `db:"email"`
+	Timezone   string     `db:"timezone"`
+	Frequency  string     `db:"frequency"`
+	SendHour   int        `db:"send_hour"`
+	Weekday    int        `db:"weekday"`
+	NextSendAt *time.Time `db:"next_send_at"`
+	LastSentAt *time.Time `db:"last_sent_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+}
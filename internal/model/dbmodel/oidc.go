@@ -0,0 +1,22 @@
+package dbmodel
+
+import "time"
+
+// OIDCLogin начатый вход через провайдера OpenID Connect, State - параметр state из ссылки на провайдера
+type OIDCLogin struct {
+	State        string    `db:"state"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// UserIdentity внешний аккаунт пользователя, Subject - идентификатор пользователя у провайдера
+type UserIdentity struct {
+	Provider  string    `db:"provider"`
+	Subject   string    `db:"subject"`
+	Username  string    `db:"username"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
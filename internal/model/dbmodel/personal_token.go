@@ -0,0 +1,17 @@
+package dbmodel
+
+import "time"
+
+// PersonalToken персональный токен доступа. TokenHash - sha256 от токена, Prefix - его начало для отображения.
+// ExpiresAt nil - токен бессрочный
+type PersonalToken struct {
+	Id         int        `db:"id"`
+	Username   string     `db:"username"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	TokenHash  string     `db:"token_hash"`
+	Scopes     []string   `db:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
@@ -0,0 +1,11 @@
+package dbmodel
+
+import "time"
+
+type RecoveryCode struct {
+	Id        int        `db:"id"`
+	Username  string     `db:"username"`
+	CodeHash  string     `db:"code_hash"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package dbmodel
+
+import "time"
+
+const (
+	ReminderChannelEmail   = "email"
+	ReminderChannelWebhook = "webhook"
+)
+
+// Reminder напоминание по задаче. Если OffsetMinutes задан, RemindAt = due_date задачи - OffsetMinutes
+// и пересчитывается при изменении срока задачи. SentAt == nil, пока напоминание не отправлено
+type Reminder struct {
+	Id            int        `db:"id"`
+	TaskId        int        `db:"task_id"`
+	Username      string     `db:"username"`
+	RemindAt      time.Time  `db:"remind_at"`
+	OffsetMinutes *int       `db:"offset_minutes"`
+	Channel       string     `db:"channel"`
+	Target        string     `db:"target"`
+	SentAt        *time.Time `db:"sent_at"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// DueReminder напоминание, захваченное воркером на отправку, вместе с данными задачи для текста уведомления
+type DueReminder struct {
+	Reminder
+	TaskTitle   string    `db:"task_title"`
+	TaskDueDate time.Time `db:"task_due_date"`
+	// OwnerEmail подтвержденный email владельца напоминания, пустой, если email не подтвержден
+	OwnerEmail string `db:"owner_email"`
+}
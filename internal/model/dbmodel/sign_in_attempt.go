@@ -0,0 +1,26 @@
+package dbmodel
+
+import "time"
+
+// Способы входа, которыми сделана попытка
+const (
+	SignInMethodPassword  = "password"
+	SignInMethodTwoFactor = "2fa"
+	SignInMethodOIDC      = "oidc"
+)
+
+type SignInAttempt struct {
+	Id        int       `db:"id"`
+	Username  string    `db:"username"`
+	IP        string    `db:"ip"`
+	UserAgent string    `db:"user_agent"`
+	Method    string    `db:"method"`
+	Success   bool      `db:"success"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SignInFailures неудачные попытки входа: сколько их и когда была последняя
+type SignInFailures struct {
+	Count  int        `db:"count"`
+	LastAt *time.Time `db:"last_at"`
+}
@@ -2,12 +2,38 @@ package dbmodel
 
 import "time"
 
+const (
+	TaskSortPriority = "priority"
+	TaskSortPosition = "position"
+)
+
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusDone       = "done"
+)
+
 type Task struct {
-	Id          int       `db:"id"`
-	Username    string    `db:"username"`
-	Title       string    `db:"title"`
-	Description string    `db:"description"`
-	DueDate     time.Time `db:"due_date"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+	Id               int       `db:"id"`
+	Username         string    `db:"username"`
+	Title            string    `db:"title"`
+	Description      string    `db:"description"`
+	DueDate          time.Time `db:"due_date"`
+	AllDay           bool      `db:"all_day"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+	Priority         int       `db:"priority"`
+	Position         string    `db:"position"`
+	Status           string    `db:"status"`
+	ColumnId         *int      `db:"column_id"`
+	Tags             []string  `db:"tags"`
+	EstimatedMinutes int       `db:"estimated_minutes"`
+	Recurrence       string    `db:"recurrence"`
+}
+
+// TaskCount количество задач пользователя в статусе
+type TaskCount struct {
+	Username string `db:"username"`
+	Status   string `db:"status"`
+	Count    int    `db:"count"`
 }
@@ -0,0 +1,27 @@
+package dbmodel
+
+import "time"
+
+const (
+	TimeReportByDay     = "day"
+	TimeReportByProject = "project"
+	TimeReportByTag     = "tag"
+)
+
+// TimeEntry запись учета времени. EndedAt == nil, пока таймер запущен
+type TimeEntry struct {
+	Id        int        `db:"id"`
+	TaskId    int        `db:"task_id"`
+	Username  string     `db:"username"`
+	StartedAt time.Time  `db:"started_at"`
+	EndedAt   *time.Time `db:"ended_at"`
+	Note      string     `db:"note"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// TimeReportRow суммарное время по одной группе отчета (день, проект или тег)
+type TimeReportRow struct {
+	Key      string        `db:"key"`
+	Duration time.Duration `db:"duration"`
+	Entries  int           `db:"entries"`
+}
@@ -2,9 +2,26 @@ package dbmodel
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	Id        int       `db:"id"`
-	Username  string    `db:"username"`
-	Password  string    `db:"password"`
-	CreatedAt time.Time `db:"created_at"`
+	Id                 int            `db:"id"`
+	Username           string         `db:"username"`
+	Password           string         `db:"password"`
+	CreatedAt          time.Time      `db:"created_at"`
+	Timezone           string         `db:"timezone"`
+	DisplayName        string         `db:"display_name"`
+	Email              string         `db:"email"`
+	Preferences        map[string]any `db:"preferences"`
+	SessionsValidAfter time.Time      `db:"sessions_valid_after"`
+	DeleteAt           *time.Time     `db:"delete_at"`
+	EmailVerified      bool           `db:"email_verified"`
+	TOTPSecret         string         `db:"totp_secret"`
+	TOTPEnabled        bool           `db:"totp_enabled"`
+	TOTPLastStep       int64          `db:"totp_last_step"`
+	Roles              []string       `db:"roles"`
+	DisabledAt         *time.Time     `db:"disabled_at"`
 }
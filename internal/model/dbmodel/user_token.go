@@ -0,0 +1,21 @@
+package dbmodel
+
+import "time"
+
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+)
+
+// UserToken одноразовый токен, отправленный пользователю письмом. TokenHash - sha256 от токена,
+// Email - адрес, который подтверждает токен UserTokenVerifyEmail
+type UserToken struct {
+	Id        int        `db:"id"`
+	Username  string     `db:"username"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	Email     string     `db:"email"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
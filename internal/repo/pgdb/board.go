@@ -0,0 +1,225 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+type BoardRepo struct {
+	*postgres.Postgres
+}
+
+func NewBoardRepo(pg *postgres.Postgres) *BoardRepo {
+	return &BoardRepo{pg}
+}
+
+// Create создает доску.
+// На вход принимает board с полями: Username, Name. Id и CreatedAt обновляются после записи в бд
+func (r *BoardRepo) Create(ctx context.Context, b *dbmodel.Board) error {
+	sql, args, _ := r.Builder.
+		Insert("board").
+		Columns("username", "name").
+		Values(b.Username, b.Name).
+		Suffix("returning id, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&b.Id, &b.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *BoardRepo) Find(ctx context.Context, username string) ([]dbmodel.Board, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "username", "name", "created_at").
+		From("board").
+		Where("username = ?", username).
+		OrderBy("id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.Board])
+}
+
+func (r *BoardRepo) FindById(ctx context.Context, id int, username string) (dbmodel.Board, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "username", "name", "created_at").
+		From("board").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		ToSql()
+
+	var board dbmodel.Board
+	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&board.Id,
+		&board.Username,
+		&board.Name,
+		&board.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.Board{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.Board{}, err
+	}
+	return board, nil
+}
+
+func (r *BoardRepo) Update(ctx context.Context, b *dbmodel.Board) error {
+	sql, args, _ := r.Builder.
+		Update("board").
+		Set("name", b.Name).
+		Where("id = ?", b.Id).
+		Where("username = ?", b.Username).
+		Suffix("returning created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&b.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет доску вместе с колонками, задачи остаются и просто перестают быть привязаны к колонкам
+func (r *BoardRepo) Delete(ctx context.Context, id int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("board").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateColumn добавляет колонку в конец доски пользователя.
+// На вход принимает column с полями: BoardId, Name, WipLimit, Status.
+// Если доски нет или она принадлежит другому пользователю, возвращает pgerrs.ErrNotFound
+func (r *BoardRepo) CreateColumn(ctx context.Context, c *dbmodel.BoardColumn, username string) error {
+	sql, args, _ := r.Builder.
+		Insert("board_column").
+		Columns("board_id", "name", "wip_limit", "status", "position").
+		Select(r.Builder.
+			Select("b.id").
+			Column("?::varchar", c.Name).
+			Column("?::integer", c.WipLimit).
+			Column("?::varchar", c.Status).
+			Column("(select coalesce(max(position) + 1, 0) from board_column where board_id = b.id)").
+			From("board b").
+			Where("b.id = ?", c.BoardId).
+			Where("b.username = ?", username),
+		).
+		Suffix("returning id, position, created_at").
+		ToSql()
+
+	err := r.Pool.QueryRow(ctx, sql, args...).Scan(&c.Id, &c.Position, &c.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *BoardRepo) FindColumns(ctx context.Context, boardId int) ([]dbmodel.BoardColumn, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "board_id", "name", "wip_limit", "status", "position", "created_at").
+		From("board_column").
+		Where("board_id = ?", boardId).
+		OrderBy("position", "id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.BoardColumn])
+}
+
+// UpdateColumn меняет имя, WIP-лимит и статус колонки. Позиция меняется только через ReorderColumns
+func (r *BoardRepo) UpdateColumn(ctx context.Context, c *dbmodel.BoardColumn, username string) error {
+	sql, args, _ := r.Builder.
+		Update("board_column").
+		Set("name", c.Name).
+		Set("wip_limit", c.WipLimit).
+		Set("status", c.Status).
+		Where("id = ?", c.Id).
+		Where("board_id = ?", c.BoardId).
+		Where("board_id in (select id from board where username = ?)", username).
+		Suffix("returning position, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&c.Position, &c.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *BoardRepo) DeleteColumn(ctx context.Context, boardId, columnId int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("board_column").
+		Where("id = ?", columnId).
+		Where("board_id = ?", boardId).
+		Where("board_id in (select id from board where username = ?)", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ReorderColumns выставляет колонкам позиции в порядке columnIds.
+// Проверка того, что columnIds - это ровно все колонки доски, остается на вызывающей стороне
+func (r *BoardRepo) ReorderColumns(ctx context.Context, boardId int, columnIds []int) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for position, id := range columnIds {
+		sql, args, _ := r.Builder.
+			Update("board_column").
+			Set("position", position).
+			Where("id = ?", id).
+			Where("board_id = ?", boardId).
+			ToSql()
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,171 @@
+package pgdb
+
+import (
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) setupTestsBoard(username string) *dbmodel.Board {
+	board := &dbmodel.Board{
+		Username: username,
+		Name:     "Sprint",
+	}
+	if err := s.board.Create(s.ctx, board); err != nil {
+		panic(err)
+	}
+	return board
+}
+
+func (s *pgdbTestSuite) TestBoardRepo_CreateColumn() {
+	username := s.setupTestsData()
+	board := s.setupTestsBoard(username)
+
+	testCases := []struct {
+		testName       string
+		column         *dbmodel.BoardColumn
+		username       string
+		expectPosition int
+		expectErr      error
+	}{
+		{
+			testName: "Correct test",
+			column: &dbmodel.BoardColumn{
+				BoardId: board.Id,
+				Name:    "To do",
+				Status:  dbmodel.TaskStatusTodo,
+			},
+			username:       username,
+			expectPosition: 0,
+			expectErr:      nil,
+		},
+		{
+			testName: "Column added to the end",
+			column: &dbmodel.BoardColumn{
+				BoardId:  board.Id,
+				Name:     "Review",
+				WipLimit: 2,
+			},
+			username:       username,
+			expectPosition: 1,
+			expectErr:      nil,
+		},
+		{
+			testName: "Board of another user",
+			column: &dbmodel.BoardColumn{
+				BoardId: board.Id,
+				Name:    "Done",
+			},
+			username:  "petya",
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.board.CreateColumn(s.ctx, tc.column, tc.username)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			s.Assert().Equal(tc.expectPosition, tc.column.Position, tc.testName)
+		}
+	}
+
+	columns, err := s.board.FindColumns(s.ctx, board.Id)
+	s.Assert().Nil(err)
+	s.Assert().Len(columns, 2)
+}
+
+func (s *pgdbTestSuite) TestBoardRepo_ReorderColumns() {
+	username := s.setupTestsData()
+	board := s.setupTestsBoard(username)
+
+	var ids []int
+	for _, name := range []string{"To do", "In progress", "Done"} {
+		column := &dbmodel.BoardColumn{BoardId: board.Id, Name: name}
+		if err := s.board.CreateColumn(s.ctx, column, username); err != nil {
+			panic(err)
+		}
+		ids = append(ids, column.Id)
+	}
+
+	expectIds := []int{ids[2], ids[0], ids[1]}
+	err := s.board.ReorderColumns(s.ctx, board.Id, expectIds)
+	s.Assert().Nil(err)
+
+	columns, err := s.board.FindColumns(s.ctx, board.Id)
+	s.Assert().Nil(err)
+
+	actualIds := make([]int, 0, len(columns))
+	for _, c := range columns {
+		actualIds = append(actualIds, c.Id)
+	}
+	s.Assert().Equal(expectIds, actualIds)
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_MoveToColumn() {
+	username := s.setupTestsData()
+	board := s.setupTestsBoard(username)
+	taskIds := s.setupTestsTasks(username, "b", "i", "r")
+
+	column := &dbmodel.BoardColumn{
+		BoardId:  board.Id,
+		Name:     "In progress",
+		WipLimit: 2,
+		Status:   dbmodel.TaskStatusInProgress,
+	}
+	if err := s.board.CreateColumn(s.ctx, column, username); err != nil {
+		panic(err)
+	}
+
+	testCases := []struct {
+		testName  string
+		taskId    int
+		columnId  int
+		expectErr error
+	}{
+		{
+			testName:  "Correct test",
+			taskId:    taskIds[0],
+			columnId:  column.Id,
+			expectErr: nil,
+		},
+		{
+			testName:  "Move again to the same column",
+			taskId:    taskIds[0],
+			columnId:  column.Id,
+			expectErr: nil,
+		},
+		{
+			testName:  "Last free place",
+			taskId:    taskIds[1],
+			columnId:  column.Id,
+			expectErr: nil,
+		},
+		{
+			testName:  "WIP limit exceeded",
+			taskId:    taskIds[2],
+			columnId:  column.Id,
+			expectErr: pgerrs.ErrLimitExceeded,
+		},
+		{
+			testName:  "Column not exist",
+			taskId:    taskIds[2],
+			columnId:  123123,
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		task := &dbmodel.Task{Id: tc.taskId, Username: username}
+		err := s.task.MoveToColumn(s.ctx, task, board.Id, tc.columnId)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			s.Assert().Equal(dbmodel.TaskStatusInProgress, task.Status, tc.testName)
+			s.Assert().Equal(tc.columnId, *task.ColumnId, tc.testName)
+		}
+	}
+
+	tasks, err := s.task.FindByBoard(s.ctx, board.Id)
+	s.Assert().Nil(err)
+	s.Assert().Len(tasks, 2)
+}
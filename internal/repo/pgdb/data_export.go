@@ -0,0 +1,152 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+// dataExportColumns - колонки выгрузки без архива, архив читается только при скачивании
+var dataExportColumns = []string{"id", "username", "status", "last_error", "expires_at", "created_at"}
+
+// claimDataExportsQuery захватывает до $3 ожидающих выгрузок, продлевая им locked_until до $2.
+// Если реплика не успеет собрать архив, после $2 выгрузку возьмет другая
+const claimDataExportsQuery = `with claimed as (
+  update data_export
+  set locked_until = $2
+  where id in (
+    select id from data_export
+    where status = 'pending'
+      and (locked_until is null or locked_until <= $1)
+    order by created_at
+    limit $3
+    for update skip locked
+  )
+  returning id, username, status, last_error, expires_at, created_at
+)
+select * from claimed order by id`
+
+type DataExportRepo struct {
+	*postgres.Postgres
+}
+
+func NewDataExportRepo(pg *postgres.Postgres) *DataExportRepo {
+	return &DataExportRepo{pg}
+}
+
+// Create создает ожидающую выгрузку. Возвращает pgerrs.ErrAlreadyExists, если у пользователя уже есть ожидающая выгрузка
+func (r *DataExportRepo) Create(ctx context.Context, e *dbmodel.DataExport) error {
+	sql, args, _ := r.Builder.
+		Insert("data_export").
+		Columns("username").
+		Values(e.Username).
+		Suffix("returning id, status, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&e.Id, &e.Status, &e.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *DataExportRepo) FindById(ctx context.Context, id int, username string) (dbmodel.DataExport, error) {
+	sql, args, _ := r.Builder.
+		Select(dataExportColumns...).
+		From("data_export").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		ToSql()
+
+	return r.queryOne(ctx, sql, args...)
+}
+
+// FindArchive возвращает готовую выгрузку вместе с архивом (по ссылке на скачивание, без привязки к пользователю)
+func (r *DataExportRepo) FindArchive(ctx context.Context, id int) (dbmodel.DataExport, error) {
+	sql, args, _ := r.Builder.
+		Select(append(dataExportColumns, "archive")...).
+		From("data_export").
+		Where("id = ?", id).
+		Where("status = ?", dbmodel.DataExportStatusReady).
+		ToSql()
+
+	return r.queryOne(ctx, sql, args...)
+}
+
+// Claim захватывает на время lease до limit ожидающих выгрузок
+func (r *DataExportRepo) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dbmodel.DataExport, error) {
+	rows, err := r.Pool.Query(ctx, claimDataExportsQuery, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByNameLax[dbmodel.DataExport])
+}
+
+func (r *DataExportRepo) MarkReady(ctx context.Context, id int, archive []byte, expiresAt time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("data_export").
+		Set("status", dbmodel.DataExportStatusReady).
+		Set("archive", archive).
+		Set("expires_at", expiresAt).
+		Set("locked_until", nil).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *DataExportRepo) MarkFailed(ctx context.Context, id int, lastError string) error {
+	sql, args, _ := r.Builder.
+		Update("data_export").
+		Set("status", dbmodel.DataExportStatusFailed).
+		Set("last_error", lastError).
+		Set("locked_until", nil).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteExpired удаляет выгрузки, срок хранения которых истек к now, и возвращает их количество
+func (r *DataExportRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	sql, args, _ := r.Builder.
+		Delete("data_export").
+		Where("expires_at <= ?", now).
+		ToSql()
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *DataExportRepo) queryOne(ctx context.Context, sql string, args ...any) (dbmodel.DataExport, error) {
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.DataExport{}, err
+	}
+	export, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[dbmodel.DataExport])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.DataExport{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.DataExport{}, err
+	}
+	return export, nil
+}
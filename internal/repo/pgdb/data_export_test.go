@@ -0,0 +1,105 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestDataExportRepo_Create() {
+	username := s.setupTestsData()
+
+	testCases := []struct {
+		testName  string
+		username  string
+		expectErr error
+	}{
+		{
+			testName:  "Correct test",
+			username:  username,
+			expectErr: nil,
+		},
+		{
+			testName:  "Pending export already exists",
+			username:  username,
+			expectErr: pgerrs.ErrAlreadyExists,
+		},
+		{
+			testName:  "User not exist",
+			username:  "foobar",
+			expectErr: pgerrs.ErrForeignKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		export := &dbmodel.DataExport{Username: tc.username}
+		err := s.dataExport.Create(s.ctx, export)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			actual, err := s.dataExport.FindById(s.ctx, export.Id, tc.username)
+			s.Assert().Nil(err, tc.testName)
+			s.Assert().Equal(dbmodel.DataExportStatusPending, actual.Status, tc.testName)
+			s.Assert().Nil(actual.Archive, tc.testName)
+
+			_, err = s.dataExport.FindById(s.ctx, export.Id, "petya")
+			s.Assert().Equal(pgerrs.ErrNotFound, err, tc.testName)
+		}
+	}
+}
+
+func (s *pgdbTestSuite) TestDataExportRepo_Claim() {
+	username := s.setupTestsData()
+	export := &dbmodel.DataExport{Username: username}
+	if err := s.dataExport.Create(s.ctx, export); err != nil {
+		panic(err)
+	}
+	now := time.Now()
+
+	claimed, err := s.dataExport.Claim(s.ctx, now, time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Len(claimed, 1)
+	s.Assert().Equal(export.Id, claimed[0].Id)
+
+	// захваченную выгрузку до истечения аренды не берет никто другой
+	claimed, err = s.dataExport.Claim(s.ctx, now, time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed)
+
+	claimed, err = s.dataExport.Claim(s.ctx, now.Add(2*time.Minute), time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Len(claimed, 1)
+
+	// архив недоступен, пока выгрузка не готова
+	_, err = s.dataExport.FindArchive(s.ctx, export.Id)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	expiresAt := now.Add(time.Hour)
+	s.Assert().Nil(s.dataExport.MarkReady(s.ctx, export.Id, []byte("zip"), expiresAt))
+
+	claimed, err = s.dataExport.Claim(s.ctx, now.Add(time.Hour), time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed)
+
+	actual, err := s.dataExport.FindArchive(s.ctx, export.Id)
+	s.Assert().Nil(err)
+	s.Assert().Equal(dbmodel.DataExportStatusReady, actual.Status)
+	s.Assert().Equal([]byte("zip"), actual.Archive)
+	s.Assert().NotNil(actual.ExpiresAt)
+
+	// после сборки можно запросить новую выгрузку
+	failed := &dbmodel.DataExport{Username: username}
+	s.Assert().Nil(s.dataExport.Create(s.ctx, failed))
+	s.Assert().Nil(s.dataExport.MarkFailed(s.ctx, failed.Id, "boom"))
+	actual, err = s.dataExport.FindById(s.ctx, failed.Id, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal(dbmodel.DataExportStatusFailed, actual.Status)
+	s.Assert().Equal("boom", actual.LastError)
+
+	deleted, err := s.dataExport.DeleteExpired(s.ctx, now)
+	s.Assert().Nil(err)
+	s.Assert().Zero(deleted)
+	deleted, err = s.dataExport.DeleteExpired(s.ctx, expiresAt)
+	s.Assert().Nil(err)
+	s.Assert().Equal(int64(1), deleted)
+}
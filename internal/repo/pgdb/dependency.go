@@ -0,0 +1,132 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+// blockersQuery рекурсивно собирает все связи, по которым задача $1 (прямо или транзитивно) заблокирована.
+// union вместо union all гарантирует завершение даже на графе с циклом
+const blockersQuery = `with recursive blockers(task_id, blocked_by_id, created_at) as (
+	select task_id, blocked_by_id, created_at from task_dependency where task_id = $1
+	union
+	select d.task_id, d.blocked_by_id, d.created_at from task_dependency d join blockers b on d.task_id = b.blocked_by_id
+)`
+
+type DependencyRepo struct {
+	*postgres.Postgres
+}
+
+func NewDependencyRepo(pg *postgres.Postgres) *DependencyRepo {
+	return &DependencyRepo{pg}
+}
+
+// Create добавляет связь "taskId заблокирована blockedById" между задачами пользователя.
+// Связи пользователя сериализуются advisory lock'ом, чтобы две параллельные вставки не образовали цикл.
+// Возвращает pgerrs.ErrNotFound, если какой-то из задач нет, pgerrs.ErrAlreadyExists для существующей связи
+// и pgerrs.ErrCycle, если связь замкнет цикл
+func (r *DependencyRepo) Create(ctx context.Context, taskId, blockedById int, username string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, "select pg_advisory_xact_lock(hashtext($1))", "task_dependency:"+username); err != nil {
+		return err
+	}
+
+	sql, args, _ := r.Builder.
+		Select("count(*)").
+		From("task").
+		Where(squirrel.Eq{"id": []int{taskId, blockedById}}).
+		Where("username = ?", username).
+		ToSql()
+
+	var count int
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return err
+	}
+	if count != 2 {
+		return pgerrs.ErrNotFound
+	}
+
+	var cycle bool
+	err = tx.QueryRow(ctx, blockersQuery+" select exists(select 1 from blockers where blocked_by_id = $2)",
+		blockedById, taskId).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return pgerrs.ErrCycle
+	}
+
+	sql, args, _ = r.Builder.
+		Insert("task_dependency").
+		Columns("task_id", "blocked_by_id").
+		Values(taskId, blockedById).
+		ToSql()
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *DependencyRepo) Delete(ctx context.Context, taskId, blockedById int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("task_dependency").
+		Where("task_id = ?", taskId).
+		Where("blocked_by_id = ?", blockedById).
+		Where("task_id in (select id from task where username = ?)", username).
+		Suffix("returning task_id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// FindByTasks возвращает все связи, в которых участвует хотя бы одна из задач taskIds
+func (r *DependencyRepo) FindByTasks(ctx context.Context, taskIds []int) ([]dbmodel.TaskDependency, error) {
+	sql, args, _ := r.Builder.
+		Select("task_id", "blocked_by_id", "created_at").
+		From("task_dependency").
+		Where(squirrel.Or{
+			squirrel.Eq{"task_id": taskIds},
+			squirrel.Eq{"blocked_by_id": taskIds},
+		}).
+		OrderBy("task_id", "blocked_by_id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TaskDependency])
+}
+
+// FindBlockers возвращает все связи, которые прямо или транзитивно блокируют задачу taskId
+func (r *DependencyRepo) FindBlockers(ctx context.Context, taskId int) ([]dbmodel.TaskDependency, error) {
+	rows, err := r.Pool.Query(ctx, blockersQuery+" select task_id, blocked_by_id, created_at from blockers", taskId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TaskDependency])
+}
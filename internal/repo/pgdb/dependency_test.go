@@ -0,0 +1,116 @@
+package pgdb
+
+import (
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestDependencyRepo_Create() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i", "r")
+
+	testCases := []struct {
+		testName    string
+		taskId      int
+		blockedById int
+		username    string
+		expectErr   error
+	}{
+		{
+			testName:    "Correct test",
+			taskId:      ids[1],
+			blockedById: ids[0],
+			username:    username,
+			expectErr:   nil,
+		},
+		{
+			testName:    "Chain",
+			taskId:      ids[2],
+			blockedById: ids[1],
+			username:    username,
+			expectErr:   nil,
+		},
+		{
+			testName:    "Dependency already exist",
+			taskId:      ids[1],
+			blockedById: ids[0],
+			username:    username,
+			expectErr:   pgerrs.ErrAlreadyExists,
+		},
+		{
+			testName:    "Direct cycle",
+			taskId:      ids[0],
+			blockedById: ids[1],
+			username:    username,
+			expectErr:   pgerrs.ErrCycle,
+		},
+		{
+			testName:    "Transitive cycle",
+			taskId:      ids[0],
+			blockedById: ids[2],
+			username:    username,
+			expectErr:   pgerrs.ErrCycle,
+		},
+		{
+			testName:    "Task of another user",
+			taskId:      ids[2],
+			blockedById: ids[0],
+			username:    "petya",
+			expectErr:   pgerrs.ErrNotFound,
+		},
+		{
+			testName:    "Task not exist",
+			taskId:      ids[2],
+			blockedById: 123123,
+			username:    username,
+			expectErr:   pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.dependency.Create(s.ctx, tc.taskId, tc.blockedById, tc.username)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+	}
+
+	edges, err := s.dependency.FindByTasks(s.ctx, []int{ids[1]})
+	s.Assert().Nil(err)
+	s.Assert().Len(edges, 2)
+
+	blockers, err := s.dependency.FindBlockers(s.ctx, ids[2])
+	s.Assert().Nil(err)
+	s.Assert().Len(blockers, 2)
+}
+
+func (s *pgdbTestSuite) TestDependencyRepo_Delete() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i")
+	if err := s.dependency.Create(s.ctx, ids[1], ids[0], username); err != nil {
+		panic(err)
+	}
+
+	testCases := []struct {
+		testName  string
+		username  string
+		expectErr error
+	}{
+		{
+			testName:  "User not exist",
+			username:  "petya",
+			expectErr: pgerrs.ErrNotFound,
+		},
+		{
+			testName:  "Correct test",
+			username:  username,
+			expectErr: nil,
+		},
+		{
+			testName:  "Dependency has been deleted",
+			username:  username,
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.dependency.Delete(s.ctx, ids[1], ids[0], tc.username)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+	}
+}
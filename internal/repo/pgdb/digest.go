@@ -0,0 +1,121 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+// digestSettingColumns колонки настроек сводки, timezone берется из профиля пользователя
+var digestSettingColumns = []string{
+	"d.username", "d.enabled", "d.email", "u.timezone", "d.frequency", "d.send_hour", "d.weekday",
+	"d.next_send_at", "d.last_sent_at", "d.updated_at",
+}
+
+// claimDigestsQuery захватывает до $3 включенных сводок, время отправки которых наступило к $1, сдвигая next_send_at на $2.
+// Если реплика не успеет отправить сводку и пересчитать расписание, после $2 сводку возьмет другая.
+// email пустой, если он больше не совпадает с подтвержденным email пользователя: на такой адрес сводка не отправляется
+const claimDigestsQuery = `with claimed as (
+  update digest_setting d
+  set next_send_at = $2
+  from "user" u
+  where u.username = d.username
+    and d.username in (
+      select username from digest_setting
+      where enabled
+        and next_send_at <= $1
+      order by next_send_at
+      limit $3
+      for update skip locked
+    )
+  returning d.username, d.enabled,
+    case when u.email_verified and lower(u.email) = lower(d.email) then d.email else '' end as email, u.timezone, d.frequency, d.send_hour, d.weekday, d.next_send_at,
+    d.last_sent_at, d.updated_at
+)
+select * from claimed order by username`
+
+type DigestRepo struct {
+	*postgres.Postgres
+}
+
+func NewDigestRepo(pg *postgres.Postgres) *DigestRepo {
+	return &DigestRepo{pg}
+}
+
+func (r *DigestRepo) FindSetting(ctx context.Context, username string) (dbmodel.DigestSetting, error) {
+	sql, args, _ := r.Builder.
+		Select(digestSettingColumns...).
+		From("digest_setting d").
+		Join("\"user\" u on u.username = d.username").
+		Where("d.username = ?", username).
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.DigestSetting{}, err
+	}
+	setting, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.DigestSetting])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.DigestSetting{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.DigestSetting{}, err
+	}
+	return setting, nil
+}
+
+// SaveSetting создает или полностью перезаписывает настройки сводки пользователя (кроме last_sent_at).
+// Timezone не сохраняется, в s возвращается пояс из профиля
+func (r *DigestRepo) SaveSetting(ctx context.Context, s *dbmodel.DigestSetting) error {
+	sql, args, _ := r.Builder.
+		Insert("digest_setting").
+		Columns("username", "enabled", "email", "frequency", "send_hour", "weekday", "next_send_at").
+		Values(s.Username, s.Enabled, s.Email, s.Frequency, s.SendHour, s.Weekday, s.NextSendAt).
+		Suffix(`on conflict (username) do update set
+			enabled = excluded.enabled, email = excluded.email,
+			frequency = excluded.frequency, send_hour = excluded.send_hour, weekday = excluded.weekday,
+			next_send_at = excluded.next_send_at, updated_at = now()
+			returning last_sent_at, updated_at,
+				(select timezone from "user" where username = digest_setting.username)`).
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&s.LastSentAt, &s.UpdatedAt, &s.Timezone); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Claim захватывает на время lease до limit сводок, которые пора отправить к now
+func (r *DigestRepo) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dbmodel.DigestSetting, error) {
+	rows, err := r.Pool.Query(ctx, claimDigestsQuery, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.DigestSetting])
+}
+
+// Reschedule назначает следующую отправку сводки. sentAt == nil, если сводка не отправлялась (например, нечего слать)
+func (r *DigestRepo) Reschedule(ctx context.Context, username string, nextSendAt time.Time, sentAt *time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("digest_setting").
+		Set("next_send_at", nextSendAt).
+		Set("last_sent_at", squirrel.Expr("coalesce(?::timestamptz, last_sent_at)", sentAt)).
+		Where("username = ?", username).
+		Where("enabled").
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,148 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestDigestRepo_SaveSetting() {
+	username := s.setupTestsData()
+	next := time.Date(2024, 8, 28, 5, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		testName  string
+		setting   *dbmodel.DigestSetting
+		expectErr error
+	}{
+		{
+			testName: "Correct test",
+			setting: &dbmodel.DigestSetting{
+				Username:   username,
+				Enabled:    true,
+				Email:      "vasya@example.com",
+				Timezone:   "UTC",
+				Frequency:  dbmodel.DigestFrequencyDaily,
+				SendHour:   8,
+				Weekday:    1,
+				NextSendAt: &next,
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "Overwrite existing",
+			setting: &dbmodel.DigestSetting{
+				Username:  username,
+				Enabled:   false,
+				Email:     "vasya@example.com",
+				Timezone:  "UTC",
+				Frequency: dbmodel.DigestFrequencyWeekly,
+				SendHour:  9,
+				Weekday:   5,
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "User not exist",
+			setting: &dbmodel.DigestSetting{
+				Username:  "foobar",
+				Frequency: dbmodel.DigestFrequencyDaily,
+			},
+			expectErr: pgerrs.ErrForeignKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.digest.SaveSetting(s.ctx, tc.setting)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			actual, err := s.digest.FindSetting(s.ctx, tc.setting.Username)
+			s.Assert().Nil(err, tc.testName)
+			s.Assert().Equal(*tc.setting, actual, tc.testName)
+		}
+	}
+
+	_, err := s.digest.FindSetting(s.ctx, "petya")
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	// часовой пояс сводки - пояс из профиля
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	u.Timezone = "Europe/Moscow"
+	s.Assert().Nil(s.user.Update(s.ctx, u))
+	actual, err := s.digest.FindSetting(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal("Europe/Moscow", actual.Timezone)
+}
+
+func (s *pgdbTestSuite) TestDigestRepo_Claim() {
+	username := s.setupTestsData()
+	now := time.Date(2024, 8, 28, 5, 0, 0, 0, time.UTC)
+	next := now.Add(-time.Minute)
+
+	setting := &dbmodel.DigestSetting{
+		Username:   username,
+		Enabled:    true,
+		Email:      "vasya@example.com",
+		Timezone:   "UTC",
+		Frequency:  dbmodel.DigestFrequencyDaily,
+		SendHour:   5,
+		NextSendAt: &next,
+	}
+	if err := s.digest.SaveSetting(s.ctx, setting); err != nil {
+		panic(err)
+	}
+
+	claimed, err := s.digest.Claim(s.ctx, now, 15*time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Len(claimed, 1)
+	s.Assert().Equal(now.Add(15*time.Minute), *claimed[0].NextSendAt)
+	s.Assert().Equal("UTC", claimed[0].Timezone)
+	s.Assert().Empty(claimed[0].Email, "Email is not the verified email of the user")
+
+	claimed, err = s.digest.Claim(s.ctx, now, 15*time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed, "Claimed digest is not taken again until lease expires")
+
+	err = s.digest.Reschedule(s.ctx, username, now.Add(24*time.Hour), &now)
+	s.Assert().Nil(err)
+
+	claimed, err = s.digest.Claim(s.ctx, now.Add(time.Hour), 15*time.Minute, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed)
+
+	actual, err := s.digest.FindSetting(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal(now, *actual.LastSentAt)
+	s.Assert().Equal(now.Add(24*time.Hour), *actual.NextSendAt)
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_FindDue() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i", "r")
+
+	dueDates := []time.Time{
+		time.Date(2024, 8, 30, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 8, 26, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 9, 5, 12, 0, 0, 0, time.UTC),
+	}
+	for i, id := range ids {
+		task, err := s.task.FindById(s.ctx, id, username)
+		if err != nil {
+			panic(err)
+		}
+		task.DueDate = dueDates[i]
+		if i == 1 {
+			task.Status = dbmodel.TaskStatusDone
+		}
+		if err = s.task.Update(s.ctx, &task); err != nil {
+			panic(err)
+		}
+	}
+
+	tasks, err := s.task.FindDue(s.ctx, username, time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC))
+	s.Assert().Nil(err)
+	s.Assert().Len(tasks, 1, "Done task and task due after the bound are skipped")
+	s.Assert().Equal(ids[0], tasks[0].Id)
+}
@@ -0,0 +1,34 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+type MigrationRepo struct {
+	*postgres.Postgres
+}
+
+func NewMigrationRepo(pg *postgres.Postgres) *MigrationRepo {
+	return &MigrationRepo{pg}
+}
+
+// Version примененная версия схемы из таблицы golang-migrate. dirty - последняя миграция упала на середине
+func (r *MigrationRepo) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	sql, args, _ := r.Builder.
+		Select("version", "dirty").
+		From("schema_migrations").
+		Limit(1).
+		ToSql()
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&version, &dirty); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, pgerrs.ErrNotFound
+		}
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
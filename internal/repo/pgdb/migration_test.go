@@ -0,0 +1,11 @@
+package pgdb
+
+func (s *pgdbTestSuite) TestMigrationRepo_Version() {
+	expected, _, err := s.m.Version()
+	s.Require().Nil(err)
+
+	version, dirty, err := s.migration.Version(s.ctx)
+	s.Assert().Nil(err)
+	s.Assert().Equal(expected, version)
+	s.Assert().False(dirty)
+}
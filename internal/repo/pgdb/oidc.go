@@ -0,0 +1,126 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+type OIDCRepo struct {
+	*postgres.Postgres
+}
+
+func NewOIDCRepo(pg *postgres.Postgres) *OIDCRepo {
+	return &OIDCRepo{pg}
+}
+
+// CreateLogin сохраняет начатый вход и заодно удаляет истекшие к now, которые так и не были завершены
+func (r *OIDCRepo) CreateLogin(ctx context.Context, l dbmodel.OIDCLogin, now time.Time) error {
+	sql, args, _ := r.Builder.
+		Delete("oidc_login").
+		Where("expires_at <= ?", now).
+		ToSql()
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+
+	sql, args, _ = r.Builder.
+		Insert("oidc_login").
+		Columns("state", "provider", "nonce", "code_verifier", "expires_at").
+		Values(l.State, l.Provider, l.Nonce, l.CodeVerifier, l.ExpiresAt).
+		ToSql()
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// ConsumeLogin удаляет и возвращает вход по state. Возвращает pgerrs.ErrNotFound, если входа с таким state у провайдера нет
+// или он истек к now
+func (r *OIDCRepo) ConsumeLogin(ctx context.Context, provider, state string, now time.Time) (dbmodel.OIDCLogin, error) {
+	sql, args, _ := r.Builder.
+		Delete("oidc_login").
+		Where("state = ?", state).
+		Where("provider = ?", provider).
+		Where("expires_at > ?", now).
+		Suffix("returning state, provider, nonce, code_verifier, expires_at, created_at").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.OIDCLogin{}, err
+	}
+	login, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.OIDCLogin])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.OIDCLogin{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.OIDCLogin{}, err
+	}
+	return login, nil
+}
+
+// CreateIdentity связывает внешний аккаунт с пользователем. Возвращает pgerrs.ErrAlreadyExists, если аккаунт уже связан,
+// и pgerrs.ErrForeignKey, если пользователя нет
+func (r *OIDCRepo) CreateIdentity(ctx context.Context, i dbmodel.UserIdentity) error {
+	sql, args, _ := r.Builder.
+		Insert("user_identity").
+		Columns("provider", "subject", "username", "email").
+		Values(i.Provider, i.Subject, i.Username, i.Email).
+		ToSql()
+
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			switch pgErr.Code {
+			case "23505":
+				return pgerrs.ErrAlreadyExists
+			case "23503":
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *OIDCRepo) FindIdentity(ctx context.Context, provider, subject string) (dbmodel.UserIdentity, error) {
+	sql, args, _ := r.Builder.
+		Select("provider", "subject", "username", "email", "created_at").
+		From("user_identity").
+		Where("provider = ?", provider).
+		Where("subject = ?", subject).
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.UserIdentity{}, err
+	}
+	identity, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.UserIdentity])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.UserIdentity{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.UserIdentity{}, err
+	}
+	return identity, nil
+}
+
+// FindIdentitiesByUser внешние аккаунты, привязанные к username, в порядке привязки
+func (r *OIDCRepo) FindIdentitiesByUser(ctx context.Context, username string) ([]dbmodel.UserIdentity, error) {
+	sql, args, _ := r.Builder.
+		Select("provider", "subject", "username", "email", "created_at").
+		From("user_identity").
+		Where("username = ?", username).
+		OrderBy("created_at", "provider").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.UserIdentity])
+}
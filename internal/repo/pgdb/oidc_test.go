@@ -0,0 +1,66 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestOIDCRepo_ConsumeLogin() {
+	now := time.Now()
+	login := dbmodel.OIDCLogin{
+		State:        "state",
+		Provider:     "corp",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		ExpiresAt:    now.Add(10 * time.Minute),
+	}
+	s.Assert().Nil(s.oidc.CreateLogin(s.ctx, login, now))
+	expired := login
+	expired.State, expired.ExpiresAt = "expired", now.Add(time.Minute)
+	s.Assert().Nil(s.oidc.CreateLogin(s.ctx, expired, now))
+
+	// state другого провайдера не подходит
+	_, err := s.oidc.ConsumeLogin(s.ctx, "google", "state", now)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+	_, err = s.oidc.ConsumeLogin(s.ctx, "corp", "expired", now.Add(2*time.Minute))
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	l, err := s.oidc.ConsumeLogin(s.ctx, "corp", "state", now)
+	s.Assert().Nil(err)
+	s.Assert().Equal("nonce", l.Nonce)
+	s.Assert().Equal("verifier", l.CodeVerifier)
+	// state одноразовый
+	_, err = s.oidc.ConsumeLogin(s.ctx, "corp", "state", now)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+}
+
+func (s *pgdbTestSuite) TestOIDCRepo_Identity() {
+	username := s.setupTestsData()
+	identity := dbmodel.UserIdentity{
+		Provider: "corp",
+		Subject:  "248289761001",
+		Username: username,
+		Email:    "vasya@example.com",
+	}
+
+	s.Assert().Nil(s.oidc.CreateIdentity(s.ctx, identity))
+	s.Assert().Equal(pgerrs.ErrAlreadyExists, s.oidc.CreateIdentity(s.ctx, identity))
+	unknown := identity
+	unknown.Subject, unknown.Username = "1", "petya"
+	s.Assert().Equal(pgerrs.ErrForeignKey, s.oidc.CreateIdentity(s.ctx, unknown))
+
+	i, err := s.oidc.FindIdentity(s.ctx, "corp", "248289761001")
+	s.Assert().Nil(err)
+	s.Assert().Equal(username, i.Username)
+	_, err = s.oidc.FindIdentity(s.ctx, "google", "248289761001")
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	identities, err := s.oidc.FindIdentitiesByUser(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Len(identities, 1)
+	s.Assert().Equal("corp", identities[0].Provider)
+	identities, err = s.oidc.FindIdentitiesByUser(s.ctx, "petya")
+	s.Assert().Nil(err)
+	s.Assert().Empty(identities)
+}
@@ -0,0 +1,111 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+var personalTokenColumns = []string{"id", "username", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at", "created_at"}
+
+var personalTokenReturning = "returning " + strings.Join(personalTokenColumns, ", ")
+
+type PersonalTokenRepo struct {
+	*postgres.Postgres
+}
+
+func NewPersonalTokenRepo(pg *postgres.Postgres) *PersonalTokenRepo {
+	return &PersonalTokenRepo{pg}
+}
+
+// Create сохраняет токен с полями Username, Name, Prefix, TokenHash, Scopes, ExpiresAt.
+// Возвращает pgerrs.ErrAlreadyExists, если у пользователя уже есть токен с таким названием, и pgerrs.ErrForeignKey, если пользователя нет
+func (r *PersonalTokenRepo) Create(ctx context.Context, t *dbmodel.PersonalToken) error {
+	sql, args, _ := r.Builder.
+		Insert("personal_token").
+		Columns("username", "name", "prefix", "token_hash", "scopes", "expires_at").
+		Values(t.Username, t.Name, t.Prefix, t.TokenHash, t.Scopes, t.ExpiresAt).
+		Suffix("returning id, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&t.Id, &t.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			switch pgErr.Code {
+			case "23505":
+				return pgerrs.ErrAlreadyExists
+			case "23503":
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PersonalTokenRepo) FindByUser(ctx context.Context, username string) ([]dbmodel.PersonalToken, error) {
+	sql, args, _ := r.Builder.
+		Select(personalTokenColumns...).
+		From("personal_token").
+		Where("username = ?", username).
+		OrderBy("id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.PersonalToken])
+}
+
+func (r *PersonalTokenRepo) Delete(ctx context.Context, id int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("personal_token").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Use находит действующий к now токен по хешу и отмечает время его использования.
+// Возвращает pgerrs.ErrNotFound, если токена нет или он истек
+func (r *PersonalTokenRepo) Use(ctx context.Context, tokenHash string, now time.Time) (dbmodel.PersonalToken, error) {
+	sql, args, _ := r.Builder.
+		Update("personal_token").
+		Set("last_used_at", now).
+		Where("token_hash = ?", tokenHash).
+		Where(squirrel.Or{
+			squirrel.Eq{"expires_at": nil},
+			squirrel.Gt{"expires_at": now},
+		}).
+		Suffix(personalTokenReturning).
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.PersonalToken{}, err
+	}
+	token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.PersonalToken])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.PersonalToken{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.PersonalToken{}, err
+	}
+	return token, nil
+}
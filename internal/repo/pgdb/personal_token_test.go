@@ -0,0 +1,94 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestPersonalTokenRepo_Create() {
+	username := s.setupTestsData()
+
+	token := dbmodel.PersonalToken{
+		Username:  username,
+		Name:      "ci",
+		Prefix:    "tdl_abcdefgh",
+		TokenHash: "hash",
+		Scopes:    []string{"tasks:read", "tasks:write"},
+	}
+	s.Assert().Nil(s.personalToken.Create(s.ctx, &token))
+	s.Assert().NotZero(token.Id)
+
+	duplicate := token
+	duplicate.TokenHash = "other"
+	s.Assert().Equal(pgerrs.ErrAlreadyExists, s.personalToken.Create(s.ctx, &duplicate))
+
+	unknown := token
+	unknown.Username, unknown.TokenHash = "petya", "another"
+	s.Assert().Equal(pgerrs.ErrForeignKey, s.personalToken.Create(s.ctx, &unknown))
+
+	tokens, err := s.personalToken.FindByUser(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Len(tokens, 1)
+	s.Assert().Equal(token.Scopes, tokens[0].Scopes)
+	s.Assert().Nil(tokens[0].ExpiresAt)
+	s.Assert().Nil(tokens[0].LastUsedAt)
+
+	s.Assert().Equal(pgerrs.ErrNotFound, s.personalToken.Delete(s.ctx, token.Id, "petya"))
+	s.Assert().Nil(s.personalToken.Delete(s.ctx, token.Id, username))
+	s.Assert().Equal(pgerrs.ErrNotFound, s.personalToken.Delete(s.ctx, token.Id, username))
+}
+
+func (s *pgdbTestSuite) TestPersonalTokenRepo_RevokedOnPasswordChange() {
+	username := s.setupTestsData()
+	token := dbmodel.PersonalToken{Username: username, Name: "ci", Prefix: "tdl_abcdefgh", TokenHash: "hash"}
+	s.Assert().Nil(s.personalToken.Create(s.ctx, &token))
+
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.UpdatePassword(s.ctx, "petya", "new", time.Now()))
+	s.Assert().Nil(s.user.UpdatePassword(s.ctx, username, "new", time.Now()))
+
+	tokens, err := s.personalToken.FindByUser(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Empty(tokens)
+	_, err = s.personalToken.Use(s.ctx, "hash", time.Now())
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+}
+
+func (s *pgdbTestSuite) TestPersonalTokenRepo_Use() {
+	username := s.setupTestsData()
+	now := time.Now().Truncate(time.Second)
+	expiresAt := now.Add(time.Hour)
+
+	if err := s.personalToken.Create(s.ctx, &dbmodel.PersonalToken{
+		Username:  username,
+		Name:      "forever",
+		Prefix:    "tdl_aaaaaaaa",
+		TokenHash: "forever",
+		Scopes:    []string{"tasks:read"},
+	}); err != nil {
+		panic(err)
+	}
+	if err := s.personalToken.Create(s.ctx, &dbmodel.PersonalToken{
+		Username:  username,
+		Name:      "hour",
+		Prefix:    "tdl_bbbbbbbb",
+		TokenHash: "hour",
+		Scopes:    []string{},
+		ExpiresAt: &expiresAt,
+	}); err != nil {
+		panic(err)
+	}
+
+	token, err := s.personalToken.Use(s.ctx, "forever", now)
+	s.Assert().Nil(err)
+	s.Assert().Equal(username, token.Username)
+	s.Assert().True(now.Equal(*token.LastUsedAt))
+
+	_, err = s.personalToken.Use(s.ctx, "hour", now)
+	s.Assert().Nil(err)
+	// истекший токен не принимается
+	_, err = s.personalToken.Use(s.ctx, "hour", expiresAt)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+	_, err = s.personalToken.Use(s.ctx, "unknown", now)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+}
@@ -0,0 +1,61 @@
+package pgdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todolist_api/pkg/postgres"
+	"todolist_api/pkg/ratelimit"
+)
+
+// rateLimitRefill запас корзины после пополнения за время с последнего запроса, не больше емкости
+const rateLimitRefill = "least(?::double precision, b.tokens + extract(epoch from now() - b.updated_at)::double precision * ?::double precision)"
+
+type RateLimitRepo struct {
+	*postgres.Postgres
+}
+
+func NewRateLimitRepo(pg *postgres.Postgres) *RateLimitRepo {
+	return &RateLimitRepo{pg}
+}
+
+// Take пополняет корзину key и забирает из нее токен одним запросом, поэтому конкурентные запросы с разных реплик
+// не могут потратить один токен дважды. Время берется из базы, чтобы не зависеть от расхождения часов реплик
+func (r *RateLimitRepo) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	burst, rate := float64(limit.Requests), limit.Rate()
+	sql, args, _ := r.Builder.
+		Insert("rate_limit_bucket as b").
+		Columns("key", "tokens", "allowed").
+		Values(key, burst-1, true).
+		Suffix(fmt.Sprintf(`on conflict (key) do update set
+			allowed = %[1]s >= 1,
+			tokens = %[1]s - case when %[1]s >= 1 then 1 else 0 end,
+			updated_at = now()
+			returning tokens, allowed`, rateLimitRefill),
+			burst, rate, burst, rate, burst, rate, burst, rate,
+		).
+		ToSql()
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&tokens, &allowed); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.NewResult(limit, tokens, allowed), nil
+}
+
+// DeleteIdle удаляет корзины, к которым не обращались с before, и возвращает их количество
+func (r *RateLimitRepo) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	sql, args, _ := r.Builder.
+		Delete("rate_limit_bucket").
+		Where("updated_at < ?", before).
+		ToSql()
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
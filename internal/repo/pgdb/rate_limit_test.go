@@ -0,0 +1,49 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/pkg/ratelimit"
+)
+
+func (s *pgdbTestSuite) TestRateLimitRepo_Take() {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+
+	r, err := s.rateLimit.Take(s.ctx, "api:user:vasya", limit)
+	s.Assert().Nil(err)
+	s.Assert().True(r.Allowed)
+	s.Assert().Equal(1, r.Remaining)
+
+	r, err = s.rateLimit.Take(s.ctx, "api:user:vasya", limit)
+	s.Assert().Nil(err)
+	s.Assert().True(r.Allowed)
+	s.Assert().Equal(0, r.Remaining)
+
+	r, err = s.rateLimit.Take(s.ctx, "api:user:vasya", limit)
+	s.Assert().Nil(err)
+	s.Assert().False(r.Allowed)
+	s.Assert().Greater(r.RetryAfter, time.Duration(0))
+
+	// у другого ключа своя корзина
+	r, err = s.rateLimit.Take(s.ctx, "api:user:petya", limit)
+	s.Assert().Nil(err)
+	s.Assert().True(r.Allowed)
+}
+
+func (s *pgdbTestSuite) TestRateLimitRepo_DeleteIdle() {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+	_, err := s.rateLimit.Take(s.ctx, "auth:ip:10.0.0.1", limit)
+	s.Assert().Nil(err)
+
+	n, err := s.rateLimit.DeleteIdle(s.ctx, time.Now().Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(int64(0), n)
+
+	n, err = s.rateLimit.DeleteIdle(s.ctx, time.Now().Add(time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(int64(1), n)
+
+	// после удаления корзина снова полная
+	r, err := s.rateLimit.Take(s.ctx, "auth:ip:10.0.0.1", limit)
+	s.Assert().Nil(err)
+	s.Assert().Equal(1, r.Remaining)
+}
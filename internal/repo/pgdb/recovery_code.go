@@ -0,0 +1,86 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+type RecoveryCodeRepo struct {
+	*postgres.Postgres
+}
+
+func NewRecoveryCodeRepo(pg *postgres.Postgres) *RecoveryCodeRepo {
+	return &RecoveryCodeRepo{pg}
+}
+
+// Replace удаляет все коды восстановления пользователя и сохраняет новые одной транзакцией
+func (r *RecoveryCodeRepo) Replace(ctx context.Context, username string, codeHashes []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.
+		Delete("recovery_code").
+		Where("username = ?", username).
+		ToSql()
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+
+	if len(codeHashes) > 0 {
+		q := r.Builder.
+			Insert("recovery_code").
+			Columns("username", "code_hash")
+		for _, hash := range codeHashes {
+			q = q.Values(username, hash)
+		}
+		sql, args, _ = q.ToSql()
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// FindUnused возвращает неиспользованные коды восстановления пользователя
+func (r *RecoveryCodeRepo) FindUnused(ctx context.Context, username string) ([]dbmodel.RecoveryCode, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "username", "code_hash", "used_at", "created_at").
+		From("recovery_code").
+		Where("username = ?", username).
+		Where("used_at is null").
+		OrderBy("id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.RecoveryCode])
+}
+
+// Use помечает код восстановления использованным. Возвращает pgerrs.ErrNotFound, если его уже использовали
+func (r *RecoveryCodeRepo) Use(ctx context.Context, id int, usedAt time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("recovery_code").
+		Set("used_at", usedAt).
+		Where("id = ?", id).
+		Where("used_at is null").
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
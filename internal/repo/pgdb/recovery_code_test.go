@@ -0,0 +1,35 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestRecoveryCodeRepo_Replace() {
+	username := s.setupTestsData()
+	now := time.Now()
+
+	s.Assert().Nil(s.recovery.Replace(s.ctx, username, []string{"a", "b", "c"}))
+	codes, err := s.recovery.FindUnused(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Len(codes, 3)
+
+	s.Assert().Nil(s.recovery.Use(s.ctx, codes[0].Id, now))
+	s.Assert().Equal(pgerrs.ErrNotFound, s.recovery.Use(s.ctx, codes[0].Id, now))
+	codes, err = s.recovery.FindUnused(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Len(codes, 2)
+	s.Assert().Equal("b", codes[0].CodeHash)
+
+	// новые коды полностью заменяют старые, в том числе использованные
+	s.Assert().Nil(s.recovery.Replace(s.ctx, username, []string{"d"}))
+	codes, err = s.recovery.FindUnused(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Len(codes, 1)
+	s.Assert().Equal("d", codes[0].CodeHash)
+
+	s.Assert().Nil(s.recovery.Replace(s.ctx, username, nil))
+	codes, err = s.recovery.FindUnused(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Empty(codes)
+}
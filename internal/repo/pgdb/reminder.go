@@ -0,0 +1,167 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+var reminderColumns = []string{
+	"id", "task_id", "username", "remind_at", "offset_minutes", "channel", "target",
+	"sent_at", "attempts", "last_error", "created_at",
+}
+
+// claimRemindersQuery захватывает до $4 напоминаний, время которых наступило к $1: продлевает им locked_until до $2
+// и увеличивает счетчик попыток. skip locked пропускает строки, которые в этот момент захватывает другая реплика,
+// а locked_until не дает повторно взять напоминание, пока захватившая его реплика не закончила отправку.
+// owner_email - подтвержденный email владельца: письмо уходит, только если target все еще совпадает с ним
+const claimRemindersQuery = `with claimed as (
+  update reminder r
+  set locked_until = $2, attempts = r.attempts + 1
+  from task t, "user" u
+  where t.id = r.task_id
+    and u.username = r.username
+    and r.id in (
+      select id from reminder
+      where sent_at is null
+        and remind_at <= $1
+        and attempts < $3
+        and (locked_until is null or locked_until <= $1)
+      order by remind_at
+      limit $4
+      for update skip locked
+    )
+  returning r.id, r.task_id, r.username, r.remind_at, r.offset_minutes, r.channel, r.target,
+    r.sent_at, r.attempts, r.last_error, r.created_at, t.title as task_title, t.due_date as task_due_date,
+    case when u.email_verified then u.email else '' end as owner_email
+)
+select * from claimed order by remind_at, id`
+
+type ReminderRepo struct {
+	*postgres.Postgres
+}
+
+func NewReminderRepo(pg *postgres.Postgres) *ReminderRepo {
+	return &ReminderRepo{pg}
+}
+
+// Create создает напоминание по задаче пользователя. Если задан OffsetMinutes, время напоминания
+// считается от срока задачи, иначе используется RemindAt. Возвращает pgerrs.ErrNotFound, если задачи нет
+func (r *ReminderRepo) Create(ctx context.Context, reminder *dbmodel.Reminder) error {
+	var remindAt *time.Time
+	if reminder.OffsetMinutes == nil {
+		remindAt = &reminder.RemindAt
+	}
+
+	sql, args, _ := r.Builder.
+		Insert("reminder").
+		Columns("task_id", "username", "remind_at", "offset_minutes", "channel", "target").
+		Select(r.Builder.
+			Select("id", "username").
+			Column("coalesce(?::timestamptz, due_date - make_interval(mins => ?::integer))", remindAt, reminder.OffsetMinutes).
+			Column("?::integer", reminder.OffsetMinutes).
+			Column("?::varchar", reminder.Channel).
+			Column("?::varchar", reminder.Target).
+			From("task").
+			Where("id = ?", reminder.TaskId).
+			Where("username = ?", reminder.Username),
+		).
+		Suffix("returning id, remind_at, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&reminder.Id, &reminder.RemindAt, &reminder.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *ReminderRepo) FindByTask(ctx context.Context, taskId int, username string) ([]dbmodel.Reminder, error) {
+	sql, args, _ := r.Builder.
+		Select(reminderColumns...).
+		From("reminder").
+		Where("task_id = ?", taskId).
+		Where("username = ?", username).
+		OrderBy("remind_at", "id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.Reminder])
+}
+
+func (r *ReminderRepo) FindByUser(ctx context.Context, username string) ([]dbmodel.Reminder, error) {
+	sql, args, _ := r.Builder.
+		Select(reminderColumns...).
+		From("reminder").
+		Where("username = ?", username).
+		OrderBy("remind_at", "id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.Reminder])
+}
+
+func (r *ReminderRepo) Delete(ctx context.Context, id int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("reminder").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Claim захватывает на время lease до limit неотправленных напоминаний, время которых наступило к now
+// и у которых было меньше maxAttempts попыток отправки
+func (r *ReminderRepo) Claim(ctx context.Context, now time.Time, lease time.Duration, limit, maxAttempts int) ([]dbmodel.DueReminder, error) {
+	rows, err := r.Pool.Query(ctx, claimRemindersQuery, now, now.Add(lease), maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.DueReminder])
+}
+
+func (r *ReminderRepo) MarkSent(ctx context.Context, id int, sentAt time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("reminder").
+		Set("sent_at", sentAt).
+		Set("locked_until", nil).
+		Set("last_error", "").
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// MarkFailed сохраняет ошибку отправки и откладывает следующую попытку до retryAt
+func (r *ReminderRepo) MarkFailed(ctx context.Context, id int, lastError string, retryAt time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("reminder").
+		Set("locked_until", retryAt).
+		Set("last_error", lastError).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,167 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestReminderRepo_Create() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "i")
+	remindAt := time.Date(2024, 8, 27, 9, 0, 0, 0, time.UTC)
+	offset := 90
+
+	testCases := []struct {
+		testName       string
+		reminder       *dbmodel.Reminder
+		expectRemindAt time.Time
+		expectErr      error
+	}{
+		{
+			testName: "Absolute time",
+			reminder: &dbmodel.Reminder{
+				TaskId:   ids[0],
+				Username: username,
+				RemindAt: remindAt,
+				Channel:  dbmodel.ReminderChannelEmail,
+				Target:   "vasya@example.com",
+			},
+			expectRemindAt: remindAt,
+			expectErr:      nil,
+		},
+		{
+			testName: "Relative to due date",
+			reminder: &dbmodel.Reminder{
+				TaskId:        ids[0],
+				Username:      username,
+				OffsetMinutes: &offset,
+				Channel:       dbmodel.ReminderChannelWebhook,
+				Target:        "https://example.com/hook",
+			},
+			expectRemindAt: time.Date(2024, 8, 27, 21, 30, 0, 0, time.UTC),
+			expectErr:      nil,
+		},
+		{
+			testName: "Task of another user",
+			reminder: &dbmodel.Reminder{
+				TaskId:   ids[0],
+				Username: "petya",
+				RemindAt: remindAt,
+				Channel:  dbmodel.ReminderChannelEmail,
+				Target:   "petya@example.com",
+			},
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.reminder.Create(s.ctx, tc.reminder)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			s.Assert().Equal(tc.expectRemindAt, tc.reminder.RemindAt, tc.testName)
+		}
+	}
+}
+
+func (s *pgdbTestSuite) TestReminderRepo_Claim() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i")
+	now := time.Date(2024, 8, 27, 12, 0, 0, 0, time.UTC)
+
+	for i, remindAt := range []time.Time{now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)} {
+		reminder := &dbmodel.Reminder{
+			TaskId:   ids[i%2],
+			Username: username,
+			RemindAt: remindAt,
+			Channel:  dbmodel.ReminderChannelEmail,
+			Target:   "vasya@example.com",
+		}
+		if err := s.reminder.Create(s.ctx, reminder); err != nil {
+			panic(err)
+		}
+	}
+
+	claimed, err := s.reminder.Claim(s.ctx, now, time.Minute, 10, 2)
+	s.Assert().Nil(err)
+	s.Assert().Len(claimed, 2)
+	s.Assert().Equal("Task b", claimed[0].TaskTitle)
+	s.Assert().Equal(1, claimed[0].Attempts)
+	s.Assert().Empty(claimed[0].OwnerEmail, "Owner email is not verified")
+	sentId, failedId := claimed[0].Id, claimed[1].Id
+
+	claimed, err = s.reminder.Claim(s.ctx, now, time.Minute, 10, 2)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed, "Claimed reminders are locked until lease expires")
+
+	s.Assert().Nil(s.reminder.MarkSent(s.ctx, sentId, now))
+	s.Assert().Nil(s.reminder.MarkFailed(s.ctx, failedId, "connection refused", now.Add(5*time.Minute)))
+
+	claimed, err = s.reminder.Claim(s.ctx, now.Add(2*time.Minute), time.Minute, 10, 2)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed, "Sent reminder is never claimed, failed one waits for retry")
+
+	claimed, err = s.reminder.Claim(s.ctx, now.Add(5*time.Minute), time.Minute, 10, 2)
+	s.Assert().Nil(err)
+	s.Assert().Len(claimed, 1)
+	s.Assert().Equal(failedId, claimed[0].Id)
+	s.Assert().Equal(2, claimed[0].Attempts)
+	s.Assert().Equal("connection refused", claimed[0].LastError)
+
+	s.Assert().Nil(s.reminder.MarkFailed(s.ctx, failedId, "connection refused", now.Add(6*time.Minute)))
+	claimed, err = s.reminder.Claim(s.ctx, now.Add(10*time.Minute), time.Minute, 10, 2)
+	s.Assert().Nil(err)
+	s.Assert().Empty(claimed, "Attempts exhausted")
+}
+
+func (s *pgdbTestSuite) TestReminderRepo_RescheduleOnDueDateChange() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "i")
+	offset := 60
+	remindAt := time.Date(2024, 8, 27, 9, 0, 0, 0, time.UTC)
+
+	relative := &dbmodel.Reminder{
+		TaskId:        ids[0],
+		Username:      username,
+		OffsetMinutes: &offset,
+		Channel:       dbmodel.ReminderChannelEmail,
+		Target:        "vasya@example.com",
+	}
+	absolute := &dbmodel.Reminder{
+		TaskId:   ids[0],
+		Username: username,
+		RemindAt: remindAt,
+		Channel:  dbmodel.ReminderChannelEmail,
+		Target:   "vasya@example.com",
+	}
+	for _, r := range []*dbmodel.Reminder{relative, absolute} {
+		if err := s.reminder.Create(s.ctx, r); err != nil {
+			panic(err)
+		}
+		if err := s.reminder.MarkSent(s.ctx, r.Id, remindAt); err != nil {
+			panic(err)
+		}
+	}
+
+	task, err := s.task.FindById(s.ctx, ids[0], username)
+	if err != nil {
+		panic(err)
+	}
+	task.DueDate = time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	if err = s.task.Update(s.ctx, &task); err != nil {
+		panic(err)
+	}
+
+	reminders, err := s.reminder.FindByTask(s.ctx, ids[0], username)
+	s.Assert().Nil(err)
+	s.Assert().Len(reminders, 2)
+
+	s.Assert().Equal(absolute.Id, reminders[0].Id)
+	s.Assert().Equal(remindAt, reminders[0].RemindAt)
+	s.Assert().NotNil(reminders[0].SentAt, "Absolute reminder is not rescheduled")
+
+	s.Assert().Equal(relative.Id, reminders[1].Id)
+	s.Assert().Equal(time.Date(2024, 9, 1, 11, 0, 0, 0, time.UTC), reminders[1].RemindAt)
+	s.Assert().Nil(reminders[1].SentAt, "Relative reminder waits for sending again")
+}
@@ -0,0 +1,173 @@
+package pgdb
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/pkg/postgres"
+)
+
+type SignInAttemptRepo struct {
+	*postgres.Postgres
+}
+
+func NewSignInAttemptRepo(pg *postgres.Postgres) *SignInAttemptRepo {
+	return &SignInAttemptRepo{pg}
+}
+
+// Create сохраняет попытку входа и заодно удаляет попытки старше deleteBefore
+func (r *SignInAttemptRepo) Create(ctx context.Context, a dbmodel.SignInAttempt, deleteBefore time.Time) error {
+	sql, args, _ := r.Builder.
+		Delete("sign_in_attempt").
+		Where("created_at < ?", deleteBefore).
+		ToSql()
+	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+
+	sql, args, _ = r.Builder.
+		Insert("sign_in_attempt").
+		Columns("username", "ip", "user_agent", "method", "success").
+		Values(a.Username, a.IP, a.UserAgent, a.Method, a.Success).
+		ToSql()
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// Reserve записывает попытку a как неудачную и возвращает неудачи под ее именем и с ее IP после since, накопленные до нее.
+// Попытки под одним именем и с одного IP сериализуются advisory lock'ами, поэтому параллельные попытки учитывают друг друга.
+// Заполняет a.Id и a.CreatedAt, исход попытки потом сохраняется через SetSuccess или Delete
+func (r *SignInAttemptRepo) Reserve(ctx context.Context, a *dbmodel.SignInAttempt, since, deleteBefore time.Time) (dbmodel.SignInFailures, dbmodel.SignInFailures, error) {
+	var byUsername, byIP dbmodel.SignInFailures
+
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return byUsername, byIP, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// блокировки берутся по возрастанию ключа, чтобы попытки с пересекающимися именем и IP не ждали друг друга по кругу
+	_, err = tx.Exec(ctx, `select pg_advisory_xact_lock(k) from (values (hashtext($1)), (hashtext($2))) v(k) order by k`,
+		"sign_in_attempt:username:"+a.Username, "sign_in_attempt:ip:"+a.IP)
+	if err != nil {
+		return byUsername, byIP, err
+	}
+
+	sql, args, _ := r.Builder.
+		Delete("sign_in_attempt").
+		Where("created_at < ?", deleteBefore).
+		ToSql()
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return byUsername, byIP, err
+	}
+
+	sql, args = r.failuresByUsernameSql(a.Username, since)
+	if byUsername, err = queryFailures(ctx, tx, sql, args...); err != nil {
+		return byUsername, byIP, err
+	}
+	sql, args = r.failuresByIPSql(a.IP, since)
+	if byIP, err = queryFailures(ctx, tx, sql, args...); err != nil {
+		return byUsername, byIP, err
+	}
+
+	sql, args, _ = r.Builder.
+		Insert("sign_in_attempt").
+		Columns("username", "ip", "user_agent", "method", "success").
+		Values(a.Username, a.IP, a.UserAgent, a.Method, false).
+		Suffix("returning id, created_at").
+		ToSql()
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&a.Id, &a.CreatedAt); err != nil {
+		return byUsername, byIP, err
+	}
+	return byUsername, byIP, tx.Commit(ctx)
+}
+
+// SetSuccess отмечает зарезервированную попытку успешной
+func (r *SignInAttemptRepo) SetSuccess(ctx context.Context, id int) error {
+	sql, args, _ := r.Builder.
+		Update("sign_in_attempt").
+		Set("success", true).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// Delete удаляет попытку, например зарезервированную, исход которой не нужно учитывать
+func (r *SignInAttemptRepo) Delete(ctx context.Context, id int) error {
+	sql, args, _ := r.Builder.
+		Delete("sign_in_attempt").
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// FailuresByUsername неудачные попытки входа под username после since, идущие подряд: успешный вход счетчик сбрасывает
+func (r *SignInAttemptRepo) FailuresByUsername(ctx context.Context, username string, since time.Time) (dbmodel.SignInFailures, error) {
+	sql, args := r.failuresByUsernameSql(username, since)
+	return queryFailures(ctx, r.Pool, sql, args...)
+}
+
+// FailuresByIP неудачные попытки входа с ip после since под любыми именами
+func (r *SignInAttemptRepo) FailuresByIP(ctx context.Context, ip string, since time.Time) (dbmodel.SignInFailures, error) {
+	sql, args := r.failuresByIPSql(ip, since)
+	return queryFailures(ctx, r.Pool, sql, args...)
+}
+
+func (r *SignInAttemptRepo) failuresByUsernameSql(username string, since time.Time) (string, []any) {
+	sql, args, _ := r.Builder.
+		Select("count(*) as count", "max(created_at) as last_at").
+		From("sign_in_attempt").
+		Where("username = ?", username).
+		Where("not success").
+		Where("created_at > ?", since).
+		Where("created_at > coalesce((select max(created_at) from sign_in_attempt where username = ? and success), '-infinity')", username).
+		ToSql()
+	return sql, args
+}
+
+func (r *SignInAttemptRepo) failuresByIPSql(ip string, since time.Time) (string, []any) {
+	sql, args, _ := r.Builder.
+		Select("count(*) as count", "max(created_at) as last_at").
+		From("sign_in_attempt").
+		Where("ip = ?", ip).
+		Where("not success").
+		Where("created_at > ?", since).
+		ToSql()
+	return sql, args
+}
+
+// FindByUsername последние limit попыток входа под username после since, от новых к старым
+func (r *SignInAttemptRepo) FindByUsername(ctx context.Context, username string, since time.Time, limit int) ([]dbmodel.SignInAttempt, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "username", "ip", "user_agent", "method", "success", "created_at").
+		From("sign_in_attempt").
+		Where("username = ?", username).
+		Where("created_at >= ?", since).
+		OrderBy("created_at desc", "id desc").
+		Limit(uint64(limit)).
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.SignInAttempt])
+}
+
+// querier пул или транзакция
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func queryFailures(ctx context.Context, q querier, sql string, args ...any) (dbmodel.SignInFailures, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.SignInFailures{}, err
+	}
+	return pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.SignInFailures])
+}
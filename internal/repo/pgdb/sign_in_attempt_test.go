@@ -0,0 +1,114 @@
+package pgdb
+
+import (
+	"sync"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+)
+
+func (s *pgdbTestSuite) TestSignInAttemptRepo_Failures() {
+	now := time.Now()
+	attempts := []dbmodel.SignInAttempt{
+		{Username: "vasya", IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword, Success: false},
+		{Username: "vasya", IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword, Success: true},
+		{Username: "vasya", IP: "10.0.0.2", Method: dbmodel.SignInMethodPassword, Success: false},
+		{Username: "vasya", IP: "10.0.0.2", Method: dbmodel.SignInMethodTwoFactor, Success: false},
+		{Username: "petya", IP: "10.0.0.2", Method: dbmodel.SignInMethodPassword, Success: false},
+	}
+	for _, a := range attempts {
+		s.Assert().Nil(s.signInAttempt.Create(s.ctx, a, now.Add(-time.Hour)))
+	}
+
+	// успешный вход сбрасывает счетчик неудач по имени, но не по ip
+	failures, err := s.signInAttempt.FailuresByUsername(s.ctx, "vasya", now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(2, failures.Count)
+	s.Assert().NotNil(failures.LastAt)
+
+	failures, err = s.signInAttempt.FailuresByIP(s.ctx, "10.0.0.2", now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(3, failures.Count)
+
+	failures, err = s.signInAttempt.FailuresByUsername(s.ctx, "kolya", now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(0, failures.Count)
+	s.Assert().Nil(failures.LastAt)
+
+	// попытки раньше since не считаются
+	failures, err = s.signInAttempt.FailuresByIP(s.ctx, "10.0.0.2", now.Add(time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(0, failures.Count)
+}
+
+func (s *pgdbTestSuite) TestSignInAttemptRepo_FindByUsername() {
+	now := time.Now()
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		a := dbmodel.SignInAttempt{Username: "vasya", IP: ip, UserAgent: "curl/8.0", Method: dbmodel.SignInMethodPassword}
+		s.Assert().Nil(s.signInAttempt.Create(s.ctx, a, now.Add(-time.Hour)))
+	}
+
+	attempts, err := s.signInAttempt.FindByUsername(s.ctx, "vasya", now.Add(-time.Hour), 2)
+	s.Assert().Nil(err)
+	s.Assert().Len(attempts, 2)
+	s.Assert().Equal("10.0.0.3", attempts[0].IP)
+	s.Assert().Equal("10.0.0.2", attempts[1].IP)
+	s.Assert().Equal("curl/8.0", attempts[0].UserAgent)
+
+	attempts, err = s.signInAttempt.FindByUsername(s.ctx, "petya", now.Add(-time.Hour), 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(attempts)
+
+	// создание попытки удаляет историю старше deleteBefore
+	s.Assert().Nil(s.signInAttempt.Create(s.ctx, dbmodel.SignInAttempt{Username: "petya", IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword}, now.Add(time.Hour)))
+	attempts, err = s.signInAttempt.FindByUsername(s.ctx, "vasya", now.Add(-time.Hour), 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(attempts)
+}
+
+func (s *pgdbTestSuite) TestSignInAttemptRepo_Reserve() {
+	now := time.Now()
+	s.Assert().Nil(s.signInAttempt.Create(s.ctx, dbmodel.SignInAttempt{Username: "vasya", IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword}, now.Add(-time.Hour)))
+
+	// параллельные резервы сериализуются: каждый видит неудачи, зарезервированные до него
+	const parallel = 5
+	counts := make(chan int, parallel)
+	var wg sync.WaitGroup
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := dbmodel.SignInAttempt{Username: "vasya", IP: "10.0.0.2", Method: dbmodel.SignInMethodPassword}
+			byUsername, _, err := s.signInAttempt.Reserve(s.ctx, &a, now.Add(-time.Hour), now.Add(-time.Hour))
+			s.Assert().Nil(err)
+			s.Assert().NotZero(a.Id)
+			counts <- byUsername.Count
+		}()
+	}
+	wg.Wait()
+	close(counts)
+
+	var seen []int
+	for c := range counts {
+		seen = append(seen, c)
+	}
+	s.Assert().ElementsMatch([]int{1, 2, 3, 4, 5}, seen)
+
+	a := dbmodel.SignInAttempt{Username: "vasya", IP: "10.0.0.2", Method: dbmodel.SignInMethodPassword}
+	byUsername, byIP, err := s.signInAttempt.Reserve(s.ctx, &a, now.Add(-time.Hour), now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(parallel+1, byUsername.Count)
+	s.Assert().Equal(parallel, byIP.Count)
+
+	// снятый резерв не считается, успешный сбрасывает счетчик по имени
+	s.Assert().Nil(s.signInAttempt.Delete(s.ctx, a.Id))
+	failures, err := s.signInAttempt.FailuresByIP(s.ctx, "10.0.0.2", now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(parallel, failures.Count)
+
+	_, _, err = s.signInAttempt.Reserve(s.ctx, &a, now.Add(-time.Hour), now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Nil(s.signInAttempt.SetSuccess(s.ctx, a.Id))
+	failures, err = s.signInAttempt.FailuresByUsername(s.ctx, "vasya", now.Add(-time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(0, failures.Count)
+}
@@ -3,13 +3,26 @@ package pgdb
 import (
 	"context"
 	"errors"
+	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/lexorank"
 	"todolist_api/pkg/postgres"
 )
 
+// taskColumns - колонки задачи в порядке, в котором их сканирует scanTask
+var taskColumns = []string{
+	"id", "username", "title", "description", "due_date", "created_at", "updated_at",
+	"priority", "position", "status", "column_id", "tags", "estimated_minutes", "all_day",
+	"recurrence",
+}
+
+var taskReturning = "returning " + strings.Join(taskColumns, ", ")
+
 type TaskRepo struct {
 	*postgres.Postgres
 }
@@ -19,22 +32,20 @@ func NewTaskRepo(pg *postgres.Postgres) *TaskRepo {
 }
 
 // Create создает запись.
-// На вход принимает task с полями: Username, Title, Description, DueDate
+// На вход принимает task с полями: Username, Title, Description, DueDate, AllDay, Priority, Position, Status, Tags,
+// EstimatedMinutes, Recurrence
 // Остальные поля структуры обновляются после записи в бд (поэтому в аргументах пойнтер)
 func (r *TaskRepo) Create(ctx context.Context, t *dbmodel.Task) error {
 	sql, args, _ := r.Builder.
 		Insert("task").
-		Columns("username", "title", "description", "due_date").
-		Values(t.Username, t.Title, t.Description, t.DueDate).
-		Suffix("returning id, created_at, updated_at").
+		Columns("username", "title", "description", "due_date", "all_day", "priority", "position", "status", "tags",
+			"estimated_minutes", "recurrence").
+		Values(t.Username, t.Title, t.Description, t.DueDate, t.AllDay, t.Priority, t.Position, t.Status, t.Tags,
+			t.EstimatedMinutes, t.Recurrence).
+		Suffix(taskReturning).
 		ToSql()
 
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
-		&t.Id,
-		&t.CreatedAt,
-		&t.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(r.Pool.QueryRow(ctx, sql, args...), t); err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
 			if pgErr.Code == "23503" {
@@ -46,57 +57,37 @@ func (r *TaskRepo) Create(ctx context.Context, t *dbmodel.Task) error {
 	return nil
 }
 
-func (r *TaskRepo) Find(ctx context.Context, username string) ([]dbmodel.Task, error) {
-	sql, args, _ := r.Builder.
-		Select("id", "title", "description", "due_date", "created_at", "updated_at").
+// Find возвращает задачи пользователя.
+// sort задает порядок: dbmodel.TaskSortPriority, dbmodel.TaskSortPosition, по умолчанию - в порядке создания
+func (r *TaskRepo) Find(ctx context.Context, username, sort string) ([]dbmodel.Task, error) {
+	q := r.Builder.
+		Select(taskColumns...).
 		From("task").
-		Where("username = ?", username).
-		ToSql()
+		Where("username = ?", username)
 
-	rows, err := r.Pool.Query(ctx, sql, args...)
-	if err != nil {
-		return nil, err
+	switch sort {
+	case dbmodel.TaskSortPriority:
+		q = q.OrderBy("priority", "position", "id")
+	case dbmodel.TaskSortPosition:
+		q = q.OrderBy("position", "id")
+	default:
+		q = q.OrderBy("id")
 	}
-	defer rows.Close()
-
-	var result []dbmodel.Task
-	for rows.Next() {
-		var task dbmodel.Task
-
-		err = rows.Scan(
-			&task.Id,
-			&task.Title,
-			&task.Description,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
+	sql, args, _ := q.ToSql()
 
-		result = append(result, task)
-	}
-	return result, nil
+	return r.queryTasks(ctx, sql, args...)
 }
 
 func (r *TaskRepo) FindById(ctx context.Context, id int, username string) (dbmodel.Task, error) {
 	sql, args, _ := r.Builder.
-		Select("title", "description", "due_date", "created_at", "updated_at").
+		Select(taskColumns...).
 		From("task").
 		Where("id = ?", id).
 		Where("username = ?", username).
 		ToSql()
 
 	var task dbmodel.Task
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
-		&task.Title,
-		&task.Description,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(r.Pool.QueryRow(ctx, sql, args...), &task); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return dbmodel.Task{}, pgerrs.ErrNotFound
 		}
@@ -105,25 +96,41 @@ func (r *TaskRepo) FindById(ctx context.Context, id int, username string) (dbmod
 	return task, nil
 }
 
+func (r *TaskRepo) FindByIds(ctx context.Context, ids []int, username string) ([]dbmodel.Task, error) {
+	sql, args, _ := r.Builder.
+		Select(taskColumns...).
+		From("task").
+		Where(squirrel.Eq{"id": ids}).
+		Where("username = ?", username).
+		OrderBy("id").
+		ToSql()
+
+	return r.queryTasks(ctx, sql, args...)
+}
+
 // Update обновляет запись (по-сути, все доступные поля заменяет на новые).
-// На вход принимает task с полями: Id, Username, Title, Description, DueDate.
-// Поля CreatedAt, UpdatedAt устанавливаются в соответствии с бд аналогично функции Create (через пойнтер)
+// На вход принимает task с полями: Id, Username, Title, Description, DueDate, AllDay, Priority, Status, Tags,
+// EstimatedMinutes, Recurrence.
+// Пустой Status означает, что статус не меняется. Позиция и колонка доски здесь не меняются.
+// Остальные поля устанавливаются в соответствии с бд аналогично функции Create (через пойнтер)
 func (r *TaskRepo) Update(ctx context.Context, t *dbmodel.Task) error {
 	sql, args, _ := r.Builder.
 		Update("task").
 		Set("title", t.Title).
 		Set("description", t.Description).
 		Set("due_date", t.DueDate).
+		Set("all_day", t.AllDay).
+		Set("priority", t.Priority).
+		Set("status", squirrel.Expr("coalesce(nullif(?, ''), status)", t.Status)).
+		Set("tags", t.Tags).
+		Set("estimated_minutes", t.EstimatedMinutes).
+		Set("recurrence", t.Recurrence).
 		Where("id = ?", t.Id).
 		Where("username = ?", t.Username).
-		Suffix("returning created_at, updated_at").
+		Suffix(taskReturning).
 		ToSql()
 
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
-		&t.CreatedAt,
-		&t.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(r.Pool.QueryRow(ctx, sql, args...), t); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return pgerrs.ErrNotFound
 		}
@@ -150,3 +157,252 @@ func (r *TaskRepo) Delete(ctx context.Context, id int, username string) error {
 	}
 	return nil
 }
+
+// FindLastPosition возвращает максимальную позицию среди задач пользователя (пустую строку, если задач нет)
+func (r *TaskRepo) FindLastPosition(ctx context.Context, username string) (string, error) {
+	sql, args, _ := r.Builder.
+		Select("coalesce(max(position), '')").
+		From("task").
+		Where("username = ?", username).
+		ToSql()
+
+	var position string
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&position); err != nil {
+		return "", err
+	}
+	return position, nil
+}
+
+// FindPrevPosition возвращает ближайшую позицию перед position (пустую строку, если такой нет).
+// Задача excludeId не учитывается, чтобы перемещаемая задача не мешала сама себе
+func (r *TaskRepo) FindPrevPosition(ctx context.Context, username, position string, excludeId int) (string, error) {
+	return r.findNeighbourPosition(ctx, "max(position)", squirrel.Lt{"position": position}, username, excludeId)
+}
+
+// FindNextPosition возвращает ближайшую позицию после position (пустую строку, если такой нет)
+func (r *TaskRepo) FindNextPosition(ctx context.Context, username, position string, excludeId int) (string, error) {
+	return r.findNeighbourPosition(ctx, "min(position)", squirrel.Gt{"position": position}, username, excludeId)
+}
+
+func (r *TaskRepo) findNeighbourPosition(ctx context.Context, aggregate string, cond squirrel.Sqlizer, username string, excludeId int) (string, error) {
+	sql, args, _ := r.Builder.
+		Select("coalesce("+aggregate+", '')").
+		From("task").
+		Where("username = ?", username).
+		Where("id <> ?", excludeId).
+		Where(cond).
+		ToSql()
+
+	var position string
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&position); err != nil {
+		return "", err
+	}
+	return position, nil
+}
+
+// UpdatePosition меняет позицию одной задачи, остальные задачи не затрагиваются
+func (r *TaskRepo) UpdatePosition(ctx context.Context, id int, username, position string) error {
+	sql, args, _ := r.Builder.
+		Update("task").
+		Set("position", position).
+		Where("id = ?", id).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Rebalance заново равномерно распределяет позиции всех задач пользователя, сохраняя их порядок.
+// Нужен, когда после множества перемещений ключи становятся слишком длинными
+func (r *TaskRepo) Rebalance(ctx context.Context, username string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.
+		Select("id").
+		From("task").
+		Where("username = ?", username).
+		OrderBy("position", "id").
+		Suffix("for update").
+		ToSql()
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	for i, position := range lexorank.Spread(len(ids)) {
+		sql, args, _ = r.Builder.
+			Update("task").
+			Set("position", position).
+			Where("id = ?", ids[i]).
+			ToSql()
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// FindByBoard возвращает задачи, находящиеся в колонках доски, в пользовательском порядке
+func (r *TaskRepo) FindByBoard(ctx context.Context, boardId int) ([]dbmodel.Task, error) {
+	sql, args, _ := r.Builder.
+		Select(taskColumns...).
+		From("task").
+		Where("column_id in (select id from board_column where board_id = ?)", boardId).
+		OrderBy("position", "id").
+		ToSql()
+
+	return r.queryTasks(ctx, sql, args...)
+}
+
+// MoveToColumn перемещает задачу в колонку доски пользователя.
+// Колонка блокируется на время транзакции, чтобы параллельные перемещения не превысили WIP-лимит.
+// Если у колонки задан статус, задача его получает.
+// Возвращает pgerrs.ErrNotFound, если нет задачи или колонки, pgerrs.ErrLimitExceeded при превышении WIP-лимита
+func (r *TaskRepo) MoveToColumn(ctx context.Context, t *dbmodel.Task, boardId, columnId int) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.
+		Select("c.wip_limit", "c.status").
+		From("board_column c").
+		Join("board b on b.id = c.board_id").
+		Where("c.id = ?", columnId).
+		Where("c.board_id = ?", boardId).
+		Where("b.username = ?", t.Username).
+		Suffix("for update of c").
+		ToSql()
+
+	var (
+		wipLimit int
+		status   string
+	)
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&wipLimit, &status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+
+	if wipLimit > 0 {
+		sql, args, _ = r.Builder.
+			Select("count(*)").
+			From("task").
+			Where("column_id = ?", columnId).
+			Where("id <> ?", t.Id).
+			ToSql()
+
+		var count int
+		if err = tx.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+			return err
+		}
+		if count >= wipLimit {
+			return pgerrs.ErrLimitExceeded
+		}
+	}
+
+	sql, args, _ = r.Builder.
+		Update("task").
+		Set("column_id", columnId).
+		Set("status", squirrel.Expr("coalesce(nullif(?, ''), status)", status)).
+		Where("id = ?", t.Id).
+		Where("username = ?", t.Username).
+		Suffix(taskReturning).
+		ToSql()
+
+	if err = scanTask(tx.QueryRow(ctx, sql, args...), t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// FindDue возвращает незавершенные задачи пользователя со сроком раньше before, по возрастанию срока
+func (r *TaskRepo) FindDue(ctx context.Context, username string, before time.Time) ([]dbmodel.Task, error) {
+	sql, args, _ := r.Builder.
+		Select(taskColumns...).
+		From("task").
+		Where("username = ?", username).
+		Where("status <> ?", dbmodel.TaskStatusDone).
+		Where("due_date < ?", before).
+		OrderBy("due_date", "id").
+		ToSql()
+
+	return r.queryTasks(ctx, sql, args...)
+}
+
+// CountByStatus считает задачи пользователей usernames по статусам. Статусы без задач в результат не попадают
+func (r *TaskRepo) CountByStatus(ctx context.Context, usernames []string) ([]dbmodel.TaskCount, error) {
+	sql, args, _ := r.Builder.
+		Select("username", "status", "count(*) as count").
+		From("task").
+		Where(squirrel.Eq{"username": usernames}).
+		GroupBy("username", "status").
+		OrderBy("username", "status").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TaskCount])
+}
+
+func (r *TaskRepo) queryTasks(ctx context.Context, sql string, args ...any) ([]dbmodel.Task, error) {
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []dbmodel.Task
+	for rows.Next() {
+		var task dbmodel.Task
+		if err = scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		result = append(result, task)
+	}
+	return result, rows.Err()
+}
+
+func scanTask(row pgx.Row, t *dbmodel.Task) error {
+	return row.Scan(
+		&t.Id,
+		&t.Username,
+		&t.Title,
+		&t.Description,
+		&t.DueDate,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&t.Priority,
+		&t.Position,
+		&t.Status,
+		&t.ColumnId,
+		&t.Tags,
+		&t.EstimatedMinutes,
+		&t.AllDay,
+		&t.Recurrence,
+	)
+}
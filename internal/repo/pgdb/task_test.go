@@ -31,11 +31,32 @@ func (s *pgdbTestSuite) TestTaskRepo_Create() {
 	}{
 		{
 			testName: "Correct test",
+			task: &dbmodel.Task{
+				Username:         username,
+				Title:            "Test",
+				Description:      "desc test",
+				DueDate:          time.Date(2024, 8, 27, 23, 0, 0, 0, time.UTC),
+				Priority:         1,
+				Position:         "i",
+				Status:           dbmodel.TaskStatusTodo,
+				Tags:             []string{"home", "urgent"},
+				EstimatedMinutes: 30,
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "All-day task",
 			task: &dbmodel.Task{
 				Username:    username,
-				Title:       "Test",
-				Description: "desc test",
-				DueDate:     time.Date(2024, 8, 27, 23, 0, 0, 0, time.UTC),
+				Title:       "Birthday",
+				Description: "",
+				DueDate:     time.Date(2024, 8, 30, 0, 0, 0, 0, time.UTC),
+				AllDay:      true,
+				Priority:    4,
+				Position:    "j",
+				Status:      dbmodel.TaskStatusTodo,
+				Tags:        []string{},
+				Recurrence:  "FREQ=YEARLY",
 			},
 			expectErr: nil,
 		},
@@ -46,6 +67,10 @@ func (s *pgdbTestSuite) TestTaskRepo_Create() {
 				Title:       "title",
 				Description: "desc",
 				DueDate:     time.Now(),
+				Priority:    4,
+				Position:    "i",
+				Status:      dbmodel.TaskStatusTodo,
+				Tags:        []string{},
 			},
 			expectErr: pgerrs.ErrForeignKey,
 		},
@@ -57,21 +82,13 @@ func (s *pgdbTestSuite) TestTaskRepo_Create() {
 
 		if tc.expectErr == nil {
 			sql, args, _ := s.pg.Builder.
-				Select("*").
+				Select(taskColumns...).
 				From("task").
 				Where("id = ?", tc.task.Id).
 				ToSql()
 
 			var actualTask dbmodel.Task
-			err = s.pg.Pool.QueryRow(s.ctx, sql, args...).Scan(
-				&actualTask.Id,
-				&actualTask.Username,
-				&actualTask.Title,
-				&actualTask.Description,
-				&actualTask.DueDate,
-				&actualTask.CreatedAt,
-				&actualTask.UpdatedAt,
-			)
+			err = scanTask(s.pg.Pool.QueryRow(s.ctx, sql, args...), &actualTask)
 			s.Assert().Nil(err)
 			s.Assert().Equal(*tc.task, actualTask)
 		}
@@ -86,6 +103,10 @@ func (s *pgdbTestSuite) TestTaskRepo_Update() {
 		Title:       "Hello",
 		Description: "desc hello",
 		DueDate:     time.Date(2024, 8, 27, 23, 0, 0, 0, time.UTC),
+		Priority:    4,
+		Position:    "i",
+		Status:      dbmodel.TaskStatusTodo,
+		Tags:        []string{},
 	}
 	if err := s.task.Create(s.ctx, task); err != nil { // так можно делать, потому что есть отдельный тест
 		panic(err)
@@ -104,6 +125,9 @@ func (s *pgdbTestSuite) TestTaskRepo_Update() {
 				Title:       "New title",
 				Description: "New desc",
 				DueDate:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				Priority:    2,
+				Status:      dbmodel.TaskStatusDone,
+				Tags:        []string{},
 			},
 			expectErr: nil,
 		},
@@ -115,6 +139,7 @@ func (s *pgdbTestSuite) TestTaskRepo_Update() {
 				Title:       "New title",
 				Description: "New desc",
 				DueDate:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Priority:    2,
 			},
 			expectErr: pgerrs.ErrNotFound,
 		},
@@ -126,6 +151,7 @@ func (s *pgdbTestSuite) TestTaskRepo_Update() {
 				Title:       "New title",
 				Description: "New desc",
 				DueDate:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Priority:    2,
 			},
 			expectErr: pgerrs.ErrNotFound,
 		},
@@ -137,22 +163,14 @@ func (s *pgdbTestSuite) TestTaskRepo_Update() {
 
 		if tc.expectErr == nil {
 			sql, args, _ := s.pg.Builder.
-				Select("id", "username", "title", "description", "due_date", "created_at", "updated_at").
+				Select(taskColumns...).
 				From("task").
 				Where("id = ?", tc.task.Id).
 				Where("username = ?", tc.task.Username).
 				ToSql()
 
 			var actualTask dbmodel.Task
-			err = s.pg.Pool.QueryRow(s.ctx, sql, args...).Scan(
-				&actualTask.Id,
-				&actualTask.Username,
-				&actualTask.Title,
-				&actualTask.Description,
-				&actualTask.DueDate,
-				&actualTask.CreatedAt,
-				&actualTask.UpdatedAt,
-			)
+			err = scanTask(s.pg.Pool.QueryRow(s.ctx, sql, args...), &actualTask)
 			s.Assert().Nil(err)
 			s.Assert().Equal(*tc.task, actualTask)
 			s.Assert().NotEqual(task.UpdatedAt, actualTask.UpdatedAt)
@@ -168,6 +186,10 @@ func (s *pgdbTestSuite) TestTaskRepo_Delete() {
 		Title:       "Hello",
 		Description: "desc hello",
 		DueDate:     time.Date(2024, 8, 27, 23, 0, 0, 0, time.UTC),
+		Priority:    4,
+		Position:    "i",
+		Status:      dbmodel.TaskStatusTodo,
+		Tags:        []string{},
 	}
 	if err := s.task.Create(s.ctx, task); err != nil { // так можно делать, потому что есть отдельный тест
 		panic(err)
@@ -221,3 +243,132 @@ func (s *pgdbTestSuite) TestTaskRepo_Delete() {
 		}
 	}
 }
+
+func (s *pgdbTestSuite) setupTestsTasks(username string, positions ...string) []int {
+	ids := make([]int, 0, len(positions))
+	for _, position := range positions {
+		task := &dbmodel.Task{
+			Username:    username,
+			Title:       "Task " + position,
+			Description: "desc",
+			DueDate:     time.Date(2024, 8, 27, 23, 0, 0, 0, time.UTC),
+			Priority:    4,
+			Position:    position,
+			Status:      dbmodel.TaskStatusTodo,
+			Tags:        []string{},
+		}
+		if err := s.task.Create(s.ctx, task); err != nil {
+			panic(err)
+		}
+		ids = append(ids, task.Id)
+	}
+	return ids
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_Find() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "r", "b", "i")
+
+	// приоритеты: второй задаче выше всех
+	sql, args, _ := s.pg.Builder.Update("task").Set("priority", 1).Where("id = ?", ids[1]).ToSql()
+	if _, err := s.pg.Pool.Exec(s.ctx, sql, args...); err != nil {
+		panic(err)
+	}
+
+	testCases := []struct {
+		testName  string
+		sort      string
+		expectIds []int
+	}{
+		{
+			testName:  "Creation order",
+			sort:      "",
+			expectIds: ids,
+		},
+		{
+			testName:  "Sort by position",
+			sort:      dbmodel.TaskSortPosition,
+			expectIds: []int{ids[1], ids[2], ids[0]},
+		},
+		{
+			testName:  "Sort by priority",
+			sort:      dbmodel.TaskSortPriority,
+			expectIds: []int{ids[1], ids[2], ids[0]},
+		},
+	}
+
+	for _, tc := range testCases {
+		tasks, err := s.task.Find(s.ctx, username, tc.sort)
+		s.Assert().Nil(err)
+
+		actualIds := make([]int, 0, len(tasks))
+		for _, t := range tasks {
+			actualIds = append(actualIds, t.Id)
+		}
+		s.Assert().Equal(tc.expectIds, actualIds, tc.testName)
+	}
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_FindNeighbourPosition() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i", "r")
+
+	prev, err := s.task.FindPrevPosition(s.ctx, username, "i", 0)
+	s.Assert().Nil(err)
+	s.Assert().Equal("b", prev)
+
+	next, err := s.task.FindNextPosition(s.ctx, username, "i", 0)
+	s.Assert().Nil(err)
+	s.Assert().Equal("r", next)
+
+	// перемещаемая задача не должна учитываться
+	next, err = s.task.FindNextPosition(s.ctx, username, "i", ids[2])
+	s.Assert().Nil(err)
+	s.Assert().Equal("", next)
+
+	last, err := s.task.FindLastPosition(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal("r", last)
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_Rebalance() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "izzzzzzzzzzzzzzzzzzk", "a", "izzzzzzzzzzzzzzzzzzz")
+
+	err := s.task.Rebalance(s.ctx, username)
+	s.Assert().Nil(err)
+
+	tasks, err := s.task.Find(s.ctx, username, dbmodel.TaskSortPosition)
+	s.Assert().Nil(err)
+	s.Assert().Len(tasks, 3)
+	s.Assert().Equal([]int{ids[1], ids[0], ids[2]}, []int{tasks[0].Id, tasks[1].Id, tasks[2].Id})
+	for _, t := range tasks {
+		s.Assert().Len(t.Position, 1)
+	}
+}
+
+func (s *pgdbTestSuite) TestTaskRepo_CountByStatus() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i", "r")
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: "petya", Password: "abc"}); err != nil {
+		panic(err)
+	}
+	s.setupTestsTasks("petya", "b")
+
+	sql, args, _ := s.pg.Builder.
+		Update("task").
+		Set("status", dbmodel.TaskStatusDone).
+		Where("id = ?", ids[0]).
+		ToSql()
+	if _, err := s.pg.Pool.Exec(s.ctx, sql, args...); err != nil {
+		panic(err)
+	}
+
+	counts, err := s.task.CountByStatus(s.ctx, []string{username, "petya", "kolya"})
+	s.Assert().Nil(err)
+	s.Assert().Equal([]dbmodel.TaskCount{
+		{Username: "petya", Status: dbmodel.TaskStatusTodo, Count: 1},
+		{Username: username, Status: dbmodel.TaskStatusDone, Count: 1},
+		{Username: username, Status: dbmodel.TaskStatusTodo, Count: 2},
+	}, counts)
+}
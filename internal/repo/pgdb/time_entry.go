@@ -0,0 +1,223 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+// timeEntryDuration длительность записи, у запущенного таймера считается до текущего момента
+const timeEntryDuration = "coalesce(e.ended_at, now()) - e.started_at"
+
+type TimeEntryRepo struct {
+	*postgres.Postgres
+}
+
+func NewTimeEntryRepo(pg *postgres.Postgres) *TimeEntryRepo {
+	return &TimeEntryRepo{pg}
+}
+
+// Create создает запись учета времени по задаче пользователя.
+// На вход принимает entry с полями: TaskId, Username, StartedAt, EndedAt, Note.
+// Если EndedAt не задан, запись становится запущенным таймером.
+// Возвращает pgerrs.ErrNotFound, если задачи нет, и pgerrs.ErrAlreadyExists, если у пользователя уже запущен таймер
+func (r *TimeEntryRepo) Create(ctx context.Context, e *dbmodel.TimeEntry) error {
+	sql, args, _ := r.Builder.
+		Insert("time_entry").
+		Columns("task_id", "username", "started_at", "ended_at", "note").
+		Select(r.Builder.
+			Select("id", "username").
+			Column("?::timestamptz", e.StartedAt).
+			Column("?::timestamptz", e.EndedAt).
+			Column("?::text", e.Note).
+			From("task").
+			Where("id = ?", e.TaskId).
+			Where("username = ?", e.Username),
+		).
+		Suffix("returning id, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&e.Id, &e.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Stop останавливает запущенный таймер пользователя по задаче taskId
+func (r *TimeEntryRepo) Stop(ctx context.Context, taskId int, username string, endedAt time.Time) (dbmodel.TimeEntry, error) {
+	sql, args, _ := r.Builder.
+		Update("time_entry").
+		Set("ended_at", squirrel.Expr("greatest(?::timestamptz, started_at)", endedAt)).
+		Where("task_id = ?", taskId).
+		Where("username = ?", username).
+		Where("ended_at is null").
+		Suffix("returning id, task_id, username, started_at, ended_at, note, created_at").
+		ToSql()
+
+	var entry dbmodel.TimeEntry
+	if err := scanTimeEntry(r.Pool.QueryRow(ctx, sql, args...), &entry); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.TimeEntry{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+func (r *TimeEntryRepo) FindRunning(ctx context.Context, username string) (dbmodel.TimeEntry, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "task_id", "username", "started_at", "ended_at", "note", "created_at").
+		From("time_entry").
+		Where("username = ?", username).
+		Where("ended_at is null").
+		ToSql()
+
+	var entry dbmodel.TimeEntry
+	if err := scanTimeEntry(r.Pool.QueryRow(ctx, sql, args...), &entry); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.TimeEntry{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+func (r *TimeEntryRepo) FindByTask(ctx context.Context, taskId int, username string) ([]dbmodel.TimeEntry, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "task_id", "username", "started_at", "ended_at", "note", "created_at").
+		From("time_entry").
+		Where("task_id = ?", taskId).
+		Where("username = ?", username).
+		OrderBy("started_at", "id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TimeEntry])
+}
+
+func (r *TimeEntryRepo) FindByUser(ctx context.Context, username string) ([]dbmodel.TimeEntry, error) {
+	sql, args, _ := r.Builder.
+		Select("id", "task_id", "username", "started_at", "ended_at", "note", "created_at").
+		From("time_entry").
+		Where("username = ?", username).
+		OrderBy("started_at", "id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TimeEntry])
+}
+
+func (r *TimeEntryRepo) Delete(ctx context.Context, id int, username string) error {
+	sql, args, _ := r.Builder.
+		Delete("time_entry").
+		Where("id = ?", id).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgerrs.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// SumByTasks возвращает суммарное отслеженное время по задачам (задач без записей в результате нет)
+func (r *TimeEntryRepo) SumByTasks(ctx context.Context, taskIds []int) (map[int]time.Duration, error) {
+	sql, args, _ := r.Builder.
+		Select("e.task_id", "sum("+timeEntryDuration+")").
+		From("time_entry e").
+		Where(squirrel.Eq{"e.task_id": taskIds}).
+		GroupBy("e.task_id").
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]time.Duration)
+	for rows.Next() {
+		var (
+			taskId   int
+			duration time.Duration
+		)
+		if err = rows.Scan(&taskId, &duration); err != nil {
+			return nil, err
+		}
+		result[taskId] = duration
+	}
+	return result, rows.Err()
+}
+
+// Report суммирует время пользователя по записям, начатым в [from, to), с группировкой groupBy:
+// dbmodel.TimeReportByDay - по дню начала записи в часовом поясе timezone, dbmodel.TimeReportByProject - по доске, на которой находится задача,
+// dbmodel.TimeReportByTag - по тегам задачи (запись задачи с несколькими тегами учитывается в каждом из них).
+// Записи задач без доски или без тегов попадают в группу с пустым ключом
+func (r *TimeEntryRepo) Report(ctx context.Context, username string, from, to time.Time, groupBy, timezone string) ([]dbmodel.TimeReportRow, error) {
+	q := r.Builder.
+		Select("sum("+timeEntryDuration+") as duration", "count(*) as entries").
+		From("time_entry e").
+		Where("e.username = ?", username).
+		Where("e.started_at >= ?", from).
+		Where("e.started_at < ?", to).
+		GroupBy("key").
+		OrderBy("key")
+
+	switch groupBy {
+	case dbmodel.TimeReportByProject:
+		q = q.Column("coalesce(b.name, '') as key").
+			Join("task t on t.id = e.task_id").
+			LeftJoin("board_column c on c.id = t.column_id").
+			LeftJoin("board b on b.id = c.board_id")
+	case dbmodel.TimeReportByTag:
+		q = q.Column("tag as key").
+			Join("task t on t.id = e.task_id").
+			Join("unnest(case when cardinality(t.tags) = 0 then array['']::varchar[] else t.tags end) as tag on true")
+	default:
+		q = q.Column("to_char(e.started_at at time zone ?, 'YYYY-MM-DD') as key", timezone)
+	}
+	sql, args, _ := q.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[dbmodel.TimeReportRow])
+}
+
+func scanTimeEntry(row pgx.Row, e *dbmodel.TimeEntry) error {
+	return row.Scan(
+		&e.Id,
+		&e.TaskId,
+		&e.Username,
+		&e.StartedAt,
+		&e.EndedAt,
+		&e.Note,
+		&e.CreatedAt,
+	)
+}
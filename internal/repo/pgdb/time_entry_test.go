@@ -0,0 +1,182 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestTimeEntryRepo_Create() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i")
+	startedAt := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+	endedAt := startedAt.Add(time.Hour)
+
+	testCases := []struct {
+		testName  string
+		entry     *dbmodel.TimeEntry
+		expectErr error
+	}{
+		{
+			testName: "Correct test",
+			entry: &dbmodel.TimeEntry{
+				TaskId:    ids[0],
+				Username:  username,
+				StartedAt: startedAt,
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "Timer already running",
+			entry: &dbmodel.TimeEntry{
+				TaskId:    ids[1],
+				Username:  username,
+				StartedAt: startedAt,
+			},
+			expectErr: pgerrs.ErrAlreadyExists,
+		},
+		{
+			testName: "Manual entry while timer is running",
+			entry: &dbmodel.TimeEntry{
+				TaskId:    ids[1],
+				Username:  username,
+				StartedAt: startedAt,
+				EndedAt:   &endedAt,
+				Note:      "meeting",
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "Task of another user",
+			entry: &dbmodel.TimeEntry{
+				TaskId:    ids[0],
+				Username:  "petya",
+				StartedAt: startedAt,
+			},
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := s.timeEntry.Create(s.ctx, tc.entry)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+	}
+
+	running, err := s.timeEntry.FindRunning(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal(ids[0], running.TaskId)
+}
+
+func (s *pgdbTestSuite) TestTimeEntryRepo_Stop() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "b", "i")
+	startedAt := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+
+	if err := s.timeEntry.Create(s.ctx, &dbmodel.TimeEntry{TaskId: ids[0], Username: username, StartedAt: startedAt}); err != nil {
+		panic(err)
+	}
+
+	_, err := s.timeEntry.Stop(s.ctx, ids[1], username, startedAt.Add(time.Hour))
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	entry, err := s.timeEntry.Stop(s.ctx, ids[0], username, startedAt.Add(time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal(startedAt.Add(time.Hour), *entry.EndedAt)
+
+	_, err = s.timeEntry.FindRunning(s.ctx, username)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	tracked, err := s.timeEntry.SumByTasks(s.ctx, ids)
+	s.Assert().Nil(err)
+	s.Assert().Equal(map[int]time.Duration{ids[0]: time.Hour}, tracked)
+}
+
+func (s *pgdbTestSuite) TestTimeEntryRepo_Report() {
+	username := s.setupTestsData()
+	board := s.setupTestsBoard(username)
+	ids := s.setupTestsTasks(username, "b", "i")
+
+	column := &dbmodel.BoardColumn{BoardId: board.Id, Name: "To do"}
+	if err := s.board.CreateColumn(s.ctx, column, username); err != nil {
+		panic(err)
+	}
+	task, err := s.task.FindById(s.ctx, ids[0], username)
+	if err != nil {
+		panic(err)
+	}
+	task.Tags = []string{"home", "urgent"}
+	if err = s.task.Update(s.ctx, &task); err != nil {
+		panic(err)
+	}
+	if err = s.task.MoveToColumn(s.ctx, &task, board.Id, column.Id); err != nil {
+		panic(err)
+	}
+
+	day := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+	for _, e := range []struct {
+		taskId    int
+		startedAt time.Time
+		duration  time.Duration
+	}{
+		{taskId: ids[0], startedAt: day, duration: time.Hour},
+		{taskId: ids[1], startedAt: day.Add(2 * time.Hour), duration: 30 * time.Minute},
+		{taskId: ids[0], startedAt: day.Add(24 * time.Hour), duration: 15 * time.Minute},
+	} {
+		endedAt := e.startedAt.Add(e.duration)
+		entry := &dbmodel.TimeEntry{TaskId: e.taskId, Username: username, StartedAt: e.startedAt, EndedAt: &endedAt}
+		if err := s.timeEntry.Create(s.ctx, entry); err != nil {
+			panic(err)
+		}
+	}
+
+	testCases := []struct {
+		testName     string
+		groupBy      string
+		timezone     string
+		expectReport []dbmodel.TimeReportRow
+	}{
+		{
+			testName: "By day",
+			groupBy:  dbmodel.TimeReportByDay,
+			timezone: "UTC",
+			expectReport: []dbmodel.TimeReportRow{
+				{Key: "2024-08-27", Duration: 90 * time.Minute, Entries: 2},
+				{Key: "2024-08-28", Duration: 15 * time.Minute, Entries: 1},
+			},
+		},
+		{
+			testName: "By day in user timezone",
+			groupBy:  dbmodel.TimeReportByDay,
+			timezone: "Pacific/Kiritimati",
+			expectReport: []dbmodel.TimeReportRow{
+				{Key: "2024-08-28", Duration: 90 * time.Minute, Entries: 2},
+				{Key: "2024-08-29", Duration: 15 * time.Minute, Entries: 1},
+			},
+		},
+		{
+			testName: "By project",
+			groupBy:  dbmodel.TimeReportByProject,
+			timezone: "UTC",
+			expectReport: []dbmodel.TimeReportRow{
+				{Key: "", Duration: 30 * time.Minute, Entries: 1},
+				{Key: board.Name, Duration: 75 * time.Minute, Entries: 2},
+			},
+		},
+		{
+			testName: "By tag",
+			groupBy:  dbmodel.TimeReportByTag,
+			timezone: "UTC",
+			expectReport: []dbmodel.TimeReportRow{
+				{Key: "", Duration: 30 * time.Minute, Entries: 1},
+				{Key: "home", Duration: 75 * time.Minute, Entries: 2},
+				{Key: "urgent", Duration: 75 * time.Minute, Entries: 2},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		report, err := s.timeEntry.Report(s.ctx, username, day, day.Add(48*time.Hour), tc.groupBy, tc.timezone)
+		s.Assert().Nil(err, tc.testName)
+		s.Assert().Equal(tc.expectReport, report, tc.testName)
+	}
+}
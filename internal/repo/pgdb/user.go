@@ -3,13 +3,60 @@ package pgdb
 import (
 	"context"
 	"errors"
+	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo/pgerrs"
 	"todolist_api/pkg/postgres"
 )
 
+// userColumns - колонки пользователя в порядке, в котором их сканирует scanUser
+var userColumns = []string{
+	"id", "username", "password", "created_at", "timezone", "display_name", "email", "preferences", "sessions_valid_after",
+	"delete_at", "email_verified", "totp_secret", "totp_enabled", "totp_last_step", "roles", "disabled_at",
+}
+
+// deleteScheduledUsersQuery удаляет пользователей с delete_at <= $1 вместе с их попытками входа
+const deleteScheduledUsersQuery = `with deleted as (
+  delete from "user"
+  where delete_at <= $1
+  returning username
+), deleted_attempts as (
+  delete from sign_in_attempt
+  where username in (select username from deleted)
+)
+select username from deleted order by username`
+
+// updatePasswordQuery задает пользователю $1 пароль $2 и sessions_valid_after $3 и удаляет его персональные токены
+const updatePasswordQuery = `with updated as (
+  update "user"
+  set password = $2, sessions_valid_after = $3
+  where username = $1
+  returning id, username
+), revoked as (
+  delete from personal_token
+  where username in (select username from updated)
+)
+select id from updated`
+
+// updateUsernameQuery переименовывает пользователя $1 в $2, задает sessions_valid_after $3 и переносит на новое имя
+// его историю входов, у которой нет внешнего ключа
+const updateUsernameQuery = `with updated as (
+  update "user"
+  set username = $2, sessions_valid_after = $3
+  where username = $1
+  returning id, username
+), renamed_attempts as (
+  update sign_in_attempt
+  set username = $2
+  where username = $1
+    and exists (select 1 from updated)
+)
+select id from updated`
+
 type UserRepo struct {
 	*postgres.Postgres
 }
@@ -21,8 +68,8 @@ func NewUserRepo(pg *postgres.Postgres) *UserRepo {
 func (r *UserRepo) Create(ctx context.Context, u dbmodel.User) error {
 	sql, args, _ := r.Builder.
 		Insert("\"user\"").
-		Columns("username", "password").
-		Values(u.Username, u.Password).
+		Columns("username", "password", "email", "display_name").
+		Values(u.Username, u.Password, u.Email, u.DisplayName).
 		ToSql()
 
 	if _, err := r.Pool.Exec(ctx, sql, args...); err != nil {
@@ -37,27 +84,246 @@ func (r *UserRepo) Create(ctx context.Context, u dbmodel.User) error {
 	return nil
 }
 
+// FindByEmail ищет пользователя, подтвердившего email, без учета регистра. Неподтвержденный адрес могут указать
+// несколько пользователей, и принадлежащим им он не считается
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (dbmodel.User, error) {
+	sql, args, _ := r.Builder.
+		Select("username").
+		From("\"user\"").
+		Where("lower(email) = lower(?)", email).
+		Where("email <> ''").
+		Where("email_verified").
+		ToSql()
+
+	var username string
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&username); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.User{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.User{}, err
+	}
+	return r.FindByUsername(ctx, username)
+}
+
 func (r *UserRepo) FindByUsername(ctx context.Context, username string) (dbmodel.User, error) {
 	sql, args, _ := r.Builder.
-		Select("*").
+		Select(userColumns...).
 		From("\"user\"").
 		Where("username = ?", username).
 		ToSql()
 
+	user, err := scanUser(r.Pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.User{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.User{}, err
+	}
+	return user, nil
+}
+
+// Search ищет пользователей по вхождению query в имя, отображаемое имя или email без учета регистра,
+// пустой query - все пользователи. Пользователи упорядочены по имени
+func (r *UserRepo) Search(ctx context.Context, query string, limit, offset int) ([]dbmodel.User, error) {
+	b := r.Builder.
+		Select(userColumns...).
+		From("\"user\"").
+		OrderBy("username").
+		Limit(uint64(limit)).
+		Offset(uint64(offset))
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		b = b.Where(squirrel.Or{
+			squirrel.ILike{"username": pattern},
+			squirrel.ILike{"display_name": pattern},
+			squirrel.ILike{"email": pattern},
+		})
+	}
+	sql, args, _ := b.ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []dbmodel.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func scanUser(row pgx.Row) (dbmodel.User, error) {
 	var user dbmodel.User
 
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := row.Scan(
 		&user.Id,
 		&user.Username,
 		&user.Password,
 		&user.CreatedAt,
+		&user.Timezone,
+		&user.DisplayName,
+		&user.Email,
+		&user.Preferences,
+		&user.SessionsValidAfter,
+		&user.DeleteAt,
+		&user.EmailVerified,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		&user.Roles,
+		&user.DisabledAt,
 	)
+	return user, err
+}
+
+// Update обновляет профиль пользователя: DisplayName, Email, Timezone, Preferences.
+// При смене email он перестает считаться подтвержденным.
+// Возвращает pgerrs.ErrAlreadyExists, если email уже занят другим пользователем
+func (r *UserRepo) Update(ctx context.Context, u dbmodel.User) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("display_name", u.DisplayName).
+		Set("email", u.Email).
+		Set("email_verified", squirrel.Expr("email_verified and lower(email) = lower(?)", u.Email)).
+		Set("timezone", u.Timezone).
+		Set("preferences", u.Preferences).
+		Where("username = ?", u.Username).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// VerifyEmail подтверждает email пользователя. Возвращает pgerrs.ErrNotFound, если у пользователя уже другой email,
+// и pgerrs.ErrAlreadyExists, если этот email уже подтвердил другой пользователь
+func (r *UserRepo) VerifyEmail(ctx context.Context, username, email string) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("email_verified", true).
+		Where("username = ?", username).
+		Where("lower(email) = lower(?)", email).
+		Where("email <> ''").
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
 
+// UpdatePassword меняет пароль и отзывает токены, выданные раньше validAfter. Персональные токены доступа удаляются
+// тем же запросом: пароль меняют в том числе при компрометации аккаунта, а токены не зависят от sessions_valid_after
+func (r *UserRepo) UpdatePassword(ctx context.Context, username, password string, validAfter time.Time) error {
+	return r.execReturning(ctx, updatePasswordQuery, username, password, validAfter)
+}
+
+// UpdateUsername переименовывает пользователя, данные пользователя переносятся каскадно внешними ключами,
+// история входов - в том же запросе.
+// Токены, выданные раньше validAfter, отзываются. Возвращает pgerrs.ErrAlreadyExists, если имя занято
+func (r *UserRepo) UpdateUsername(ctx context.Context, username, newUsername string, validAfter time.Time) error {
+	return r.execReturning(ctx, updateUsernameQuery, username, newUsername, validAfter)
+}
+
+// ScheduleDeletion назначает удаление пользователя на deleteAt, deleteAt == nil отменяет удаление
+func (r *UserRepo) ScheduleDeletion(ctx context.Context, username string, deleteAt *time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("delete_at", deleteAt).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// DeleteScheduled удаляет пользователей, время удаления которых наступило к now, и возвращает их имена.
+// Задачи и остальные данные удаляются каскадно внешними ключами, история входов, у которой внешнего ключа нет, -
+// в том же запросе. Все это происходит в одной транзакции с удалением пользователя
+func (r *UserRepo) DeleteScheduled(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := r.Pool.Query(ctx, deleteScheduledUsersQuery, now)
 	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// SetTOTP сохраняет секрет TOTP и включает или выключает двухфакторную аутентификацию.
+// Счетчик использованных шагов сбрасывается, пустой secret удаляет секрет
+func (r *UserRepo) SetTOTP(ctx context.Context, username, secret string, enabled bool) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_secret", secret).
+		Set("totp_enabled", enabled).
+		Set("totp_last_step", 0).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// UseTOTPStep запоминает использованный шаг TOTP. Возвращает pgerrs.ErrNotFound, если код этого
+// или более позднего шага уже использовался: так один код нельзя принять дважды
+func (r *UserRepo) UseTOTPStep(ctx context.Context, username string, step int64) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("totp_last_step", step).
+		Where("username = ?", username).
+		Where("totp_last_step < ?", step).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// SetRoles задает роли пользователя и отзывает токены, выданные раньше validAfter: в них записаны прежние роли
+func (r *UserRepo) SetRoles(ctx context.Context, username string, roles []string, validAfter time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("roles", roles).
+		Set("sessions_valid_after", validAfter).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// SetDisabled отключает аккаунт с момента disabledAt, disabledAt == nil включает его снова.
+// Токены, выданные раньше validAfter, отзываются
+func (r *UserRepo) SetDisabled(ctx context.Context, username string, disabledAt *time.Time, validAfter time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("\"user\"").
+		Set("disabled_at", disabledAt).
+		Set("sessions_valid_after", validAfter).
+		Where("username = ?", username).
+		Suffix("returning id").
+		ToSql()
+
+	return r.execReturning(ctx, sql, args...)
+}
+
+// escapeLike экранирует спецсимволы шаблона like, чтобы они искались как обычные символы
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *UserRepo) execReturning(ctx context.Context, sql string, args ...any) error {
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(nil); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return dbmodel.User{}, pgerrs.ErrNotFound
+			return pgerrs.ErrNotFound
 		}
-		return dbmodel.User{}, err
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23505" {
+				return pgerrs.ErrAlreadyExists
+			}
+		}
+		return err
 	}
-	return user, nil
+	return nil
 }
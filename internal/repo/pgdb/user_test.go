@@ -1,6 +1,7 @@
 package pgdb
 
 import (
+	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo/pgerrs"
 )
@@ -27,6 +28,25 @@ func (s *pgdbTestSuite) TestUserRepo_Create() {
 			},
 			expectErr: pgerrs.ErrAlreadyExists,
 		},
+		{
+			testName: "With email",
+			user: dbmodel.User{
+				Username: "petya",
+				Password: "abc",
+				Email:    "petya@example.com",
+			},
+			expectErr: nil,
+		},
+		{
+			// уникальны только подтвержденные email
+			testName: "Email used by another user but not verified",
+			user: dbmodel.User{
+				Username: "kolya",
+				Password: "abc",
+				Email:    "Petya@example.com",
+			},
+			expectErr: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -35,7 +55,7 @@ func (s *pgdbTestSuite) TestUserRepo_Create() {
 
 		if tc.expectErr == nil {
 			sql, args, _ := s.pg.Builder.
-				Select("username", "password").
+				Select("username", "password", "email").
 				From("\"user\"").
 				Where("username = ?", tc.user.Username).
 				ToSql()
@@ -45,6 +65,7 @@ func (s *pgdbTestSuite) TestUserRepo_Create() {
 			err = s.pg.Pool.QueryRow(s.ctx, sql, args...).Scan(
 				&actualUser.Username,
 				&actualUser.Password,
+				&actualUser.Email,
 			)
 			s.Assert().Nil(err)
 			s.Assert().Equal(tc.user, actualUser)
@@ -90,3 +111,313 @@ func (s *pgdbTestSuite) TestUserRepo_FindByUsername() {
 		}
 	}
 }
+
+func (s *pgdbTestSuite) TestUserRepo_Update() {
+	username := s.setupTestsData()
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: "petya", Password: "abc"}); err != nil {
+		panic(err)
+	}
+
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal("UTC", u.Timezone)
+	s.Assert().Equal(map[string]any{}, u.Preferences)
+
+	testCases := []struct {
+		testName  string
+		user      dbmodel.User
+		expectErr error
+	}{
+		{
+			testName: "Correct test",
+			user: dbmodel.User{
+				Username:    username,
+				DisplayName: "Vasya",
+				Email:       "vasya@example.com",
+				Timezone:    "Europe/Moscow",
+				Preferences: map[string]any{"theme": "dark"},
+			},
+			expectErr: nil,
+		},
+		{
+			testName: "Email of another user",
+			user: dbmodel.User{
+				Username:    "petya",
+				Email:       "VASYA@example.com",
+				Timezone:    "UTC",
+				Preferences: map[string]any{},
+			},
+			expectErr: pgerrs.ErrAlreadyExists,
+		},
+		{
+			testName: "User not exist",
+			user: dbmodel.User{
+				Username:    "kolya",
+				Timezone:    "UTC",
+				Preferences: map[string]any{},
+			},
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		err = s.user.Update(s.ctx, tc.user)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			u, err = s.user.FindByUsername(s.ctx, tc.user.Username)
+			s.Assert().Nil(err)
+			s.Assert().Equal(tc.user.DisplayName, u.DisplayName)
+			s.Assert().Equal(tc.user.Email, u.Email)
+			s.Assert().Equal(tc.user.Timezone, u.Timezone)
+			s.Assert().Equal(tc.user.Preferences, u.Preferences)
+		}
+	}
+}
+
+func (s *pgdbTestSuite) TestUserRepo_UpdateUsername() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "i")
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: "petya", Password: "abc"}); err != nil {
+		panic(err)
+	}
+	validAfter := time.Now().Truncate(time.Second)
+	attempt := dbmodel.SignInAttempt{Username: username, IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword, Success: true}
+	s.Assert().Nil(s.signInAttempt.Create(s.ctx, attempt, validAfter.Add(-time.Hour)))
+
+	s.Assert().Equal(pgerrs.ErrAlreadyExists, s.user.UpdateUsername(s.ctx, username, "petya", validAfter))
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.UpdateUsername(s.ctx, "kolya", "misha", validAfter))
+	s.Assert().Nil(s.user.UpdateUsername(s.ctx, username, "vasiliy", validAfter))
+
+	u, err := s.user.FindByUsername(s.ctx, "vasiliy")
+	s.Assert().Nil(err)
+	s.Assert().True(validAfter.Equal(u.SessionsValidAfter))
+
+	// задачи переехали к новому имени
+	_, err = s.task.FindById(s.ctx, ids[0], "vasiliy")
+	s.Assert().Nil(err)
+	_, err = s.task.FindById(s.ctx, ids[0], username)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	// история входов тоже
+	attempts, err := s.signInAttempt.FindByUsername(s.ctx, "vasiliy", time.Time{}, 10)
+	s.Assert().Nil(err)
+	s.Assert().Len(attempts, 1)
+	attempts, err = s.signInAttempt.FindByUsername(s.ctx, username, time.Time{}, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(attempts)
+}
+
+func (s *pgdbTestSuite) TestUserRepo_DeleteScheduled() {
+	username := s.setupTestsData()
+	ids := s.setupTestsTasks(username, "i")
+	s.setupTestsBoard(username)
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: "petya", Password: "abc"}); err != nil {
+		panic(err)
+	}
+	now := time.Now()
+	deleteAt := now.Add(time.Hour)
+	later := now.Add(time.Minute)
+	for _, name := range []string{username, "petya"} {
+		attempt := dbmodel.SignInAttempt{Username: name, IP: "10.0.0.1", Method: dbmodel.SignInMethodPassword, Success: true}
+		s.Assert().Nil(s.signInAttempt.Create(s.ctx, attempt, now.Add(-time.Hour)))
+	}
+
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.ScheduleDeletion(s.ctx, "kolya", &deleteAt))
+	s.Assert().Nil(s.user.ScheduleDeletion(s.ctx, username, &deleteAt))
+	s.Assert().Nil(s.user.ScheduleDeletion(s.ctx, "petya", &later))
+	// отмена удаления
+	s.Assert().Nil(s.user.ScheduleDeletion(s.ctx, "petya", nil))
+
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().NotNil(u.DeleteAt)
+
+	// время удаления еще не наступило
+	deleted, err := s.user.DeleteScheduled(s.ctx, now)
+	s.Assert().Nil(err)
+	s.Assert().Empty(deleted)
+
+	deleted, err = s.user.DeleteScheduled(s.ctx, now.Add(2*time.Hour))
+	s.Assert().Nil(err)
+	s.Assert().Equal([]string{username}, deleted)
+
+	_, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+	_, err = s.task.FindById(s.ctx, ids[0], username)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+	boards, err := s.board.Find(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Empty(boards)
+	// история входов удаляется вместе с пользователем, а не только скрывается от следующего владельца имени
+	attempts, err := s.signInAttempt.FindByUsername(s.ctx, username, time.Time{}, 10)
+	s.Assert().Nil(err)
+	s.Assert().Empty(attempts)
+
+	_, err = s.user.FindByUsername(s.ctx, "petya")
+	s.Assert().Nil(err)
+	attempts, err = s.signInAttempt.FindByUsername(s.ctx, "petya", time.Time{}, 10)
+	s.Assert().Nil(err)
+	s.Assert().Len(attempts, 1)
+}
+
+func (s *pgdbTestSuite) TestUserRepo_VerifyEmail() {
+	username := "vasya"
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: username, Password: "abc", Email: "vasya@example.com"}); err != nil {
+		panic(err)
+	}
+
+	// тот же адрес, пока он не подтвержден, может указать и другой пользователь
+	if err := s.user.Create(s.ctx, dbmodel.User{Username: "petya", Password: "abc", Email: "Vasya@example.com"}); err != nil {
+		panic(err)
+	}
+
+	// неподтвержденный email никому не принадлежит
+	_, err := s.user.FindByEmail(s.ctx, "VASYA@example.com")
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+	_, err = s.user.FindByEmail(s.ctx, "")
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.VerifyEmail(s.ctx, username, "other@example.com"))
+	s.Assert().Nil(s.user.VerifyEmail(s.ctx, username, "vasya@example.com"))
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().True(u.EmailVerified)
+
+	u, err = s.user.FindByEmail(s.ctx, "VASYA@example.com")
+	s.Assert().Nil(err)
+	s.Assert().Equal(username, u.Username)
+	s.Assert().Equal(pgerrs.ErrAlreadyExists, s.user.VerifyEmail(s.ctx, "petya", "vasya@example.com"))
+
+	// обновление профиля без смены email подтверждение сохраняет
+	u.DisplayName = "Vasya"
+	s.Assert().Nil(s.user.Update(s.ctx, u))
+	u, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().True(u.EmailVerified)
+
+	u.Email = "vasiliy@example.com"
+	s.Assert().Nil(s.user.Update(s.ctx, u))
+	u, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().False(u.EmailVerified)
+}
+
+func (s *pgdbTestSuite) TestUserRepo_TOTP() {
+	username := s.setupTestsData()
+
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.SetTOTP(s.ctx, "petya", "SECRET", false))
+	s.Assert().Nil(s.user.SetTOTP(s.ctx, username, "SECRET", true))
+
+	s.Assert().Nil(s.user.UseTOTPStep(s.ctx, username, 100))
+	// повторно и более ранний шаг не принимаются
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.UseTOTPStep(s.ctx, username, 100))
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.UseTOTPStep(s.ctx, username, 99))
+	s.Assert().Nil(s.user.UseTOTPStep(s.ctx, username, 101))
+
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal("SECRET", u.TOTPSecret)
+	s.Assert().True(u.TOTPEnabled)
+	s.Assert().Equal(int64(101), u.TOTPLastStep)
+
+	s.Assert().Nil(s.user.SetTOTP(s.ctx, username, "", false))
+	u, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal("", u.TOTPSecret)
+	s.Assert().False(u.TOTPEnabled)
+	s.Assert().Zero(u.TOTPLastStep)
+}
+
+func (s *pgdbTestSuite) TestUserRepo_Search() {
+	for _, u := range []dbmodel.User{
+		{Username: "vasya", Password: "abc", Email: "vasya@example.com", DisplayName: "Vasiliy"},
+		{Username: "petya", Password: "abc", Email: "petr@corp.example.com"},
+		{Username: "kolya_1", Password: "abc"},
+	} {
+		if err := s.user.Create(s.ctx, u); err != nil {
+			panic(err)
+		}
+	}
+
+	testCases := []struct {
+		testName      string
+		query         string
+		limit         int
+		offset        int
+		expectedUsers []string
+	}{
+		{
+			testName:      "All users",
+			limit:         10,
+			expectedUsers: []string{"kolya_1", "petya", "vasya"},
+		},
+		{
+			testName:      "Page",
+			limit:         1,
+			offset:        1,
+			expectedUsers: []string{"petya"},
+		},
+		{
+			testName:      "By email",
+			query:         "CORP",
+			limit:         10,
+			expectedUsers: []string{"petya"},
+		},
+		{
+			testName:      "By display name",
+			query:         "vasil",
+			limit:         10,
+			expectedUsers: []string{"vasya"},
+		},
+		{
+			testName:      "Like wildcard is escaped",
+			query:         "_",
+			limit:         10,
+			expectedUsers: []string{"kolya_1"},
+		},
+		{
+			testName: "Nothing found",
+			query:    "masha",
+			limit:    10,
+		},
+	}
+
+	for _, tc := range testCases {
+		users, err := s.user.Search(s.ctx, tc.query, tc.limit, tc.offset)
+		s.Assert().Nil(err, tc.testName)
+
+		var actual []string
+		for _, u := range users {
+			actual = append(actual, u.Username)
+		}
+		s.Assert().Equal(tc.expectedUsers, actual, tc.testName)
+	}
+}
+
+func (s *pgdbTestSuite) TestUserRepo_RolesAndDisabled() {
+	username := s.setupTestsData()
+
+	u, err := s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal([]string{dbmodel.RoleUser}, u.Roles)
+	s.Assert().Nil(u.DisabledAt)
+
+	validAfter := time.Now().Truncate(time.Second)
+	s.Assert().Equal(pgerrs.ErrNotFound, s.user.SetRoles(s.ctx, "petya", []string{dbmodel.RoleAdmin}, validAfter))
+	s.Assert().Nil(s.user.SetRoles(s.ctx, username, []string{dbmodel.RoleUser, dbmodel.RoleAdmin}, validAfter))
+
+	disabledAt := time.Date(2024, 8, 29, 12, 0, 0, 0, time.UTC)
+	s.Assert().Nil(s.user.SetDisabled(s.ctx, username, &disabledAt, validAfter))
+
+	u, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Equal([]string{dbmodel.RoleUser, dbmodel.RoleAdmin}, u.Roles)
+	s.Assert().True(disabledAt.Equal(*u.DisabledAt))
+	s.Assert().True(validAfter.Equal(u.SessionsValidAfter))
+
+	s.Assert().Nil(s.user.SetDisabled(s.ctx, username, nil, validAfter))
+	u, err = s.user.FindByUsername(s.ctx, username)
+	s.Assert().Nil(err)
+	s.Assert().Nil(u.DisabledAt)
+}
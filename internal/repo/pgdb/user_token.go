@@ -0,0 +1,88 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/postgres"
+)
+
+var userTokenColumns = []string{"id", "username", "purpose", "token_hash", "email", "expires_at", "used_at", "created_at"}
+
+var userTokenReturning = "returning " + strings.Join(userTokenColumns, ", ")
+
+type UserTokenRepo struct {
+	*postgres.Postgres
+}
+
+func NewUserTokenRepo(pg *postgres.Postgres) *UserTokenRepo {
+	return &UserTokenRepo{pg}
+}
+
+// Create сохраняет токен с полями Username, Purpose, TokenHash, Email, ExpiresAt.
+// Возвращает pgerrs.ErrForeignKey, если пользователя нет
+func (r *UserTokenRepo) Create(ctx context.Context, t *dbmodel.UserToken) error {
+	sql, args, _ := r.Builder.
+		Insert("user_token").
+		Columns("username", "purpose", "token_hash", "email", "expires_at").
+		Values(t.Username, t.Purpose, t.TokenHash, t.Email, t.ExpiresAt).
+		Suffix("returning id, created_at").
+		ToSql()
+
+	if err := r.Pool.QueryRow(ctx, sql, args...).Scan(&t.Id, &t.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == "23503" {
+				return pgerrs.ErrForeignKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Consume помечает токен использованным и возвращает его. Возвращает pgerrs.ErrNotFound, если токена с таким назначением нет,
+// он уже использован или истек к now. Условие проверяется в том же update, поэтому токен нельзя использовать дважды
+func (r *UserTokenRepo) Consume(ctx context.Context, purpose, tokenHash string, now time.Time) (dbmodel.UserToken, error) {
+	sql, args, _ := r.Builder.
+		Update("user_token").
+		Set("used_at", now).
+		Where("token_hash = ?", tokenHash).
+		Where("purpose = ?", purpose).
+		Where("used_at is null").
+		Where("expires_at > ?", now).
+		Suffix(userTokenReturning).
+		ToSql()
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return dbmodel.UserToken{}, err
+	}
+	token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dbmodel.UserToken])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dbmodel.UserToken{}, pgerrs.ErrNotFound
+		}
+		return dbmodel.UserToken{}, err
+	}
+	return token, nil
+}
+
+// Revoke помечает использованными все неиспользованные токены пользователя с назначением purpose
+func (r *UserTokenRepo) Revoke(ctx context.Context, username, purpose string, now time.Time) error {
+	sql, args, _ := r.Builder.
+		Update("user_token").
+		Set("used_at", now).
+		Where("username = ?", username).
+		Where("purpose = ?", purpose).
+		Where("used_at is null").
+		ToSql()
+
+	_, err := r.Pool.Exec(ctx, sql, args...)
+	return err
+}
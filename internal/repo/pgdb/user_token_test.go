@@ -0,0 +1,80 @@
+package pgdb
+
+import (
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+)
+
+func (s *pgdbTestSuite) TestUserTokenRepo_Consume() {
+	username := s.setupTestsData()
+	now := time.Now()
+
+	tokens := []*dbmodel.UserToken{
+		{Username: username, Purpose: dbmodel.UserTokenResetPassword, TokenHash: "reset", ExpiresAt: now.Add(time.Hour)},
+		{Username: username, Purpose: dbmodel.UserTokenResetPassword, TokenHash: "reset2", ExpiresAt: now.Add(time.Hour)},
+		{Username: username, Purpose: dbmodel.UserTokenVerifyEmail, TokenHash: "verify", Email: "vasya@example.com", ExpiresAt: now.Add(time.Hour)},
+		{Username: username, Purpose: dbmodel.UserTokenVerifyEmail, TokenHash: "expired", ExpiresAt: now.Add(-time.Minute)},
+	}
+	for _, t := range tokens {
+		if err := s.userToken.Create(s.ctx, t); err != nil {
+			panic(err)
+		}
+	}
+	s.Assert().Equal(pgerrs.ErrForeignKey, s.userToken.Create(s.ctx, &dbmodel.UserToken{
+		Username: "petya", Purpose: dbmodel.UserTokenResetPassword, TokenHash: "petya", ExpiresAt: now,
+	}))
+
+	testCases := []struct {
+		testName  string
+		purpose   string
+		hash      string
+		expectErr error
+	}{
+		{
+			testName:  "Correct test",
+			purpose:   dbmodel.UserTokenVerifyEmail,
+			hash:      "verify",
+			expectErr: nil,
+		},
+		{
+			testName:  "Already used",
+			purpose:   dbmodel.UserTokenVerifyEmail,
+			hash:      "verify",
+			expectErr: pgerrs.ErrNotFound,
+		},
+		{
+			testName:  "Wrong purpose",
+			purpose:   dbmodel.UserTokenVerifyEmail,
+			hash:      "reset",
+			expectErr: pgerrs.ErrNotFound,
+		},
+		{
+			testName:  "Expired",
+			purpose:   dbmodel.UserTokenVerifyEmail,
+			hash:      "expired",
+			expectErr: pgerrs.ErrNotFound,
+		},
+		{
+			testName:  "Not exist",
+			purpose:   dbmodel.UserTokenResetPassword,
+			hash:      "foobar",
+			expectErr: pgerrs.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		token, err := s.userToken.Consume(s.ctx, tc.purpose, tc.hash, now)
+		s.Assert().Equal(tc.expectErr, err, tc.testName)
+
+		if tc.expectErr == nil {
+			s.Assert().Equal(username, token.Username, tc.testName)
+			s.Assert().Equal("vasya@example.com", token.Email, tc.testName)
+			s.Assert().NotNil(token.UsedAt, tc.testName)
+		}
+	}
+
+	s.Assert().Nil(s.userToken.Revoke(s.ctx, username, dbmodel.UserTokenResetPassword, now))
+	_, err := s.userToken.Consume(s.ctx, dbmodel.UserTokenResetPassword, "reset2", now)
+	s.Assert().Equal(pgerrs.ErrNotFound, err)
+}
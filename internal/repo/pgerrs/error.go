@@ -6,4 +6,6 @@ var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
 	ErrForeignKey    = errors.New("incorrect foreign key")
+	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrCycle         = errors.New("cycle detected")
 )
@@ -2,32 +2,187 @@ package repo
 
 import (
 	"context"
+	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo/pgdb"
 	"todolist_api/pkg/postgres"
+	"todolist_api/pkg/ratelimit"
 )
 
 type User interface {
 	Create(ctx context.Context, u dbmodel.User) error
 	FindByUsername(ctx context.Context, username string) (dbmodel.User, error)
+	FindByEmail(ctx context.Context, email string) (dbmodel.User, error)
+	Update(ctx context.Context, u dbmodel.User) error
+	VerifyEmail(ctx context.Context, username, email string) error
+	UpdatePassword(ctx context.Context, username, password string, validAfter time.Time) error
+	UpdateUsername(ctx context.Context, username, newUsername string, validAfter time.Time) error
+	ScheduleDeletion(ctx context.Context, username string, deleteAt *time.Time) error
+	DeleteScheduled(ctx context.Context, now time.Time) ([]string, error)
+	SetTOTP(ctx context.Context, username, secret string, enabled bool) error
+	UseTOTPStep(ctx context.Context, username string, step int64) error
+	Search(ctx context.Context, query string, limit, offset int) ([]dbmodel.User, error)
+	SetRoles(ctx context.Context, username string, roles []string, validAfter time.Time) error
+	SetDisabled(ctx context.Context, username string, disabledAt *time.Time, validAfter time.Time) error
+}
+
+type RecoveryCode interface {
+	Replace(ctx context.Context, username string, codeHashes []string) error
+	FindUnused(ctx context.Context, username string) ([]dbmodel.RecoveryCode, error)
+	Use(ctx context.Context, id int, usedAt time.Time) error
+}
+
+type UserToken interface {
+	Create(ctx context.Context, t *dbmodel.UserToken) error
+	Consume(ctx context.Context, purpose, tokenHash string, now time.Time) (dbmodel.UserToken, error)
+	Revoke(ctx context.Context, username, purpose string, now time.Time) error
+}
+
+type PersonalToken interface {
+	Create(ctx context.Context, t *dbmodel.PersonalToken) error
+	FindByUser(ctx context.Context, username string) ([]dbmodel.PersonalToken, error)
+	Delete(ctx context.Context, id int, username string) error
+	Use(ctx context.Context, tokenHash string, now time.Time) (dbmodel.PersonalToken, error)
+}
+
+type OIDC interface {
+	CreateLogin(ctx context.Context, l dbmodel.OIDCLogin, now time.Time) error
+	ConsumeLogin(ctx context.Context, provider, state string, now time.Time) (dbmodel.OIDCLogin, error)
+	CreateIdentity(ctx context.Context, i dbmodel.UserIdentity) error
+	FindIdentity(ctx context.Context, provider, subject string) (dbmodel.UserIdentity, error)
+	FindIdentitiesByUser(ctx context.Context, username string) ([]dbmodel.UserIdentity, error)
+}
+
+type SignInAttempt interface {
+	Create(ctx context.Context, a dbmodel.SignInAttempt, deleteBefore time.Time) error
+	Reserve(ctx context.Context, a *dbmodel.SignInAttempt, since, deleteBefore time.Time) (dbmodel.SignInFailures, dbmodel.SignInFailures, error)
+	SetSuccess(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int) error
+	FailuresByUsername(ctx context.Context, username string, since time.Time) (dbmodel.SignInFailures, error)
+	FailuresByIP(ctx context.Context, ip string, since time.Time) (dbmodel.SignInFailures, error)
+	FindByUsername(ctx context.Context, username string, since time.Time, limit int) ([]dbmodel.SignInAttempt, error)
+}
+
+// RateLimit хранит корзины ограничения частоты запросов в базе, чтобы ограничения были общими для всех реплик
+type RateLimit interface {
+	ratelimit.Store
+	DeleteIdle(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Migration interface {
+	Version(ctx context.Context) (version uint, dirty bool, err error)
 }
 
 type Task interface {
 	Create(ctx context.Context, t *dbmodel.Task) error
-	Find(ctx context.Context, username string) ([]dbmodel.Task, error)
+	Find(ctx context.Context, username, sort string) ([]dbmodel.Task, error)
 	FindById(ctx context.Context, id int, username string) (dbmodel.Task, error)
+	FindByIds(ctx context.Context, ids []int, username string) ([]dbmodel.Task, error)
 	Update(ctx context.Context, t *dbmodel.Task) error
 	Delete(ctx context.Context, id int, username string) error
+	FindLastPosition(ctx context.Context, username string) (string, error)
+	FindPrevPosition(ctx context.Context, username, position string, excludeId int) (string, error)
+	FindNextPosition(ctx context.Context, username, position string, excludeId int) (string, error)
+	UpdatePosition(ctx context.Context, id int, username, position string) error
+	Rebalance(ctx context.Context, username string) error
+	FindByBoard(ctx context.Context, boardId int) ([]dbmodel.Task, error)
+	MoveToColumn(ctx context.Context, t *dbmodel.Task, boardId, columnId int) error
+	FindDue(ctx context.Context, username string, before time.Time) ([]dbmodel.Task, error)
+	CountByStatus(ctx context.Context, usernames []string) ([]dbmodel.TaskCount, error)
+}
+
+type Board interface {
+	Create(ctx context.Context, b *dbmodel.Board) error
+	Find(ctx context.Context, username string) ([]dbmodel.Board, error)
+	FindById(ctx context.Context, id int, username string) (dbmodel.Board, error)
+	Update(ctx context.Context, b *dbmodel.Board) error
+	Delete(ctx context.Context, id int, username string) error
+	CreateColumn(ctx context.Context, c *dbmodel.BoardColumn, username string) error
+	FindColumns(ctx context.Context, boardId int) ([]dbmodel.BoardColumn, error)
+	UpdateColumn(ctx context.Context, c *dbmodel.BoardColumn, username string) error
+	DeleteColumn(ctx context.Context, boardId, columnId int, username string) error
+	ReorderColumns(ctx context.Context, boardId int, columnIds []int) error
+}
+
+type Dependency interface {
+	Create(ctx context.Context, taskId, blockedById int, username string) error
+	Delete(ctx context.Context, taskId, blockedById int, username string) error
+	FindByTasks(ctx context.Context, taskIds []int) ([]dbmodel.TaskDependency, error)
+	FindBlockers(ctx context.Context, taskId int) ([]dbmodel.TaskDependency, error)
+}
+
+type TimeEntry interface {
+	Create(ctx context.Context, e *dbmodel.TimeEntry) error
+	Stop(ctx context.Context, taskId int, username string, endedAt time.Time) (dbmodel.TimeEntry, error)
+	FindRunning(ctx context.Context, username string) (dbmodel.TimeEntry, error)
+	FindByTask(ctx context.Context, taskId int, username string) ([]dbmodel.TimeEntry, error)
+	Delete(ctx context.Context, id int, username string) error
+	SumByTasks(ctx context.Context, taskIds []int) (map[int]time.Duration, error)
+	Report(ctx context.Context, username string, from, to time.Time, groupBy, timezone string) ([]dbmodel.TimeReportRow, error)
+	FindByUser(ctx context.Context, username string) ([]dbmodel.TimeEntry, error)
+}
+
+type Reminder interface {
+	Create(ctx context.Context, reminder *dbmodel.Reminder) error
+	FindByTask(ctx context.Context, taskId int, username string) ([]dbmodel.Reminder, error)
+	FindByUser(ctx context.Context, username string) ([]dbmodel.Reminder, error)
+	Delete(ctx context.Context, id int, username string) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit, maxAttempts int) ([]dbmodel.DueReminder, error)
+	MarkSent(ctx context.Context, id int, sentAt time.Time) error
+	MarkFailed(ctx context.Context, id int, lastError string, retryAt time.Time) error
+}
+
+type Digest interface {
+	FindSetting(ctx context.Context, username string) (dbmodel.DigestSetting, error)
+	SaveSetting(ctx context.Context, s *dbmodel.DigestSetting) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dbmodel.DigestSetting, error)
+	Reschedule(ctx context.Context, username string, nextSendAt time.Time, sentAt *time.Time) error
+}
+
+type DataExport interface {
+	Create(ctx context.Context, e *dbmodel.DataExport) error
+	FindById(ctx context.Context, id int, username string) (dbmodel.DataExport, error)
+	FindArchive(ctx context.Context, id int) (dbmodel.DataExport, error)
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dbmodel.DataExport, error)
+	MarkReady(ctx context.Context, id int, archive []byte, expiresAt time.Time) error
+	MarkFailed(ctx context.Context, id int, lastError string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
 }
 
 type Repositories struct {
 	User
+	UserToken
+	RecoveryCode
+	PersonalToken
+	OIDC
+	SignInAttempt
+	RateLimit
+	Migration
 	Task
+	Board
+	Dependency
+	TimeEntry
+	Reminder
+	Digest
+	DataExport
 }
 
 func NewRepositories(pg *postgres.Postgres) *Repositories {
 	return &Repositories{
-		User: pgdb.NewUserRepo(pg),
-		Task: pgdb.NewTaskRepo(pg),
+		User:          pgdb.NewUserRepo(pg),
+		UserToken:     pgdb.NewUserTokenRepo(pg),
+		RecoveryCode:  pgdb.NewRecoveryCodeRepo(pg),
+		PersonalToken: pgdb.NewPersonalTokenRepo(pg),
+		OIDC:          pgdb.NewOIDCRepo(pg),
+		SignInAttempt: pgdb.NewSignInAttemptRepo(pg),
+		RateLimit:     pgdb.NewRateLimitRepo(pg),
+		Migration:     pgdb.NewMigrationRepo(pg),
+		Task:          pgdb.NewTaskRepo(pg),
+		Board:         pgdb.NewBoardRepo(pg),
+		Dependency:    pgdb.NewDependencyRepo(pg),
+		TimeEntry:     pgdb.NewTimeEntryRepo(pg),
+		Reminder:      pgdb.NewReminderRepo(pg),
+		Digest:        pgdb.NewDigestRepo(pg),
+		DataExport:    pgdb.NewDataExportRepo(pg),
 	}
 }
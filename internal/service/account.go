@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/mailer"
+	"todolist_api/pkg/worker"
+)
+
+const (
+	userTokenBytes = 32
+)
+
+// VerifyEmail подтверждает email по токену из письма. Токен действует, только если email с тех пор не менялся.
+// Подтвердить email, который уже подтвердил другой пользователь, нельзя
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracer.Start(ctx, "userService.VerifyEmail")
+	defer span.End()
+
+	t, err := s.token.Consume(ctx, dbmodel.UserTokenVerifyEmail, hashUserToken(token), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserTokenInvalid
+		}
+		logger.From(ctx).Errorf("%s/VerifyEmail error consume token: %s", userServicePrefixLog, err)
+		return err
+	}
+
+	if err = s.user.VerifyEmail(ctx, t.Username, t.Email); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserTokenInvalid
+		}
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return ErrUserEmailTaken
+		}
+		logger.From(ctx).Errorf("%s/VerifyEmail error verify email: %s", userServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// ForgotPassword отправляет письмо со ссылкой на сброс пароля, если email принадлежит пользователю и подтвержден.
+// Поиск пользователя и отправка идут в фоне, чтобы ни ответ, ни время ответа не выдавали, есть ли такой аккаунт
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "userService.ForgotPassword")
+	defer span.End()
+
+	s.enqueueMail(ctx, "ForgotPassword", func(ctx context.Context) {
+		u, err := s.user.FindByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, pgerrs.ErrNotFound) {
+				logger.From(ctx).Errorf("%s/ForgotPassword error find user: %s", userServicePrefixLog, err)
+			}
+			return
+		}
+		if !u.EmailVerified {
+			return
+		}
+		if err = s.sendToken(ctx, u.Username, u.Email, dbmodel.UserTokenResetPassword); err != nil {
+			logger.From(ctx).Errorf("%s/ForgotPassword error send reset token: %s", userServicePrefixLog, err)
+		}
+	})
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма. Выданные ранее токены доступа, включая персональные,
+// и остальные ссылки на сброс перестают действовать
+func (s *userService) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, span := tracer.Start(ctx, "userService.ResetPassword")
+	defer span.End()
+
+	now := time.Now().UTC()
+	t, err := s.token.Consume(ctx, dbmodel.UserTokenResetPassword, hashUserToken(token), now)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserTokenInvalid
+		}
+		logger.From(ctx).Errorf("%s/ResetPassword error consume token: %s", userServicePrefixLog, err)
+		return err
+	}
+
+	if err = s.user.UpdatePassword(ctx, t.Username, s.hasher.Hash(password), sessionsValidAfter()); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserTokenInvalid
+		}
+		logger.From(ctx).Errorf("%s/ResetPassword error update password: %s", userServicePrefixLog, err)
+		return err
+	}
+	if err = s.token.Revoke(ctx, t.Username, dbmodel.UserTokenResetPassword, now); err != nil {
+		logger.From(ctx).Errorf("%s/ResetPassword error revoke reset tokens: %s", userServicePrefixLog, err)
+	}
+	return nil
+}
+
+// sendEmailMail в фоне отправляет на новый email пользователя письмо для подтверждения или, если email уже подтвердил
+// другой пользователь (taken), письмо его владельцу. Отправка в фоне, чтобы время ответа не выдавало, занят ли email
+func (s *userService) sendEmailMail(ctx context.Context, username, email string, taken bool) {
+	if email == "" {
+		return
+	}
+	s.enqueueMail(ctx, "sendEmailMail", func(ctx context.Context) {
+		if taken {
+			s.sendEmailTaken(ctx, email)
+			return
+		}
+		s.sendVerification(ctx, username, email)
+	})
+}
+
+// enqueueMail ставит отправку письма в очередь и не ждет ее. Если очередь заполнена, письмо не отправляется:
+// лучше потерять письмо, чем копить горутины или задерживать ответ
+func (s *userService) enqueueMail(ctx context.Context, method string, job worker.Job) {
+	if err := s.mailQueue.Add(ctx, job); err != nil {
+		logger.From(ctx).Errorf("%s/%s error enqueue mail: %s", userServicePrefixLog, method, err)
+	}
+}
+
+// sendVerification отправляет письмо для подтверждения email. Ошибка отправки только логируется:
+// из-за нее не должна падать регистрация или обновление профиля
+func (s *userService) sendVerification(ctx context.Context, username, email string) {
+	if err := s.sendToken(ctx, username, email, dbmodel.UserTokenVerifyEmail); err != nil {
+		logger.From(ctx).Errorf("%s/sendVerification error send verification token: %s", userServicePrefixLog, err)
+	}
+}
+
+// emailTaken подтвержден ли email другим пользователем, не username. Пустой email никому не принадлежит
+func (s *userService) emailTaken(ctx context.Context, username, email string) (bool, error) {
+	if email == "" {
+		return false, nil
+	}
+	u, err := s.user.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return u.Username != username, nil
+}
+
+// sendEmailTaken сообщает владельцу email, что кто-то пытался указать его адрес при регистрации или в профиле.
+// Ошибка отправки только логируется
+func (s *userService) sendEmailTaken(ctx context.Context, email string) {
+	if s.mailer == nil {
+		return
+	}
+	err := s.mailer.Send(ctx, mailer.Mail{
+		To:      email,
+		Subject: "Your email was used for another account",
+		Body: fmt.Sprintf("Someone tried to use this email for another account, but it is already used by your account.\n"+
+			"If it was you, sign in with your username or reset your password:\n\n%s/forgot-password\n\n"+
+			"If you did not request this, ignore this email.\n", s.mail.appURL),
+	})
+	if err != nil {
+		logger.From(ctx).Errorf("%s/sendEmailTaken error send email: %s", userServicePrefixLog, err)
+	}
+}
+
+// sendToken создает одноразовый токен с назначением purpose и отправляет его письмом на email.
+// Если почта не настроена, ничего не делает
+func (s *userService) sendToken(ctx context.Context, username, email, purpose string) error {
+	if s.mailer == nil {
+		return nil
+	}
+
+	b := make([]byte, userTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	ttl, path, subject, action := s.mail.verifyTTL, "/verify-email", "Confirm your email", "confirm your email address"
+	if purpose == dbmodel.UserTokenResetPassword {
+		ttl, path, subject, action = s.mail.resetTTL, "/reset-password", "Reset your password", "set a new password"
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+
+	err := s.token.Create(ctx, &dbmodel.UserToken{
+		Username:  username,
+		Purpose:   purpose,
+		TokenHash: hashUserToken(token),
+		Email:     email,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.mail.appURL + path + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Mail{
+		To:      email,
+		Subject: subject,
+		Body: fmt.Sprintf("Open the link to %s:\n\n%s\n\nThe link can be used once and is valid until %s.\n"+
+			"If you did not request this, ignore this email.\n", action, link, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+// hashUserToken в базе хранится только хеш токена, поэтому утечка таблицы не дает использовать токены
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	adminServicePrefixLog = "/service/admin"
+)
+
+type adminService struct {
+	user    repo.User
+	task    repo.Task
+	account *userService
+}
+
+// newAdminService account отправляет письма со ссылкой на сброс пароля
+func newAdminService(user repo.User, task repo.Task, account *userService) *adminService {
+	return &adminService{
+		user:    user,
+		task:    task,
+		account: account,
+	}
+}
+
+func (s *adminService) FindUsers(ctx context.Context, input AdminUserSearchInput) ([]AdminUserOutput, error) {
+	users, err := s.user.Search(ctx, input.Query, input.Limit, input.Offset)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindUsers error search users: %s", adminServicePrefixLog, err)
+		return nil, err
+	}
+	return s.newOutputs(ctx, users)
+}
+
+func (s *adminService) FindUser(ctx context.Context, username string) (AdminUserOutput, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return AdminUserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindUser error find user: %s", adminServicePrefixLog, err)
+		return AdminUserOutput{}, err
+	}
+	output, err := s.newOutputs(ctx, []dbmodel.User{u})
+	if err != nil {
+		return AdminUserOutput{}, err
+	}
+	return output[0], nil
+}
+
+// SetDisabled отключает или снова включает аккаунт. Токены отключенного пользователя отзываются,
+// войти он не может, пока аккаунт не включат. Отключить себя администратор не может
+func (s *adminService) SetDisabled(ctx context.Context, actor, username string, disabled bool) (AdminUserOutput, error) {
+	if disabled && actor == username {
+		return AdminUserOutput{}, ErrAdminSelf
+	}
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now().UTC()
+		disabledAt = &now
+	}
+
+	if err := s.user.SetDisabled(ctx, username, disabledAt, sessionsValidAfter()); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return AdminUserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/SetDisabled error update user: %s", adminServicePrefixLog, err)
+		return AdminUserOutput{}, err
+	}
+	logger.From(ctx).Infof("%s/SetDisabled user %s disabled=%t by %s", adminServicePrefixLog, username, disabled, actor)
+	return s.FindUser(ctx, username)
+}
+
+// SetRoles заменяет роли пользователя. Выданные ему токены отзываются, чтобы новые роли действовали сразу.
+// Снять роль администратора с себя нельзя, чтобы не остаться без администраторов по ошибке
+func (s *adminService) SetRoles(ctx context.Context, input AdminRolesInput) (AdminUserOutput, error) {
+	roles, err := normalizeRoles(input.Roles)
+	if err != nil {
+		return AdminUserOutput{}, err
+	}
+	if input.Actor == input.Username && !slices.Contains(roles, dbmodel.RoleAdmin) {
+		return AdminUserOutput{}, ErrAdminSelf
+	}
+
+	if err = s.user.SetRoles(ctx, input.Username, roles, sessionsValidAfter()); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return AdminUserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/SetRoles error update user: %s", adminServicePrefixLog, err)
+		return AdminUserOutput{}, err
+	}
+	logger.From(ctx).Infof("%s/SetRoles user %s roles=%v by %s", adminServicePrefixLog, input.Username, roles, input.Actor)
+	return s.FindUser(ctx, input.Username)
+}
+
+// ForcePasswordReset заменяет пароль пользователя случайным и отзывает его токены, в том числе персональные,
+// например если аккаунт скомпрометирован.
+// Если email подтвержден, пользователю отправляется ссылка на сброс пароля, иначе задать пароль он сможет
+// только после подтверждения email через /auth/forgot-password
+func (s *adminService) ForcePasswordReset(ctx context.Context, username string) (AdminPasswordResetOutput, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return AdminPasswordResetOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/ForcePasswordReset error find user: %s", adminServicePrefixLog, err)
+		return AdminPasswordResetOutput{}, err
+	}
+
+	b := make([]byte, userTokenBytes)
+	if _, err = rand.Read(b); err != nil {
+		logger.From(ctx).Errorf("%s/ForcePasswordReset error generate password: %s", adminServicePrefixLog, err)
+		return AdminPasswordResetOutput{}, err
+	}
+	password := s.account.hasher.Hash(base64.RawURLEncoding.EncodeToString(b))
+	if err = s.user.UpdatePassword(ctx, username, password, sessionsValidAfter()); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return AdminPasswordResetOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/ForcePasswordReset error update password: %s", adminServicePrefixLog, err)
+		return AdminPasswordResetOutput{}, err
+	}
+	logger.From(ctx).Infof("%s/ForcePasswordReset password of user %s reset", adminServicePrefixLog, username)
+
+	if !u.EmailVerified || s.account.mailer == nil {
+		return AdminPasswordResetOutput{}, nil
+	}
+	if err = s.account.sendToken(ctx, u.Username, u.Email, dbmodel.UserTokenResetPassword); err != nil {
+		logger.From(ctx).Errorf("%s/ForcePasswordReset error send reset token: %s", adminServicePrefixLog, err)
+		return AdminPasswordResetOutput{}, nil
+	}
+	return AdminPasswordResetOutput{EmailSent: true}, nil
+}
+
+// newOutputs дополняет пользователей количеством задач по статусам, задачи считаются одним запросом
+func (s *adminService) newOutputs(ctx context.Context, users []dbmodel.User) ([]AdminUserOutput, error) {
+	usernames := make([]string, 0, len(users))
+	for _, u := range users {
+		usernames = append(usernames, u.Username)
+	}
+	counts, err := s.task.CountByStatus(ctx, usernames)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/newOutputs error count tasks: %s", adminServicePrefixLog, err)
+		return nil, err
+	}
+	tasks := make(map[string]TaskCountsOutput, len(users))
+	for _, c := range counts {
+		t := tasks[c.Username]
+		switch c.Status {
+		case dbmodel.TaskStatusTodo:
+			t.Todo = c.Count
+		case dbmodel.TaskStatusInProgress:
+			t.InProgress = c.Count
+		case dbmodel.TaskStatusDone:
+			t.Done = c.Count
+		}
+		t.Total += c.Count
+		tasks[c.Username] = t
+	}
+
+	output := make([]AdminUserOutput, 0, len(users))
+	for _, u := range users {
+		o := AdminUserOutput{
+			UserOutput: newUserOutput(u),
+			Disabled:   u.DisabledAt != nil,
+			Tasks:      tasks[u.Username],
+		}
+		if u.DisabledAt != nil {
+			o.DisabledAt = u.DisabledAt.Format(time.RFC3339)
+		}
+		output = append(output, o)
+	}
+	return output, nil
+}
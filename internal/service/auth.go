@@ -1,58 +1,183 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"github.com/golang-jwt/jwt"
-	log "github.com/sirupsen/logrus"
+	"sync/atomic"
 	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/jwtkeys"
+	"todolist_api/pkg/logger"
 )
 
 const (
 	authServicePrefixLog = "/service/auth"
+
+	// mfaTokenTTL сколько действует токен между вводом пароля и вводом второго фактора
+	mfaTokenTTL     = 5 * time.Minute
+	tokenPurposeMFA = "mfa"
 )
 
 var defaultSignMethod = jwt.SigningMethodHS256
 
+// TokenClaims Purpose пустой у токена доступа. Токены с другим назначением (например, mfa) для доступа к API не принимаются.
+// Roles - роли пользователя на момент выдачи, смена ролей отзывает выданные токены
 type TokenClaims struct {
 	jwt.StandardClaims
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
+	Purpose  string   `json:"purpose,omitempty"`
+}
+
+// AuthKeys ключи подписи токенов. Signing - активный ключ RS256 или EdDSA, nil - токены подписываются HS256 общим
+// секретом. Retired - выведенные из оборота ключи, которыми токены только проверяются, пока не истекут.
+// AcceptLegacy разрешает принимать токены HS256, выданные до перехода на асимметричную подпись: только выпущенные до
+// запуска сервиса и не дольше, чем на TokenTTL, поэтому общий секрет перестает работать, как только такие токены истекут
+type AuthKeys struct {
+	Signing      *jwtkeys.Key
+	Retired      []jwtkeys.Key
+	AcceptLegacy bool
 }
 
 type authService struct {
+	user     repo.User
 	signKey  []byte
 	tokenTTL time.Duration
+	signing  *jwtkeys.Key
+	// legacyBefore токены HS256 принимаются, только если выданы раньше, - момент перехода на асимметричную подпись
+	legacyBefore time.Time
+	// verification заменяется целиком при перечитывании выведенных ключей, см. SetVerifyKeys
+	verification atomic.Pointer[authVerification]
+}
+
+// authVerification keys - активный и выведенные ключи в порядке публикации, verifyKeys - они же по kid
+type authVerification struct {
+	keys         []jwtkeys.Key
+	verifyKeys   map[string]jwtkeys.Key
+	acceptLegacy bool
 }
 
-func newAuthService(signKey string, tokenTTL time.Duration) *authService {
-	return &authService{
+func newAuthService(user repo.User, signKey string, tokenTTL time.Duration, keys AuthKeys) *authService {
+	s := &authService{
+		user:     user,
 		signKey:  []byte(signKey),
 		tokenTTL: tokenTTL,
+		signing:  keys.Signing,
+
+		legacyBefore: time.Now(),
+	}
+	s.SetVerifyKeys(keys.Retired, keys.AcceptLegacy)
+	return s
+}
+
+// SetVerifyKeys атомарно заменяет выведенные ключи и прием токенов HS256. Активный ключ подписи не меняется
+func (s *authService) SetVerifyKeys(retired []jwtkeys.Key, acceptLegacy bool) {
+	v := &authVerification{
+		verifyKeys:   make(map[string]jwtkeys.Key, len(retired)+1),
+		acceptLegacy: s.signing == nil || acceptLegacy,
+	}
+	if s.signing != nil {
+		v.keys = append(v.keys, *s.signing)
+	}
+	v.keys = append(v.keys, retired...)
+	for _, k := range v.keys {
+		v.verifyKeys[k.ID] = k
+	}
+	s.verification.Store(v)
+}
+
+// JWKS открытые ключи, которыми проверяются токены: активный и выведенные из оборота
+func (s *authService) JWKS() jwtkeys.Set {
+	return jwtkeys.NewSet(s.verification.Load().keys...)
+}
+
+// CreateToken выдает токен доступа с текущими ролями пользователя. Отключенному пользователю токен не выдается
+func (s *authService) CreateToken(ctx context.Context, username string) (string, error) {
+	u, err := s.findActiveUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	return s.createToken(ctx, u.Username, u.Roles, "", s.tokenTTL)
+}
+
+// CreateMFAToken выдает короткоживущий токен после проверки пароля, который вместе с кодом второго фактора обменивается на токен доступа
+func (s *authService) CreateMFAToken(ctx context.Context, username string) (string, error) {
+	u, err := s.findActiveUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	return s.createToken(ctx, u.Username, nil, tokenPurposeMFA, mfaTokenTTL)
+}
+
+func (s *authService) ParseToken(tokenString string) (*TokenClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (s *authService) ParseMFAToken(tokenString string) (*TokenClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != tokenPurposeMFA {
+		return nil, ErrInvalidToken
 	}
+	return claims, nil
 }
 
-func (s *authService) CreateToken(username string) (string, error) {
-	token := jwt.NewWithClaims(defaultSignMethod, &TokenClaims{
+func (s *authService) findActiveUser(ctx context.Context, username string) (dbmodel.User, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return dbmodel.User{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/findActiveUser error find user: %s", authServicePrefixLog, err)
+		return dbmodel.User{}, err
+	}
+	if u.DisabledAt != nil {
+		return dbmodel.User{}, ErrUserDisabled
+	}
+	return u, nil
+}
+
+func (s *authService) createToken(ctx context.Context, username string, roles []string, purpose string, ttl time.Duration) (string, error) {
+	method, key := jwt.SigningMethod(defaultSignMethod), any(s.signKey)
+	if s.signing != nil {
+		method, key = s.signing.Method, s.signing.Private
+	}
+
+	token := jwt.NewWithClaims(method, &TokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(s.tokenTTL).Unix(),
+			ExpiresAt: time.Now().Add(ttl).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 		Username: username,
+		Roles:    roles,
+		Purpose:  purpose,
 	})
+	if s.signing != nil {
+		token.Header["kid"] = s.signing.ID
+	}
 
-	signedToken, err := token.SignedString(s.signKey)
+	signedToken, err := token.SignedString(key)
 	if err != nil {
-		log.Errorf("%s/CreateToken error sign token: %s", authServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/createToken error sign token: %s", authServicePrefixLog, err)
 		return "", err
 	}
 	return signedToken, nil
 }
 
-func (s *authService) ParseToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrIncorrectSignMethod
-		}
-		return s.signKey, nil
-	})
+func (s *authService) parseToken(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, s.verifyKey)
 	if err != nil {
 		return nil, err
 	}
@@ -66,3 +191,39 @@ func (s *authService) ParseToken(tokenString string) (*TokenClaims, error) {
 	}
 	return claims, nil
 }
+
+// legacyIssued токен HS256 мог быть выдан до перехода на асимметричную подпись: iat раньше запуска сервиса,
+// а срок действия не длиннее TokenTTL. Иначе общим секретом можно было бы выпускать токены после перехода
+func (s *authService) legacyIssued(t *jwt.Token) bool {
+	claims, ok := t.Claims.(*TokenClaims)
+	if !ok || claims.IssuedAt == 0 {
+		return false
+	}
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	return issuedAt.Before(s.legacyBefore) && claims.ExpiresAt-claims.IssuedAt <= int64(s.tokenTTL/time.Second)
+}
+
+// verifyKey выбирает ключ проверки подписи: HS256 - общий секрет, если такие токены еще принимаются,
+// иначе ключ по kid из заголовка. Алгоритм токена должен совпадать с алгоритмом ключа
+func (s *authService) verifyKey(t *jwt.Token) (interface{}, error) {
+	v := s.verification.Load()
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if !v.acceptLegacy {
+			return nil, ErrIncorrectSignMethod
+		}
+		if s.signing != nil && !s.legacyIssued(t) {
+			return nil, ErrInvalidToken
+		}
+		return s.signKey, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := v.verifyKeys[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if key.Method.Alg() != t.Method.Alg() {
+		return nil, ErrIncorrectSignMethod
+	}
+	return key.Public, nil
+}
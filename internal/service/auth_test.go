@@ -0,0 +1,87 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"todolist_api/pkg/jwtkeys"
+)
+
+const testSignKey = "secret"
+
+func legacyToken(t *testing.T, issuedAt time.Time, ttl time.Duration) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: issuedAt.Add(ttl).Unix(),
+		},
+		Username: "vasya",
+	}).SignedString([]byte(testSignKey))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthService_ParseToken_Legacy(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := jwtkeys.NewKey(private)
+	require.NoError(t, err)
+
+	ttl := time.Hour
+	s := newAuthService(nil, testSignKey, ttl, AuthKeys{Signing: &key, AcceptLegacy: true})
+	// сервис переключился на ключ подписи полчаса назад
+	s.legacyBefore = time.Now().Add(-30 * time.Minute)
+	before := s.legacyBefore.Add(-time.Minute)
+
+	testCases := []struct {
+		testName  string
+		token     string
+		expectErr bool
+	}{
+		{
+			testName: "Issued before switching to the signing key",
+			token:    legacyToken(t, before, ttl),
+		},
+		{
+			testName:  "Issued after switching to the signing key",
+			token:     legacyToken(t, s.legacyBefore.Add(time.Minute), ttl),
+			expectErr: true,
+		},
+		{
+			testName:  "Lifetime longer than token TTL",
+			token:     legacyToken(t, before, 24*time.Hour),
+			expectErr: true,
+		},
+		{
+			testName:  "Expired",
+			token:     legacyToken(t, before.Add(-ttl), ttl),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			claims, err := s.ParseToken(tc.token)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "vasya", claims.Username)
+		})
+	}
+
+	// без AcceptLegacy токены HS256 не принимаются совсем
+	s.SetVerifyKeys(nil, false)
+	_, err = s.ParseToken(legacyToken(t, before, ttl))
+	assert.Error(t, err)
+
+	// без ключа подписи HS256 - основной алгоритм, ограничений по iat нет
+	s = newAuthService(nil, testSignKey, ttl, AuthKeys{})
+	_, err = s.ParseToken(legacyToken(t, time.Now(), ttl))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	boardServicePrefixLog = "/service/board"
+)
+
+type boardService struct {
+	board   repo.Board
+	task    repo.Task
+	details *taskDetails
+	metrics *Metrics
+}
+
+func newBoardService(board repo.Board, task repo.Task, details *taskDetails, metrics *Metrics) *boardService {
+	return &boardService{
+		board:   board,
+		task:    task,
+		details: details,
+		metrics: metrics,
+	}
+}
+
+func (s *boardService) Create(ctx context.Context, input BoardInput) (BoardOutput, error) {
+	board := &dbmodel.Board{
+		Username: input.Username,
+		Name:     input.Name,
+	}
+	if err := s.board.Create(ctx, board); err != nil {
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return BoardOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Create error create board: %s", boardServicePrefixLog, err)
+		return BoardOutput{}, err
+	}
+	return newBoardOutput(*board), nil
+}
+
+func (s *boardService) Find(ctx context.Context, username string) ([]BoardOutput, error) {
+	boards, err := s.board.Find(ctx, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Find error find user boards: %s", boardServicePrefixLog, err)
+		return nil, err
+	}
+
+	result := make([]BoardOutput, 0)
+	for _, b := range boards {
+		result = append(result, newBoardOutput(b))
+	}
+	return result, nil
+}
+
+// FindById возвращает доску целиком: колонки по порядку и задачи, сгруппированные по колонкам
+func (s *boardService) FindById(ctx context.Context, id int, username string) (BoardViewOutput, error) {
+	board, err := s.board.FindById(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return BoardViewOutput{}, ErrBoardNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindById error find board by id: %s", boardServicePrefixLog, err)
+		return BoardViewOutput{}, err
+	}
+
+	columns, err := s.board.FindColumns(ctx, id)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindById error find board columns: %s", boardServicePrefixLog, err)
+		return BoardViewOutput{}, err
+	}
+	tasks, err := s.task.FindByBoard(ctx, id)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindById error find board tasks: %s", boardServicePrefixLog, err)
+		return BoardViewOutput{}, err
+	}
+
+	outputs, err := s.details.outputs(ctx, username, tasks...)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindById error find task details: %s", boardServicePrefixLog, err)
+		return BoardViewOutput{}, err
+	}
+
+	columnTasks := make(map[int][]TaskOutput, len(columns))
+	for i, t := range tasks {
+		columnTasks[*t.ColumnId] = append(columnTasks[*t.ColumnId], outputs[i])
+	}
+
+	result := BoardViewOutput{
+		BoardOutput: newBoardOutput(board),
+		Columns:     make([]BoardColumnTasksOutput, 0, len(columns)),
+	}
+	for _, c := range columns {
+		column := BoardColumnTasksOutput{
+			BoardColumnOutput: newBoardColumnOutput(c),
+			Tasks:             columnTasks[c.Id],
+		}
+		if column.Tasks == nil {
+			column.Tasks = make([]TaskOutput, 0)
+		}
+		result.Columns = append(result.Columns, column)
+	}
+	return result, nil
+}
+
+func (s *boardService) Update(ctx context.Context, input BoardInput) (BoardOutput, error) {
+	board := &dbmodel.Board{
+		Id:       input.Id,
+		Username: input.Username,
+		Name:     input.Name,
+	}
+	if err := s.board.Update(ctx, board); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return BoardOutput{}, ErrBoardNotFound
+		}
+		logger.From(ctx).Errorf("%s/Update error update board: %s", boardServicePrefixLog, err)
+		return BoardOutput{}, err
+	}
+	return newBoardOutput(*board), nil
+}
+
+func (s *boardService) Delete(ctx context.Context, id int, username string) error {
+	if err := s.board.Delete(ctx, id, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrBoardNotFound
+		}
+		logger.From(ctx).Errorf("%s/Delete error delete board: %s", boardServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+func (s *boardService) CreateColumn(ctx context.Context, input BoardColumnInput) (BoardColumnOutput, error) {
+	column := &dbmodel.BoardColumn{
+		BoardId:  input.BoardId,
+		Name:     input.Name,
+		WipLimit: input.WipLimit,
+		Status:   input.Status,
+	}
+	if err := s.board.CreateColumn(ctx, column, input.Username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return BoardColumnOutput{}, ErrBoardNotFound
+		}
+		logger.From(ctx).Errorf("%s/CreateColumn error create board column: %s", boardServicePrefixLog, err)
+		return BoardColumnOutput{}, err
+	}
+	return newBoardColumnOutput(*column), nil
+}
+
+func (s *boardService) UpdateColumn(ctx context.Context, input BoardColumnInput) (BoardColumnOutput, error) {
+	column := &dbmodel.BoardColumn{
+		Id:       input.Id,
+		BoardId:  input.BoardId,
+		Name:     input.Name,
+		WipLimit: input.WipLimit,
+		Status:   input.Status,
+	}
+	if err := s.board.UpdateColumn(ctx, column, input.Username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return BoardColumnOutput{}, ErrBoardColumnNotFound
+		}
+		logger.From(ctx).Errorf("%s/UpdateColumn error update board column: %s", boardServicePrefixLog, err)
+		return BoardColumnOutput{}, err
+	}
+	return newBoardColumnOutput(*column), nil
+}
+
+func (s *boardService) DeleteColumn(ctx context.Context, boardId, columnId int, username string) error {
+	if err := s.board.DeleteColumn(ctx, boardId, columnId, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrBoardColumnNotFound
+		}
+		logger.From(ctx).Errorf("%s/DeleteColumn error delete board column: %s", boardServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// ReorderColumns принимает новый порядок всех колонок доски
+func (s *boardService) ReorderColumns(ctx context.Context, input BoardReorderInput) ([]BoardColumnOutput, error) {
+	if _, err := s.board.FindById(ctx, input.BoardId, input.Username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrBoardNotFound
+		}
+		logger.From(ctx).Errorf("%s/ReorderColumns error find board by id: %s", boardServicePrefixLog, err)
+		return nil, err
+	}
+
+	columns, err := s.board.FindColumns(ctx, input.BoardId)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/ReorderColumns error find board columns: %s", boardServicePrefixLog, err)
+		return nil, err
+	}
+	if !sameColumns(columns, input.ColumnIds) {
+		return nil, ErrBoardColumnsMismatch
+	}
+
+	if err = s.board.ReorderColumns(ctx, input.BoardId, input.ColumnIds); err != nil {
+		logger.From(ctx).Errorf("%s/ReorderColumns error reorder board columns: %s", boardServicePrefixLog, err)
+		return nil, err
+	}
+
+	columns, err = s.board.FindColumns(ctx, input.BoardId)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/ReorderColumns error find board columns: %s", boardServicePrefixLog, err)
+		return nil, err
+	}
+	result := make([]BoardColumnOutput, 0, len(columns))
+	for _, c := range columns {
+		result = append(result, newBoardColumnOutput(c))
+	}
+	return result, nil
+}
+
+// MoveTask перемещает задачу в колонку доски с проверкой WIP-лимита колонки
+func (s *boardService) MoveTask(ctx context.Context, input BoardMoveTaskInput) (TaskOutput, error) {
+	task, err := s.task.FindById(ctx, input.TaskId, input.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TaskOutput{}, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/MoveTask error find task by id: %s", boardServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	before := task.Status
+
+	if err = s.task.MoveToColumn(ctx, &task, input.BoardId, input.ColumnId); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TaskOutput{}, ErrBoardColumnNotFound
+		}
+		if errors.Is(err, pgerrs.ErrLimitExceeded) {
+			return TaskOutput{}, ErrWIPLimitExceeded
+		}
+		logger.From(ctx).Errorf("%s/MoveTask error move task to column: %s", boardServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	s.metrics.taskCompleted(before, task.Status)
+
+	output, err := s.details.outputs(ctx, input.Username, task)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/MoveTask error find task details: %s", boardServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	return output[0], nil
+}
+
+func sameColumns(columns []dbmodel.BoardColumn, ids []int) bool {
+	if len(columns) != len(ids) {
+		return false
+	}
+	remaining := make(map[int]struct{}, len(columns))
+	for _, c := range columns {
+		remaining[c.Id] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := remaining[id]; !ok {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+func newBoardOutput(b dbmodel.Board) BoardOutput {
+	return BoardOutput{
+		Id:        b.Id,
+		Name:      b.Name,
+		CreatedAt: b.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func newBoardColumnOutput(c dbmodel.BoardColumn) BoardColumnOutput {
+	return BoardColumnOutput{
+		Id:       c.Id,
+		Name:     c.Name,
+		WipLimit: c.WipLimit,
+		Status:   c.Status,
+		Position: c.Position,
+	}
+}
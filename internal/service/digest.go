@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/notifier"
+)
+
+const (
+	digestServicePrefixLog = "/service/digest"
+
+	digestBatchSize = 20
+	// если отправка не удалась, сводка будет взята снова по истечении lease
+	digestLease = 15 * time.Minute
+
+	defaultDigestSendHour = 8
+
+	digestDateLayout    = "Mon, 02 Jan 2006"
+	digestDueDateLayout = "Mon, 02 Jan 15:04"
+	digestDueDayLayout  = "Mon, 02 Jan"
+)
+
+// digestService расписание сводки считается в часовом поясе из профиля пользователя, того же, в котором
+// показываются сроки задач
+type digestService struct {
+	digest repo.Digest
+	user   repo.User
+	task   repo.Task
+	mailer notifier.Notifier
+}
+
+// newDigestService mailer == nil, если отправка почты не настроена: тогда включить сводку нельзя
+func newDigestService(digest repo.Digest, user repo.User, task repo.Task, mailer notifier.Notifier) *digestService {
+	return &digestService{
+		digest: digest,
+		user:   user,
+		task:   task,
+		mailer: mailer,
+	}
+}
+
+func (s *digestService) FindSetting(ctx context.Context, username string) (DigestSettingOutput, error) {
+	setting, err := s.digest.FindSetting(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			u, err := s.user.FindByUsername(ctx, username)
+			if err != nil {
+				if errors.Is(err, pgerrs.ErrNotFound) {
+					return DigestSettingOutput{}, ErrUserNotFound
+				}
+				logger.From(ctx).Errorf("%s/FindSetting error find user: %s", digestServicePrefixLog, err)
+				return DigestSettingOutput{}, err
+			}
+			return newDigestSettingOutput(dbmodel.DigestSetting{
+				Timezone:  u.Timezone,
+				Frequency: dbmodel.DigestFrequencyDaily,
+				SendHour:  defaultDigestSendHour,
+				Weekday:   int(time.Monday),
+			}), nil
+		}
+		logger.From(ctx).Errorf("%s/FindSetting error find digest setting: %s", digestServicePrefixLog, err)
+		return DigestSettingOutput{}, err
+	}
+	return newDigestSettingOutput(setting), nil
+}
+
+func (s *digestService) SaveSetting(ctx context.Context, input DigestSettingInput) (DigestSettingOutput, error) {
+	u, err := s.user.FindByUsername(ctx, input.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return DigestSettingOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/SaveSetting error find user: %s", digestServicePrefixLog, err)
+		return DigestSettingOutput{}, err
+	}
+	loc := digestLocation(ctx, u.Timezone)
+	frequency := input.Frequency
+	if frequency == "" {
+		frequency = dbmodel.DigestFrequencyDaily
+	}
+
+	setting := &dbmodel.DigestSetting{
+		Username:  input.Username,
+		Enabled:   input.Enabled,
+		Email:     input.Email,
+		Timezone:  u.Timezone,
+		Frequency: frequency,
+		SendHour:  input.SendHour,
+		Weekday:   input.Weekday,
+	}
+	if setting.Enabled {
+		if s.mailer == nil {
+			return DigestSettingOutput{}, ErrDigestUnavailable
+		}
+		// сводка отправляется только на подтвержденный email пользователя, по умолчанию на него же
+		if setting.Email == "" {
+			setting.Email = u.Email
+		}
+		if !u.EmailVerified || !strings.EqualFold(u.Email, setting.Email) {
+			return DigestSettingOutput{}, ErrDigestEmail
+		}
+		next := nextDigestAt(*setting, loc, time.Now())
+		setting.NextSendAt = &next
+	}
+
+	if err = s.digest.SaveSetting(ctx, setting); err != nil {
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return DigestSettingOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/SaveSetting error save digest setting: %s", digestServicePrefixLog, err)
+		return DigestSettingOutput{}, err
+	}
+	return newDigestSettingOutput(*setting), nil
+}
+
+// SendDue отправляет сводки, время которых наступило. Вызывается воркером периодически
+func (s *digestService) SendDue(ctx context.Context) error {
+	if s.mailer == nil {
+		return nil
+	}
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		settings, err := s.digest.Claim(ctx, now, digestLease, digestBatchSize)
+		if err != nil {
+			logger.From(ctx).Errorf("%s/SendDue error claim due digests: %s", digestServicePrefixLog, err)
+			return err
+		}
+
+		for _, setting := range settings {
+			s.send(ctx, setting, now)
+		}
+		if len(settings) < digestBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *digestService) send(ctx context.Context, setting dbmodel.DigestSetting, now time.Time) {
+	loc := digestLocation(ctx, setting.Timezone)
+
+	msg, empty, err := s.build(ctx, setting, loc, now)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/send error build digest for %s: %s", digestServicePrefixLog, setting.Username, err)
+		return
+	}
+
+	var sentAt *time.Time
+	if !empty {
+		if err = s.mailer.Notify(ctx, msg); err != nil {
+			logger.From(ctx).Warnf("%s/send error send digest to %s: %s", digestServicePrefixLog, setting.Username, err)
+			return
+		}
+		sentAt = &now
+	}
+
+	next := nextDigestAt(setting, loc, now)
+	if err = s.digest.Reschedule(context.WithoutCancel(ctx), setting.Username, next, sentAt); err != nil {
+		logger.From(ctx).Errorf("%s/send error reschedule digest: %s", digestServicePrefixLog, err)
+	}
+}
+
+// build собирает сводку: просроченные задачи, задачи на сегодня и на оставшиеся дни недели (неделя начинается с понедельника).
+// empty == true, если ни в одну из групп задачи не попали или email пользователя больше не подтвержден
+// и письмо отправлять не нужно
+func (s *digestService) build(ctx context.Context, setting dbmodel.DigestSetting, loc *time.Location, now time.Time) (notifier.Message, bool, error) {
+	if setting.Email == "" {
+		logger.From(ctx).Warnf("%s/build digest for %s skipped: email is not verified", digestServicePrefixLog, setting.Username)
+		return notifier.Message{}, true, nil
+	}
+	local := now.In(loc)
+	endOfDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	endOfWeek := endOfDay.AddDate(0, 0, (int(time.Monday)-int(endOfDay.Weekday())+7)%7)
+
+	// задачи на весь день хранятся как полночь UTC своей даты, поэтому в поясах западнее UTC
+	// они могут оказаться позже конца недели. Берем с запасом в сутки и отсеиваем ниже по taskDeadline
+	tasks, err := s.task.FindDue(ctx, setting.Username, endOfWeek.UTC().AddDate(0, 0, 1))
+	if err != nil {
+		return notifier.Message{}, false, err
+	}
+	if len(tasks) == 0 {
+		return notifier.Message{}, true, nil
+	}
+
+	data := digestData{Date: local.Format(digestDateLayout)}
+	for _, t := range tasks {
+		task := digestTask{
+			Title:    t.Title,
+			DueDate:  t.DueDate.In(loc).Format(digestDueDateLayout),
+			Priority: t.Priority,
+		}
+		if t.AllDay {
+			task.DueDate = t.DueDate.Format(digestDueDayLayout)
+		}
+		switch deadline := taskDeadline(t, loc); {
+		case deadline.Before(now):
+			data.Overdue = append(data.Overdue, task)
+		case !deadline.After(endOfDay):
+			data.Today = append(data.Today, task)
+		case !deadline.After(endOfWeek):
+			data.Week = append(data.Week, task)
+		}
+	}
+	if len(data.Overdue)+len(data.Today)+len(data.Week) == 0 {
+		return notifier.Message{}, true, nil
+	}
+
+	var html, text bytes.Buffer
+	if err = digestHTMLTemplate.Execute(&html, data); err != nil {
+		return notifier.Message{}, false, err
+	}
+	if err = digestTextTemplate.Execute(&text, data); err != nil {
+		return notifier.Message{}, false, err
+	}
+
+	return notifier.Message{
+		To: setting.Email,
+		Subject: fmt.Sprintf("Tasks for %s: %d overdue, %d due today, %d this week",
+			data.Date, len(data.Overdue), len(data.Today), len(data.Week)),
+		Body: text.String(),
+		HTML: html.String(),
+	}, false, nil
+}
+
+// rescheduleDigest пересчитывает следующую отправку включенной сводки после смены часового пояса в профиле,
+// иначе ближайшая сводка пришла бы в SendHour по прежнему поясу
+func rescheduleDigest(ctx context.Context, digest repo.Digest, username string, loc *time.Location) error {
+	setting, err := digest.FindSetting(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !setting.Enabled {
+		return nil
+	}
+	return digest.Reschedule(ctx, username, nextDigestAt(setting, loc, time.Now()), nil)
+}
+
+// digestLocation часовой пояс проверяется при сохранении профиля, UTC возвращается, только если пояс пропал из базы tzdata
+func digestLocation(ctx context.Context, timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/digestLocation error load timezone %s: %s", digestServicePrefixLog, timezone, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// nextDigestAt возвращает ближайший после after момент отправки сводки: SendHour:00 по местному времени
+// каждый день или в день недели Weekday. Время считается по календарю loc, поэтому переход на летнее время его не сдвигает
+func nextDigestAt(setting dbmodel.DigestSetting, loc *time.Location, after time.Time) time.Time {
+	local := after.In(loc)
+	days := 1
+	next := time.Date(local.Year(), local.Month(), local.Day(), setting.SendHour, 0, 0, 0, loc)
+	if setting.Frequency == dbmodel.DigestFrequencyWeekly {
+		days = 7
+		next = next.AddDate(0, 0, (setting.Weekday-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next.UTC()
+}
+
+func newDigestSettingOutput(s dbmodel.DigestSetting) DigestSettingOutput {
+	output := DigestSettingOutput{
+		Enabled:   s.Enabled,
+		Email:     s.Email,
+		Timezone:  s.Timezone,
+		Frequency: s.Frequency,
+		SendHour:  s.SendHour,
+		Weekday:   s.Weekday,
+	}
+	if s.NextSendAt != nil {
+		output.NextSendAt = s.NextSendAt.Format(time.RFC3339)
+	}
+	if s.LastSentAt != nil {
+		output.LastSentAt = s.LastSentAt.Format(time.RFC3339)
+	}
+	return output
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// digestData данные для шаблонов сводки. Время задач уже отформатировано в часовом поясе пользователя
+type digestData struct {
+	Date    string
+	Overdue []digestTask
+	Today   []digestTask
+	Week    []digestTask
+}
+
+type digestTask struct {
+	Title    string
+	DueDate  string
+	Priority int
+}
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>Your tasks for {{.Date}}</h2>
+{{- define "tasks"}}
+<ul>
+{{- range .}}
+  <li><b>{{.Title}}</b> &mdash; due {{.DueDate}}{{if eq .Priority 1}} (high priority){{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Overdue}}
+<h3 style="color: #c0392b">Overdue</h3>
+{{- template "tasks" .Overdue}}
+{{- end}}
+{{- if .Today}}
+<h3>Due today</h3>
+{{- template "tasks" .Today}}
+{{- end}}
+{{- if .Week}}
+<h3>Due this week</h3>
+{{- template "tasks" .Week}}
+{{- end}}
+</body>
+</html>
+`))
+
+var digestTextTemplate = texttemplate.Must(texttemplate.New("digest").Parse(`Your tasks for {{.Date}}
+{{- define "tasks"}}
+{{- range .}}
+- {{.Title}}, due {{.DueDate}}{{if eq .Priority 1}} (high priority){{end}}
+{{- end}}
+{{- end}}
+{{- if .Overdue}}
+
+Overdue:
+{{- template "tasks" .Overdue}}
+{{- end}}
+{{- if .Today}}
+
+Due today:
+{{- template "tasks" .Today}}
+{{- end}}
+{{- if .Week}}
+
+Due this week:
+{{- template "tasks" .Week}}
+{{- end}}
+`))
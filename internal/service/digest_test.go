@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"todolist_api/internal/model/dbmodel"
+)
+
+func TestDigestService_SaveSetting_Email(t *testing.T) {
+	digests := &stubDigestRepo{settings: map[string]dbmodel.DigestSetting{}}
+	s := newDigestService(digests, &stubUserRepo{users: map[string]dbmodel.User{
+		"vasya": {Username: "vasya", Email: "vasya@example.com", EmailVerified: true, Timezone: "UTC"},
+		"kolya": {Username: "kolya", Email: "kolya@example.com", Timezone: "UTC"},
+	}}, nil, &stubNotifier{})
+
+	testCases := []struct {
+		testName    string
+		username    string
+		email       string
+		expectEmail string
+		expectErr   error
+	}{
+		{
+			testName:    "Verified email of the user",
+			username:    "vasya",
+			email:       "vasya@example.com",
+			expectEmail: "vasya@example.com",
+		},
+		{
+			testName:    "Empty email defaults to the verified one",
+			username:    "vasya",
+			expectEmail: "vasya@example.com",
+		},
+		{
+			testName:  "Someone else's email",
+			username:  "vasya",
+			email:     "petya@example.com",
+			expectErr: ErrDigestEmail,
+		},
+		{
+			testName:  "Unverified email",
+			username:  "kolya",
+			email:     "kolya@example.com",
+			expectErr: ErrDigestEmail,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			output, err := s.SaveSetting(context.Background(), DigestSettingInput{
+				Username: tc.username,
+				Enabled:  true,
+				Email:    tc.email,
+				SendHour: 8,
+			})
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectEmail, output.Email)
+		})
+	}
+}
@@ -5,10 +5,71 @@ import "errors"
 var (
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrUserNotFound      = errors.New("user not found")
+	ErrUserTimezone      = errors.New("unknown timezone")
+	ErrUserEmailTaken    = errors.New("email is already used by another user")
+	ErrUserWrongPassword = errors.New("wrong password")
+	ErrSessionExpired    = errors.New("session expired, sign in again")
+	ErrUserNotScheduled  = errors.New("account deletion is not scheduled")
+	ErrUserTokenInvalid  = errors.New("invalid or expired token")
+	ErrUserDisabled      = errors.New("account is disabled")
 
-	ErrTaskNotFound = errors.New("task not found")
+	ErrUnknownRole = errors.New("unknown role")
+	ErrAdminSelf   = errors.New("administrators cannot disable themselves or remove their own admin role")
+
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorDisabled   = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending = errors.New("two-factor authentication enrollment is not started")
+	ErrTwoFactorCode       = errors.New("invalid two-factor code")
+
+	ErrPersonalTokenNotFound      = errors.New("personal access token not found")
+	ErrPersonalTokenAlreadyExists = errors.New("personal access token with this name already exists")
+	ErrPersonalTokenScope         = errors.New("at least one scope is required, allowed scopes: tasks:read, tasks:write, boards:read, boards:write")
+	ErrPersonalTokenExpiry        = errors.New("expires_at must be in the future")
+
+	ErrOIDCProviderNotFound    = errors.New("identity provider not found")
+	ErrOIDCProviderUnavailable = errors.New("identity provider is unavailable")
+	ErrOIDCLoginInvalid        = errors.New("sign in session is invalid or expired, start again")
+	ErrOIDCAuthFailed          = errors.New("identity provider authentication failed")
+	ErrOIDCNotLinked           = errors.New("no account is linked to this identity")
+
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrTaskMoveTarget  = errors.New("exactly one of before_id and after_id must be set")
+	ErrTaskMoveItself  = errors.New("task cannot be moved relative to itself")
+	ErrQuickAddTitle   = errors.New("task title is empty")
+	ErrQuickAddDueDate = errors.New("task due date is not recognized")
+
+	ErrDependencyNotFound      = errors.New("dependency not found")
+	ErrDependencyAlreadyExists = errors.New("dependency already exists")
+	ErrDependencyItself        = errors.New("task cannot be blocked by itself")
+	ErrDependencyCycle         = errors.New("dependency would create a cycle")
+
+	ErrBoardNotFound        = errors.New("board not found")
+	ErrBoardColumnNotFound  = errors.New("board column not found")
+	ErrBoardColumnsMismatch = errors.New("column ids must contain every board column exactly once")
+	ErrWIPLimitExceeded     = errors.New("column WIP limit exceeded")
+
+	ErrTimerAlreadyRunning   = errors.New("timer is already running")
+	ErrTimerNotRunning       = errors.New("timer is not running")
+	ErrTimeEntryNotFound     = errors.New("time entry not found")
+	ErrTimeEntryInvalidRange = errors.New("ended_at must not be before started_at")
+	ErrTimeReportRange       = errors.New("to must be after from")
+
+	ErrReminderNotFound           = errors.New("reminder not found")
+	ErrReminderTime               = errors.New("exactly one of remind_at and offset_minutes must be set")
+	ErrReminderChannelUnavailable = errors.New("reminder channel is not configured")
+	ErrReminderTarget             = errors.New("reminder target must be an email address for email channel and public http(s) url for webhook channel")
+	ErrReminderEmailNotVerified   = errors.New("email reminders can only be sent to your verified email")
+
+	ErrDigestEmail       = errors.New("digest can only be sent to your verified email")
+	ErrDigestUnavailable = errors.New("digest emails are not configured")
+
+	ErrExportNotFound    = errors.New("export not found")
+	ErrExportInProgress  = errors.New("export is already in progress")
+	ErrExportLinkInvalid = errors.New("invalid download link")
+	ErrExportLinkExpired = errors.New("download link expired")
 
 	ErrIncorrectSignMethod = errors.New("incorrect sign method")
 	ErrInvalidToken        = errors.New("invalid token")
+	ErrUnknownSigningKey   = errors.New("unknown signing key")
 	ErrCannotParseToken    = errors.New("cannot parse token")
 )
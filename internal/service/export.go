@@ -0,0 +1,315 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	exportServicePrefixLog = "/service/export"
+
+	// exportLease время, на которое выгрузка захватывается воркером для сборки архива
+	exportLease     = 5 * time.Minute
+	exportBatchSize = 10
+	// exportSignInLimit сколько попыток входа попадает в выгрузку. История хранится LOCKOUT_HISTORY_TTL,
+	// так что ограничение защищает только от аккаунтов под подбором пароля
+	exportSignInLimit = 10000
+)
+
+// exportFile файл архива выгрузки, data сохраняется в нем как JSON
+type exportFile struct {
+	name string
+	data any
+}
+
+type exportService struct {
+	export    repo.DataExport
+	user      repo.User
+	task      repo.Task
+	board     repo.Board
+	timeEntry repo.TimeEntry
+	reminder  repo.Reminder
+	digest    repo.Digest
+	tokens    repo.PersonalToken
+	oidc      repo.OIDC
+	signIn    repo.SignInAttempt
+	details   *taskDetails
+	signKey   []byte
+	ttl       time.Duration
+}
+
+// newExportService ttl - сколько готовый архив доступен для скачивания, signKey подписывает ссылки на скачивание
+func newExportService(repos *repo.Repositories, details *taskDetails, signKey string, ttl time.Duration) *exportService {
+	return &exportService{
+		export:    repos.DataExport,
+		user:      repos.User,
+		task:      repos.Task,
+		board:     repos.Board,
+		timeEntry: repos.TimeEntry,
+		reminder:  repos.Reminder,
+		digest:    repos.Digest,
+		tokens:    repos.PersonalToken,
+		oidc:      repos.OIDC,
+		signIn:    repos.SignInAttempt,
+		details:   details,
+		signKey:   []byte(signKey),
+		ttl:       ttl,
+	}
+}
+
+// Create ставит в очередь сборку архива с данными пользователя
+func (s *exportService) Create(ctx context.Context, username string) (DataExportOutput, error) {
+	export := &dbmodel.DataExport{Username: username}
+	if err := s.export.Create(ctx, export); err != nil {
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return DataExportOutput{}, ErrExportInProgress
+		}
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return DataExportOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Create error create export: %s", exportServicePrefixLog, err)
+		return DataExportOutput{}, err
+	}
+	return s.newDataExportOutput(*export), nil
+}
+
+func (s *exportService) FindById(ctx context.Context, id int, username string) (DataExportOutput, error) {
+	export, err := s.export.FindById(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return DataExportOutput{}, ErrExportNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindById error find export: %s", exportServicePrefixLog, err)
+		return DataExportOutput{}, err
+	}
+	return s.newDataExportOutput(export), nil
+}
+
+// Download возвращает архив по подписанной ссылке. Ссылка действует до expires (unix-время) и не требует авторизации
+func (s *exportService) Download(ctx context.Context, id int, expires int64, signature string) ([]byte, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return nil, ErrExportLinkInvalid
+	}
+	now := time.Now()
+	if !now.Before(time.Unix(expires, 0)) {
+		return nil, ErrExportLinkExpired
+	}
+
+	export, err := s.export.FindArchive(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrExportNotFound
+		}
+		logger.From(ctx).Errorf("%s/Download error find export archive: %s", exportServicePrefixLog, err)
+		return nil, err
+	}
+	if export.ExpiresAt == nil || !now.Before(*export.ExpiresAt) {
+		return nil, ErrExportLinkExpired
+	}
+	return export.Archive, nil
+}
+
+// BuildPending собирает архивы ожидающих выгрузок и удаляет выгрузки с истекшим сроком хранения.
+// Вызывается воркером периодически
+func (s *exportService) BuildPending(ctx context.Context) error {
+	if _, err := s.export.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		logger.From(ctx).Errorf("%s/BuildPending error delete expired exports: %s", exportServicePrefixLog, err)
+		return err
+	}
+
+	for ctx.Err() == nil {
+		exports, err := s.export.Claim(ctx, time.Now().UTC(), exportLease, exportBatchSize)
+		if err != nil {
+			logger.From(ctx).Errorf("%s/BuildPending error claim pending exports: %s", exportServicePrefixLog, err)
+			return err
+		}
+
+		for _, export := range exports {
+			s.build(ctx, export)
+		}
+		if len(exports) < exportBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *exportService) build(ctx context.Context, export dbmodel.DataExport) {
+	archive, err := s.archive(ctx, export.Username)
+	if err != nil {
+		if ctx.Err() != nil {
+			// воркер останавливается, выгрузку доберет следующий запуск после истечения аренды
+			return
+		}
+		logger.From(ctx).Errorf("%s/build error build archive for %s: %s", exportServicePrefixLog, export.Username, err)
+		if err = s.export.MarkFailed(context.WithoutCancel(ctx), export.Id, err.Error()); err != nil {
+			logger.From(ctx).Errorf("%s/build error mark export failed: %s", exportServicePrefixLog, err)
+		}
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(s.ttl)
+	if err = s.export.MarkReady(context.WithoutCancel(ctx), export.Id, archive, expiresAt); err != nil {
+		logger.From(ctx).Errorf("%s/build error mark export ready: %s", exportServicePrefixLog, err)
+	}
+}
+
+// archive собирает ZIP-архив со всеми данными пользователя, каждый вид данных - отдельный JSON-файл
+func (s *exportService) archive(ctx context.Context, username string) ([]byte, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.task.Find(ctx, username, dbmodel.TaskSortPosition)
+	if err != nil {
+		return nil, err
+	}
+	taskOutputs, err := s.details.outputs(ctx, username, tasks...)
+	if err != nil {
+		return nil, err
+	}
+
+	boards, err := s.board.Find(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	boardOutputs := make([]DataExportBoard, 0, len(boards))
+	for _, b := range boards {
+		columns, err := s.board.FindColumns(ctx, b.Id)
+		if err != nil {
+			return nil, err
+		}
+		board := DataExportBoard{BoardOutput: newBoardOutput(b), Columns: make([]BoardColumnOutput, 0, len(columns))}
+		for _, c := range columns {
+			board.Columns = append(board.Columns, newBoardColumnOutput(c))
+		}
+		boardOutputs = append(boardOutputs, board)
+	}
+
+	entries, err := s.timeEntry.FindByUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	entryOutputs := make([]TimeEntryOutput, 0, len(entries))
+	for _, e := range entries {
+		entryOutputs = append(entryOutputs, newTimeEntryOutput(e))
+	}
+
+	reminders, err := s.reminder.FindByUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	reminderOutputs := make([]ReminderOutput, 0, len(reminders))
+	for _, r := range reminders {
+		reminderOutputs = append(reminderOutputs, newReminderOutput(r))
+	}
+
+	// сами токены не хранятся, выгружаются только их описания
+	tokens, err := s.tokens.FindByUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	tokenOutputs := make([]PersonalTokenOutput, 0, len(tokens))
+	for _, t := range tokens {
+		tokenOutputs = append(tokenOutputs, newPersonalTokenOutput(t))
+	}
+
+	identities, err := s.oidc.FindIdentitiesByUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	identityOutputs := make([]UserIdentityOutput, 0, len(identities))
+	for _, i := range identities {
+		identityOutputs = append(identityOutputs, UserIdentityOutput{
+			Provider:  i.Provider,
+			Subject:   i.Subject,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	// попытки до регистрации могли относиться к прежнему владельцу имени
+	attempts, err := s.signIn.FindByUsername(ctx, username, u.CreatedAt, exportSignInLimit)
+	if err != nil {
+		return nil, err
+	}
+	attemptOutputs := make([]SignInAttemptOutput, 0, len(attempts))
+	for _, a := range attempts {
+		attemptOutputs = append(attemptOutputs, newSignInAttemptOutput(a))
+	}
+
+	files := []exportFile{
+		{"profile.json", newUserOutput(u)},
+		{"tasks.json", taskOutputs},
+		{"boards.json", boardOutputs},
+		{"time_entries.json", entryOutputs},
+		{"reminders.json", reminderOutputs},
+		{"personal_tokens.json", tokenOutputs},
+		{"identities.json", identityOutputs},
+		{"sign_in_history.json", attemptOutputs},
+	}
+
+	setting, err := s.digest.FindSetting(ctx, username)
+	if err != nil && !errors.Is(err, pgerrs.ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		files = append(files, exportFile{"digest.json", newDigestSettingOutput(setting)})
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		data, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		fw, err := w.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = fw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sign подписывает ссылку на скачивание выгрузки id, действующую до expires
+func (s *exportService) sign(id int, expires int64) string {
+	mac := hmac.New(sha256.New, s.signKey)
+	mac.Write([]byte(strconv.Itoa(id) + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *exportService) newDataExportOutput(e dbmodel.DataExport) DataExportOutput {
+	output := DataExportOutput{
+		Id:        e.Id,
+		Status:    e.Status,
+		Error:     e.LastError,
+		CreatedAt: e.CreatedAt.Format(time.RFC3339),
+	}
+	if e.Status == dbmodel.DataExportStatusReady && e.ExpiresAt != nil {
+		expires := e.ExpiresAt.Unix()
+		output.ExpiresAt = e.ExpiresAt.Format(time.RFC3339)
+		output.DownloadURL = fmt.Sprintf("/exports/%d?expires=%d&signature=%s", e.Id, expires, s.sign(e.Id, expires))
+	}
+	return output
+}
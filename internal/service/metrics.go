@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"todolist_api/internal/model/dbmodel"
+)
+
+// Metrics бизнес-счетчики сервисов
+type Metrics struct {
+	tasksCreated   prometheus.Counter
+	tasksCompleted prometheus.Counter
+	signIns        *prometheus.CounterVec
+}
+
+// NewMetrics создает счетчики и регистрирует их в reg. Без reg счетчики никуда не отдаются
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		tasksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "todolist_tasks_created_total",
+			Help: "Number of created tasks",
+		}),
+		tasksCompleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "todolist_tasks_completed_total",
+			Help: "Number of tasks moved to the done status",
+		}),
+		signIns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "todolist_sign_ins_total",
+			Help: "Number of sign in attempts by method and result (succeeded or failed)",
+		}, []string{"method", "result"}),
+	}
+	if reg != nil {
+		reg.MustRegister(m.tasksCreated, m.tasksCompleted, m.signIns)
+	}
+	return m
+}
+
+func (m *Metrics) taskCompleted(before, after string) {
+	if before != after && after == dbmodel.TaskStatusDone {
+		m.tasksCompleted.Inc()
+	}
+}
+
+func (m *Metrics) signIn(method string, success bool) {
+	result := "failed"
+	if success {
+		result = "succeeded"
+	}
+	m.signIns.WithLabelValues(method, result).Inc()
+}
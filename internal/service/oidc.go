@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/oidc"
+)
+
+const (
+	oidcServicePrefixLog = "/service/oidc"
+
+	defaultOIDCLoginTTL = 10 * time.Minute
+	oidcRandomBytes     = 32
+
+	// ограничения имени пользователя по умолчанию те же, что при регистрации
+	defaultUsernameMinLength = 3
+	defaultUsernameMaxLength = 32
+	oidcUsernameAttempts     = 5
+)
+
+var oidcUsernameInvalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// OIDCProvider Client - клиент провайдера OpenID Connect. AutoProvision разрешает при первом входе
+// создать локального пользователя, если внешний аккаунт ни с кем не связан
+type OIDCProvider struct {
+	Client        *oidc.Provider
+	AutoProvision bool
+}
+
+type oidcService struct {
+	user      repo.User
+	oidc      repo.OIDC
+	hasher    hasher.Hasher
+	providers map[string]OIDCProvider
+	loginTTL  time.Duration
+	// usernameMinLength, usernameMaxLength ограничения для имен новых пользователей, как при регистрации
+	usernameMinLength int
+	usernameMaxLength int
+}
+
+// newOIDCService loginTTL - сколько пользователь может пробыть на странице провайдера до возврата
+func newOIDCService(user repo.User, oidc repo.OIDC, hasher hasher.Hasher, providers map[string]OIDCProvider, loginTTL time.Duration, usernameMinLength, usernameMaxLength int) *oidcService {
+	if loginTTL <= 0 {
+		loginTTL = defaultOIDCLoginTTL
+	}
+	if usernameMinLength <= 0 || usernameMaxLength < usernameMinLength {
+		usernameMinLength, usernameMaxLength = defaultUsernameMinLength, defaultUsernameMaxLength
+	}
+	return &oidcService{
+		user:              user,
+		oidc:              oidc,
+		hasher:            hasher,
+		providers:         providers,
+		loginTTL:          loginTTL,
+		usernameMinLength: usernameMinLength,
+		usernameMaxLength: usernameMaxLength,
+	}
+}
+
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Begin начинает вход: сохраняет state, nonce и секрет PKCE и возвращает адрес страницы входа провайдера и state
+func (s *oidcService) Begin(ctx context.Context, provider string) (OIDCLoginOutput, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return OIDCLoginOutput{}, ErrOIDCProviderNotFound
+	}
+
+	login := dbmodel.OIDCLogin{
+		Provider:  provider,
+		ExpiresAt: time.Now().Add(s.loginTTL),
+	}
+	for _, v := range []*string{&login.State, &login.Nonce, &login.CodeVerifier} {
+		r, err := oidcRandomString()
+		if err != nil {
+			logger.From(ctx).Errorf("%s/Begin error generate login secrets: %s", oidcServicePrefixLog, err)
+			return OIDCLoginOutput{}, err
+		}
+		*v = r
+	}
+
+	authURL, err := p.Client.AuthCodeURL(ctx, login.State, login.Nonce, login.CodeVerifier)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Begin error build %s auth url: %s", oidcServicePrefixLog, provider, err)
+		return OIDCLoginOutput{}, ErrOIDCProviderUnavailable
+	}
+	if err = s.oidc.CreateLogin(ctx, login, time.Now()); err != nil {
+		logger.From(ctx).Errorf("%s/Begin error save login: %s", oidcServicePrefixLog, err)
+		return OIDCLoginOutput{}, err
+	}
+	return OIDCLoginOutput{AuthURL: authURL, State: login.State, ExpiresAt: login.ExpiresAt}, nil
+}
+
+// Complete завершает вход по code и state из адреса возврата и возвращает имя локального пользователя.
+// Внешний аккаунт ищется среди связанных, затем связывается с пользователем с тем же подтвержденным email,
+// иначе при AutoProvision создается новый пользователь
+func (s *oidcService) Complete(ctx context.Context, input OIDCCallbackInput) (string, error) {
+	p, ok := s.providers[input.Provider]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	login, err := s.oidc.ConsumeLogin(ctx, input.Provider, input.State, time.Now())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return "", ErrOIDCLoginInvalid
+		}
+		logger.From(ctx).Errorf("%s/Complete error consume login: %s", oidcServicePrefixLog, err)
+		return "", err
+	}
+
+	claims, err := p.Client.Exchange(ctx, input.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Complete error exchange %s code: %s", oidcServicePrefixLog, input.Provider, err)
+		return "", ErrOIDCAuthFailed
+	}
+
+	identity, err := s.oidc.FindIdentity(ctx, input.Provider, claims.Subject)
+	if err == nil {
+		return identity.Username, nil
+	}
+	if !errors.Is(err, pgerrs.ErrNotFound) {
+		logger.From(ctx).Errorf("%s/Complete error find identity: %s", oidcServicePrefixLog, err)
+		return "", err
+	}
+
+	username, err := s.linkedUser(ctx, claims)
+	if err != nil {
+		return "", err
+	}
+	if username == "" {
+		if !p.AutoProvision {
+			return "", ErrOIDCNotLinked
+		}
+		if username, err = s.provision(ctx, claims); err != nil {
+			return "", err
+		}
+	}
+
+	err = s.oidc.CreateIdentity(ctx, dbmodel.UserIdentity{
+		Provider: input.Provider,
+		Subject:  claims.Subject,
+		Username: username,
+		Email:    claims.Email,
+	})
+	if err != nil && !errors.Is(err, pgerrs.ErrAlreadyExists) {
+		logger.From(ctx).Errorf("%s/Complete error create identity: %s", oidcServicePrefixLog, err)
+		return "", err
+	}
+	return username, nil
+}
+
+// linkedUser ищет пользователя, с которым можно связать внешний аккаунт по email. Email должен быть подтвержден
+// и у провайдера, и у нас, иначе чужой аккаунт можно было бы захватить, указав его email у провайдера
+func (s *oidcService) linkedUser(ctx context.Context, claims oidc.Claims) (string, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return "", nil
+	}
+	u, err := s.user.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return "", nil
+		}
+		logger.From(ctx).Errorf("%s/linkedUser error find user by email: %s", oidcServicePrefixLog, err)
+		return "", err
+	}
+	if !u.EmailVerified {
+		return "", nil
+	}
+	return u.Username, nil
+}
+
+// provision создает пользователя для внешнего аккаунта. Пароль случайный, задать свой можно через сброс пароля.
+// Подтвержденный у провайдера email сохраняется подтвержденным, если он не занят другим пользователем
+func (s *oidcService) provision(ctx context.Context, claims oidc.Claims) (string, error) {
+	password, err := oidcRandomString()
+	if err != nil {
+		logger.From(ctx).Errorf("%s/provision error generate password: %s", oidcServicePrefixLog, err)
+		return "", err
+	}
+
+	email := ""
+	if claims.Email != "" && claims.EmailVerified {
+		if _, err = s.user.FindByEmail(ctx, claims.Email); errors.Is(err, pgerrs.ErrNotFound) {
+			email = claims.Email
+		} else if err != nil {
+			logger.From(ctx).Errorf("%s/provision error find user by email: %s", oidcServicePrefixLog, err)
+			return "", err
+		}
+	}
+
+	base := oidcUsername(claims, s.usernameMinLength, s.usernameMaxLength)
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := oidcRandomString()
+			if err != nil {
+				return "", err
+			}
+			suffix = strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(suffix))[:4]
+			username = base[:min(len(base), max(s.usernameMaxLength-len(suffix)-1, 0))] + "_" + suffix
+		}
+
+		err = s.user.Create(ctx, dbmodel.User{
+			Username:    username,
+			Password:    s.hasher.Hash(password),
+			DisplayName: claims.Name,
+			Email:       email,
+		})
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			logger.From(ctx).Errorf("%s/provision error create user: %s", oidcServicePrefixLog, err)
+			return "", err
+		}
+
+		if email != "" {
+			if err = s.user.VerifyEmail(ctx, username, email); err != nil {
+				logger.From(ctx).Errorf("%s/provision error verify email: %s", oidcServicePrefixLog, err)
+			}
+		}
+		return username, nil
+	}
+	logger.From(ctx).Errorf("%s/provision error no free username for %s", oidcServicePrefixLog, base)
+	return "", ErrUserAlreadyExists
+}
+
+// oidcUsername имя пользователя из preferred_username или email, приведенное к правилам регистрации
+func oidcUsername(claims oidc.Claims, minLength, maxLength int) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	name = strings.Trim(oidcUsernameInvalidChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if len(name) < minLength {
+		name = "user_" + name
+	}
+	if len(name) > maxLength {
+		name = name[:maxLength]
+	}
+	return name
+}
+
+func oidcRandomString() (string, error) {
+	b := make([]byte, oidcRandomBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	personalTokenServicePrefixLog = "/service/personal_token"
+
+	// PersonalTokenPrefix начало каждого персонального токена. По нему токен отличается от JWT,
+	// а утекший токен легко найти сканерами секретов
+	PersonalTokenPrefix = "tdl_"
+
+	personalTokenBytes = 20
+	// personalTokenDisplayLength сколько первых символов токена хранится открыто, чтобы пользователь узнал его в списке
+	personalTokenDisplayLength = len(PersonalTokenPrefix) + 8
+)
+
+// Права персональных токенов. Запросы с JWT ограничений не имеют
+const (
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeBoardsRead  = "boards:read"
+	ScopeBoardsWrite = "boards:write"
+)
+
+var PersonalTokenScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeBoardsRead, ScopeBoardsWrite}
+
+var personalTokenEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+type personalTokenService struct {
+	token repo.PersonalToken
+	user  repo.User
+}
+
+func newPersonalTokenService(token repo.PersonalToken, user repo.User) *personalTokenService {
+	return &personalTokenService{token: token, user: user}
+}
+
+// Create выпускает новый токен. Сам токен возвращается только здесь, в базе хранится его хеш
+func (s *personalTokenService) Create(ctx context.Context, input PersonalTokenInput) (PersonalTokenCreateOutput, error) {
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return PersonalTokenCreateOutput{}, err
+	}
+	var expiresAt *time.Time
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(time.Now()) {
+			return PersonalTokenCreateOutput{}, ErrPersonalTokenExpiry
+		}
+		t := input.ExpiresAt.UTC()
+		expiresAt = &t
+	}
+
+	b := make([]byte, personalTokenBytes)
+	if _, err = rand.Read(b); err != nil {
+		logger.From(ctx).Errorf("%s/Create error generate token: %s", personalTokenServicePrefixLog, err)
+		return PersonalTokenCreateOutput{}, err
+	}
+	token := PersonalTokenPrefix + personalTokenEncoding.EncodeToString(b)
+
+	t := dbmodel.PersonalToken{
+		Username:  input.Username,
+		Name:      input.Name,
+		Prefix:    token[:personalTokenDisplayLength],
+		TokenHash: hashUserToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err = s.token.Create(ctx, &t); err != nil {
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return PersonalTokenCreateOutput{}, ErrPersonalTokenAlreadyExists
+		}
+		if errors.Is(err, pgerrs.ErrForeignKey) {
+			return PersonalTokenCreateOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Create error create token: %s", personalTokenServicePrefixLog, err)
+		return PersonalTokenCreateOutput{}, err
+	}
+	return PersonalTokenCreateOutput{
+		PersonalTokenOutput: newPersonalTokenOutput(t),
+		Token:               token,
+	}, nil
+}
+
+func (s *personalTokenService) Find(ctx context.Context, username string) ([]PersonalTokenOutput, error) {
+	tokens, err := s.token.FindByUser(ctx, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Find error find tokens: %s", personalTokenServicePrefixLog, err)
+		return nil, err
+	}
+	output := make([]PersonalTokenOutput, 0, len(tokens))
+	for _, t := range tokens {
+		output = append(output, newPersonalTokenOutput(t))
+	}
+	return output, nil
+}
+
+func (s *personalTokenService) Delete(ctx context.Context, id int, username string) error {
+	if err := s.token.Delete(ctx, id, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrPersonalTokenNotFound
+		}
+		logger.From(ctx).Errorf("%s/Delete error delete token: %s", personalTokenServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// Authenticate проверяет персональный токен и отмечает время его использования.
+// Возвращает ErrInvalidToken, если токена нет, он отозван или истек, и ErrUserDisabled, если владелец отключен
+func (s *personalTokenService) Authenticate(ctx context.Context, token string) (PersonalTokenIdentity, error) {
+	if !strings.HasPrefix(token, PersonalTokenPrefix) {
+		return PersonalTokenIdentity{}, ErrInvalidToken
+	}
+	t, err := s.token.Use(ctx, hashUserToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return PersonalTokenIdentity{}, ErrInvalidToken
+		}
+		logger.From(ctx).Errorf("%s/Authenticate error use token: %s", personalTokenServicePrefixLog, err)
+		return PersonalTokenIdentity{}, err
+	}
+	u, err := s.user.FindByUsername(ctx, t.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return PersonalTokenIdentity{}, ErrInvalidToken
+		}
+		logger.From(ctx).Errorf("%s/Authenticate error find user: %s", personalTokenServicePrefixLog, err)
+		return PersonalTokenIdentity{}, err
+	}
+	if u.DisabledAt != nil {
+		return PersonalTokenIdentity{}, ErrUserDisabled
+	}
+	return PersonalTokenIdentity{
+		Username: t.Username,
+		Scopes:   t.Scopes,
+	}, nil
+}
+
+// normalizeScopes проверяет, что все права известны, и убирает повторы
+func normalizeScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(PersonalTokenScopes, scope) {
+			return nil, ErrPersonalTokenScope
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrPersonalTokenScope
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+func newPersonalTokenOutput(t dbmodel.PersonalToken) PersonalTokenOutput {
+	output := PersonalTokenOutput{
+		Id:        t.Id,
+		Name:      t.Name,
+		Prefix:    t.Prefix,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+	if t.ExpiresAt != nil {
+		output.ExpiresAt = t.ExpiresAt.Format(time.RFC3339)
+	}
+	if t.LastUsedAt != nil {
+		output.LastUsedAt = t.LastUsedAt.Format(time.RFC3339)
+	}
+	return output
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+)
+
+func TestPersonalTokenService_Authenticate(t *testing.T) {
+	disabledAt := time.Now()
+	s := newPersonalTokenService(
+		&stubPersonalTokenRepo{tokens: map[string]dbmodel.PersonalToken{
+			hashUserToken(PersonalTokenPrefix + "vasya"):   {Username: "vasya", Scopes: []string{ScopeTasksRead}},
+			hashUserToken(PersonalTokenPrefix + "petya"):   {Username: "petya", Scopes: []string{ScopeTasksRead}},
+			hashUserToken(PersonalTokenPrefix + "deleted"): {Username: "kolya", Scopes: []string{ScopeTasksRead}},
+		}},
+		&stubUserRepo{users: map[string]dbmodel.User{
+			"vasya": {Username: "vasya"},
+			"petya": {Username: "petya", DisabledAt: &disabledAt},
+		}},
+	)
+
+	testCases := []struct {
+		testName     string
+		token        string
+		expectScopes []string
+		expectErr    error
+	}{
+		{
+			testName:     "Valid token",
+			token:        PersonalTokenPrefix + "vasya",
+			expectScopes: []string{ScopeTasksRead},
+		},
+		{
+			testName:  "Unknown, expired or revoked token",
+			token:     PersonalTokenPrefix + "unknown",
+			expectErr: ErrInvalidToken,
+		},
+		{
+			testName:  "Token without prefix",
+			token:     "vasya",
+			expectErr: ErrInvalidToken,
+		},
+		{
+			testName:  "Owner is disabled",
+			token:     PersonalTokenPrefix + "petya",
+			expectErr: ErrUserDisabled,
+		},
+		{
+			testName:  "Owner is deleted",
+			token:     PersonalTokenPrefix + "deleted",
+			expectErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			identity, err := s.Authenticate(context.Background(), tc.token)
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectScopes, identity.Scopes)
+		})
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := normalizeScopes([]string{ScopeTasksWrite, ScopeBoardsRead, ScopeTasksWrite})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeBoardsRead, ScopeTasksWrite}, scopes)
+
+	_, err = normalizeScopes([]string{"users:manage"})
+	assert.ErrorIs(t, err, ErrPersonalTokenScope)
+
+	_, err = normalizeScopes(nil)
+	assert.ErrorIs(t, err, ErrPersonalTokenScope)
+}
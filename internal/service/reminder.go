@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/notifier"
+)
+
+const (
+	reminderServicePrefixLog = "/service/reminder"
+
+	reminderBatchSize = 50
+	// на время отправки напоминание закреплено за репликой, по истечении lease его может забрать другая
+	reminderLease       = 2 * time.Minute
+	reminderMaxAttempts = 5
+	// после неудачной отправки следующая попытка откладывается на attempts * reminderRetryDelay
+	reminderRetryDelay = time.Minute
+)
+
+// reminderService напоминания по email отправляются только на подтвержденный email владельца,
+// чтобы через них нельзя было рассылать письма на чужие адреса
+type reminderService struct {
+	reminder  repo.Reminder
+	task      repo.Task
+	user      repo.User
+	notifiers map[string]notifier.Notifier
+}
+
+// newReminderService notifiers - отправители по каналам dbmodel.ReminderChannel*, ненастроенного канала в map нет
+func newReminderService(reminder repo.Reminder, task repo.Task, user repo.User, notifiers map[string]notifier.Notifier) *reminderService {
+	return &reminderService{
+		reminder:  reminder,
+		task:      task,
+		user:      user,
+		notifiers: notifiers,
+	}
+}
+
+func (s *reminderService) Create(ctx context.Context, input ReminderInput) (ReminderOutput, error) {
+	if input.RemindAt.IsZero() == (input.OffsetMinutes == nil) {
+		return ReminderOutput{}, ErrReminderTime
+	}
+	if _, ok := s.notifiers[input.Channel]; !ok {
+		return ReminderOutput{}, ErrReminderChannelUnavailable
+	}
+	if !validReminderTarget(input.Channel, input.Target) {
+		return ReminderOutput{}, ErrReminderTarget
+	}
+	if input.Channel == dbmodel.ReminderChannelEmail {
+		u, err := s.user.FindByUsername(ctx, input.Username)
+		if err != nil {
+			if errors.Is(err, pgerrs.ErrNotFound) {
+				return ReminderOutput{}, ErrTaskNotFound
+			}
+			logger.From(ctx).Errorf("%s/Create error find user: %s", reminderServicePrefixLog, err)
+			return ReminderOutput{}, err
+		}
+		if !u.EmailVerified || !strings.EqualFold(u.Email, input.Target) {
+			return ReminderOutput{}, ErrReminderEmailNotVerified
+		}
+	}
+
+	reminder := &dbmodel.Reminder{
+		TaskId:        input.TaskId,
+		Username:      input.Username,
+		RemindAt:      input.RemindAt.UTC(),
+		OffsetMinutes: input.OffsetMinutes,
+		Channel:       input.Channel,
+		Target:        input.Target,
+	}
+	if err := s.reminder.Create(ctx, reminder); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ReminderOutput{}, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/Create error create reminder: %s", reminderServicePrefixLog, err)
+		return ReminderOutput{}, err
+	}
+	return newReminderOutput(*reminder), nil
+}
+
+func (s *reminderService) FindByTask(ctx context.Context, taskId int, username string) ([]ReminderOutput, error) {
+	if _, err := s.task.FindById(ctx, taskId, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindByTask error find task by id: %s", reminderServicePrefixLog, err)
+		return nil, err
+	}
+
+	reminders, err := s.reminder.FindByTask(ctx, taskId, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindByTask error find task reminders: %s", reminderServicePrefixLog, err)
+		return nil, err
+	}
+
+	result := make([]ReminderOutput, 0, len(reminders))
+	for _, r := range reminders {
+		result = append(result, newReminderOutput(r))
+	}
+	return result, nil
+}
+
+func (s *reminderService) Delete(ctx context.Context, id int, username string) error {
+	if err := s.reminder.Delete(ctx, id, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrReminderNotFound
+		}
+		logger.From(ctx).Errorf("%s/Delete error delete reminder: %s", reminderServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// SendDue отправляет все напоминания, время которых наступило. Вызывается воркером периодически.
+// Ошибка отправки отдельного напоминания не прерывает обработку, напоминание будет отправлено повторно позже
+func (s *reminderService) SendDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		reminders, err := s.reminder.Claim(ctx, now, reminderLease, reminderBatchSize, reminderMaxAttempts)
+		if err != nil {
+			logger.From(ctx).Errorf("%s/SendDue error claim due reminders: %s", reminderServicePrefixLog, err)
+			return err
+		}
+
+		for _, r := range reminders {
+			s.send(ctx, r)
+		}
+		if len(reminders) < reminderBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *reminderService) send(ctx context.Context, r dbmodel.DueReminder) {
+	err := errors.New("notifier for channel " + r.Channel + " is not configured")
+	if r.Channel == dbmodel.ReminderChannelEmail && !strings.EqualFold(r.Target, r.OwnerEmail) {
+		// владелец сменил email или email еще не подтвержден
+		err = ErrReminderEmailNotVerified
+	} else if n, ok := s.notifiers[r.Channel]; ok {
+		err = n.Notify(ctx, notifier.Message{
+			To:      r.Target,
+			Subject: "Reminder: " + r.TaskTitle,
+			Body:    fmt.Sprintf("Task \"%s\" is due %s", r.TaskTitle, r.TaskDueDate.Format(time.RFC1123)),
+		})
+	}
+
+	// статус сохраняем даже после отмены ctx воркера, иначе напоминание будет отправлено повторно
+	saveCtx := context.WithoutCancel(ctx)
+	now := time.Now().UTC()
+	if err != nil {
+		logger.From(ctx).Warnf("%s/send error send reminder %d (attempt %d): %s", reminderServicePrefixLog, r.Id, r.Attempts, err)
+		retryAt := now.Add(time.Duration(r.Attempts) * reminderRetryDelay)
+		if err = s.reminder.MarkFailed(saveCtx, r.Id, err.Error(), retryAt); err != nil {
+			logger.From(ctx).Errorf("%s/send error mark reminder failed: %s", reminderServicePrefixLog, err)
+		}
+		return
+	}
+	if err = s.reminder.MarkSent(saveCtx, r.Id, now); err != nil {
+		logger.From(ctx).Errorf("%s/send error mark reminder sent: %s", reminderServicePrefixLog, err)
+	}
+}
+
+func validReminderTarget(channel, target string) bool {
+	switch channel {
+	case dbmodel.ReminderChannelEmail:
+		addr, err := mail.ParseAddress(target)
+		return err == nil && addr.Address == target
+	case dbmodel.ReminderChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return false
+		}
+		// заведомо внутренние адреса отклоняются сразу; имена проверяются при отправке, после разрешения в адрес
+		if strings.EqualFold(u.Hostname(), "localhost") || strings.HasSuffix(strings.ToLower(u.Hostname()), ".localhost") {
+			return false
+		}
+		if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+			return notifier.PublicAddr(addr)
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func newReminderOutput(r dbmodel.Reminder) ReminderOutput {
+	output := ReminderOutput{
+		Id:            r.Id,
+		TaskId:        r.TaskId,
+		RemindAt:      r.RemindAt.Format(time.RFC3339),
+		OffsetMinutes: r.OffsetMinutes,
+		Channel:       r.Channel,
+		Target:        r.Target,
+		Attempts:      r.Attempts,
+		LastError:     r.LastError,
+	}
+	if r.SentAt != nil {
+		output.SentAt = r.SentAt.Format(time.RFC3339)
+	}
+	return output
+}
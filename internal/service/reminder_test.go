@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/pkg/notifier"
+)
+
+func TestReminderService_Create_Email(t *testing.T) {
+	reminders := &stubReminderRepo{}
+	s := newReminderService(reminders, nil, &stubUserRepo{users: map[string]dbmodel.User{
+		"vasya": {Username: "vasya", Email: "vasya@example.com", EmailVerified: true},
+		"kolya": {Username: "kolya", Email: "kolya@example.com"},
+	}}, map[string]notifier.Notifier{dbmodel.ReminderChannelEmail: &stubNotifier{}})
+
+	testCases := []struct {
+		testName  string
+		username  string
+		target    string
+		expectErr error
+	}{
+		{
+			testName: "Verified email of the owner",
+			username: "vasya",
+			target:   "Vasya@example.com",
+		},
+		{
+			testName:  "Someone else's email",
+			username:  "vasya",
+			target:    "petya@example.com",
+			expectErr: ErrReminderEmailNotVerified,
+		},
+		{
+			testName:  "Unverified email of the owner",
+			username:  "kolya",
+			target:    "kolya@example.com",
+			expectErr: ErrReminderEmailNotVerified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			_, err := s.Create(context.Background(), ReminderInput{
+				TaskId:   1,
+				Username: tc.username,
+				RemindAt: time.Now().Add(time.Hour),
+				Channel:  dbmodel.ReminderChannelEmail,
+				Target:   tc.target,
+			})
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+	assert.Len(t, reminders.created, 1)
+}
+
+func TestReminderService_SendDue_EmailChanged(t *testing.T) {
+	reminders := &stubReminderRepo{}
+	email := &stubNotifier{}
+	s := newReminderService(reminders, nil, nil, map[string]notifier.Notifier{dbmodel.ReminderChannelEmail: email})
+
+	// после создания напоминания владелец сменил email
+	for _, r := range []dbmodel.DueReminder{
+		{Reminder: dbmodel.Reminder{Id: 1, Channel: dbmodel.ReminderChannelEmail, Target: "vasya@example.com"}, OwnerEmail: "vasya@example.com"},
+		{Reminder: dbmodel.Reminder{Id: 2, Channel: dbmodel.ReminderChannelEmail, Target: "vasya@example.com"}, OwnerEmail: "vasily@example.com"},
+	} {
+		s.send(context.Background(), r)
+	}
+
+	assert.Equal(t, []string{"vasya@example.com"}, email.to)
+	assert.Equal(t, []int{1}, reminders.sent)
+	assert.Equal(t, ErrReminderEmailNotVerified.Error(), reminders.failed[2])
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"slices"
+	"todolist_api/internal/model/dbmodel"
+)
+
+// Права ролей. Роль дает набор прав, у пользователя может быть несколько ролей.
+// Новые права и роли добавляются в rolePermissions, хранить их в базе не нужно
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	// PermissionConfigReload перечитывание конфигурации без перезапуска
+	PermissionConfigReload = "config:reload"
+)
+
+var rolePermissions = map[string][]string{
+	dbmodel.RoleUser:  {},
+	dbmodel.RoleAdmin: {PermissionUsersRead, PermissionUsersManage, PermissionConfigReload},
+}
+
+// HasPermission есть ли право permission хотя бы у одной из ролей roles
+func HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		if slices.Contains(rolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRoles проверяет, что все роли известны, и убирает повторы
+func normalizeRoles(roles []string) ([]string, error) {
+	result := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if _, ok := rolePermissions[role]; !ok {
+			return nil, ErrUnknownRole
+		}
+		if !slices.Contains(result, role) {
+			result = append(result, role)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
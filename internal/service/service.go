@@ -2,75 +2,577 @@ package service
 
 import (
 	"context"
+	"strings"
 	"time"
+	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo"
 	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/jwtkeys"
+	"todolist_api/pkg/mailer"
+	"todolist_api/pkg/notifier"
+	"todolist_api/pkg/worker"
 )
 
 type (
+	// UserInput Email необязателен, используется только при регистрации
 	UserInput struct {
 		Username string
 		Password string
+		Email    string
 	}
-	TaskCreateInput struct {
+	// UserUpdateInput nil-поля не меняются. Timezone - название часового пояса IANA (например, Europe/Moscow)
+	UserUpdateInput struct {
 		Username    string
-		Title       string
-		Description string
-		DueDate     time.Time
+		DisplayName *string
+		Email       *string
+		Timezone    *string
+		Preferences map[string]any
 	}
-	TaskUpdateInput struct {
-		Id          int
+	UserPasswordInput struct {
+		Username    string
+		Password    string
+		NewPassword string
+	}
+	UserRenameInput struct {
 		Username    string
-		Title       string
-		Description string
-		DueDate     time.Time
+		Password    string
+		NewUsername string
+	}
+	UserOutput struct {
+		Username         string         `json:"username"`
+		DisplayName      string         `json:"display_name"`
+		Email            string         `json:"email"`
+		EmailVerified    bool           `json:"email_verified"`
+		TwoFactorEnabled bool           `json:"two_factor_enabled"`
+		Roles            []string       `json:"roles"`
+		Timezone         string         `json:"timezone"`
+		Preferences      map[string]any `json:"preferences"`
+		CreatedAt        string         `json:"created_at"`
+		DeleteAt         string         `json:"delete_at,omitempty"`
+	}
+	// TwoFactorInput повторная аутентификация: пароль и код из приложения или код восстановления
+	TwoFactorInput struct {
+		Username string
+		Password string
+		Code     string
+	}
+	// TwoFactorEnrollOutput Secret вводится в приложение-аутентификатор вручную, URI - otpauth:// ссылка для QR-кода
+	TwoFactorEnrollOutput struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+	// TwoFactorCodesOutput коды восстановления показываются один раз, каждый можно использовать вместо кода из приложения
+	TwoFactorCodesOutput struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	// PersonalTokenInput ExpiresAt nil - токен бессрочный. Scopes - права из PersonalTokenScopes
+	PersonalTokenInput struct {
+		Username  string
+		Name      string
+		Scopes    []string
+		ExpiresAt *time.Time
+	}
+	// PersonalTokenOutput Prefix - начало токена, по которому его можно узнать. Сам токен после создания не показывается
+	PersonalTokenOutput struct {
+		Id         int      `json:"id"`
+		Name       string   `json:"name"`
+		Prefix     string   `json:"prefix"`
+		Scopes     []string `json:"scopes"`
+		ExpiresAt  string   `json:"expires_at,omitempty"`
+		LastUsedAt string   `json:"last_used_at,omitempty"`
+		CreatedAt  string   `json:"created_at"`
+	}
+	PersonalTokenCreateOutput struct {
+		PersonalTokenOutput
+		Token string `json:"token"`
+	}
+	// PersonalTokenIdentity владелец и права токена, которым аутентифицирован запрос
+	PersonalTokenIdentity struct {
+		Username string
+		Scopes   []string
+	}
+	// SignInAttemptInput Method - способ входа (dbmodel.SignInMethod*)
+	SignInAttemptInput struct {
+		Username  string
+		IP        string
+		UserAgent string
+		Method    string
+		Success   bool
+	}
+	// UserIdentityOutput внешний аккаунт, привязанный ко входу через провайдера OpenID Connect
+	UserIdentityOutput struct {
+		Provider  string `json:"provider"`
+		Subject   string `json:"subject"`
+		Email     string `json:"email,omitempty"`
+		CreatedAt string `json:"created_at"`
+	}
+	SignInAttemptOutput struct {
+		IP        string `json:"ip"`
+		UserAgent string `json:"user_agent"`
+		Method    string `json:"method"`
+		Success   bool   `json:"success"`
+		CreatedAt string `json:"created_at"`
+	}
+	// AdminUserSearchInput Query ищется в имени, отображаемом имени и email, пустой - все пользователи
+	AdminUserSearchInput struct {
+		Query  string
+		Limit  int
+		Offset int
+	}
+	// AdminUserOutput пользователь глазами администратора: профиль, состояние аккаунта и количество задач
+	AdminUserOutput struct {
+		UserOutput
+		Disabled   bool             `json:"disabled"`
+		DisabledAt string           `json:"disabled_at,omitempty"`
+		Tasks      TaskCountsOutput `json:"tasks"`
+	}
+	TaskCountsOutput struct {
+		Todo       int `json:"todo"`
+		InProgress int `json:"in_progress"`
+		Done       int `json:"done"`
+		Total      int `json:"total"`
+	}
+	// AdminRolesInput Actor - администратор, который меняет роли пользователя Username
+	AdminRolesInput struct {
+		Actor    string
+		Username string
+		Roles    []string
 	}
+	// AdminPasswordResetOutput EmailSent - отправлено ли пользователю письмо со ссылкой на сброс пароля
+	AdminPasswordResetOutput struct {
+		EmailSent bool `json:"email_sent"`
+	}
+	// OIDCLoginOutput начатый вход: AuthURL - страница входа провайдера, State действует до ExpiresAt и должен
+	// вернуться в callback из того же браузера
+	OIDCLoginOutput struct {
+		AuthURL   string
+		State     string
+		ExpiresAt time.Time
+	}
+	// OIDCCallbackInput параметры адреса возврата от провайдера
+	OIDCCallbackInput struct {
+		Provider string
+		State    string
+		Code     string
+	}
+	// UserDeletionOutput DeleteAt - когда аккаунт и все данные пользователя будут удалены
+	UserDeletionOutput struct {
+		DeleteAt string `json:"delete_at"`
+	}
+	TaskCreateInput struct {
+		Username         string
+		Title            string
+		Description      string
+		DueDate          time.Time
+		AllDay           bool
+		Priority         int
+		Status           string
+		Tags             []string
+		EstimatedMinutes int
+		Recurrence       string
+	}
+	// TaskUpdateInput пустой Status означает, что статус задачи не меняется
+	TaskUpdateInput struct {
+		Id               int
+		Username         string
+		Title            string
+		Description      string
+		DueDate          time.Time
+		AllDay           bool
+		Priority         int
+		Status           string
+		Tags             []string
+		EstimatedMinutes int
+		Recurrence       string
+	}
+	// TaskMoveInput перемещает задачу Id непосредственно перед задачей BeforeId или после задачи AfterId.
+	// Должно быть задано ровно одно из полей BeforeId, AfterId
+	TaskMoveInput struct {
+		Id       int
+		Username string
+		BeforeId int
+		AfterId  int
+	}
+	// TaskOutput даты выводятся в часовом поясе пользователя, у задачи на весь день (AllDay) DueDate - дата без времени
 	TaskOutput struct {
+		Id               int      `json:"id"`
+		Title            string   `json:"title"`
+		Description      string   `json:"description"`
+		DueDate          string   `json:"due_date"`
+		AllDay           bool     `json:"all_day"`
+		Priority         int      `json:"priority"`
+		Position         string   `json:"position"`
+		Status           string   `json:"status"`
+		Tags             []string `json:"tags"`
+		Blocked          []int    `json:"blocked"`
+		Blocking         []int    `json:"blocking"`
+		CreatedAt        string   `json:"created_at"`
+		UpdatedAt        string   `json:"updated_at"`
+		EstimatedMinutes int      `json:"estimated_minutes"`
+		TrackedMinutes   int      `json:"tracked_minutes"`
+		Recurrence       string   `json:"recurrence"`
+	}
+	// TaskQuickAddInput Text - задача одной строкой, например "Pay rent tomorrow 9am #finance !p1 every month"
+	TaskQuickAddInput struct {
+		Username string
+		Text     string
+	}
+	// TaskPreviewOutput разобранная быстрая запись задачи. DueDate пустой, если срок не распознан
+	TaskPreviewOutput struct {
+		Title      string   `json:"title"`
+		DueDate    string   `json:"due_date"`
+		AllDay     bool     `json:"all_day"`
+		Priority   int      `json:"priority"`
+		Tags       []string `json:"tags"`
+		Recurrence string   `json:"recurrence"`
+	}
+	// TaskDependencyInput задача TaskId заблокирована задачей BlockedById
+	TaskDependencyInput struct {
+		TaskId      int
+		BlockedById int
+		Username    string
+	}
+	BoardInput struct {
+		Id       int
+		Username string
+		Name     string
+	}
+	BoardOutput struct {
+		Id        int    `json:"id"`
+		Name      string `json:"name"`
+		CreatedAt string `json:"created_at"`
+	}
+	// BoardColumnInput WipLimit = 0 - без ограничения, пустой Status - произвольная колонка без привязки к статусу
+	BoardColumnInput struct {
+		Id       int
+		BoardId  int
+		Username string
+		Name     string
+		WipLimit int
+		Status   string
+	}
+	BoardColumnOutput struct {
+		Id       int    `json:"id"`
+		Name     string `json:"name"`
+		WipLimit int    `json:"wip_limit"`
+		Status   string `json:"status"`
+		Position int    `json:"position"`
+	}
+	BoardColumnTasksOutput struct {
+		BoardColumnOutput
+		Tasks []TaskOutput `json:"tasks"`
+	}
+	BoardViewOutput struct {
+		BoardOutput
+		Columns []BoardColumnTasksOutput `json:"columns"`
+	}
+	BoardReorderInput struct {
+		BoardId   int
+		Username  string
+		ColumnIds []int
+	}
+	BoardMoveTaskInput struct {
+		BoardId  int
+		ColumnId int
+		TaskId   int
+		Username string
+	}
+	// TimeEntryInput запись учета времени, внесенная вручную
+	TimeEntryInput struct {
+		TaskId    int
+		Username  string
+		StartedAt time.Time
+		EndedAt   time.Time
+		Note      string
+	}
+	// TimeEntryOutput у запущенного таймера EndedAt пустой, а длительность считается до текущего момента
+	TimeEntryOutput struct {
+		Id        int    `json:"id"`
+		TaskId    int    `json:"task_id"`
+		StartedAt string `json:"started_at"`
+		EndedAt   string `json:"ended_at,omitempty"`
+		Running   bool   `json:"running"`
+		Minutes   int    `json:"minutes"`
+		Note      string `json:"note"`
+	}
+	// TimeReportInput отчет по записям, начатым в интервале [From, To)
+	TimeReportInput struct {
+		Username string
+		From     time.Time
+		To       time.Time
+		GroupBy  string
+	}
+	TimeReportRowOutput struct {
+		Key     string `json:"key"`
+		Minutes int    `json:"minutes"`
+		Entries int    `json:"entries"`
+	}
+	// ReminderInput должно быть задано ровно одно из полей: RemindAt - абсолютное время напоминания,
+	// OffsetMinutes - за сколько минут до срока задачи напомнить
+	ReminderInput struct {
+		TaskId        int
+		Username      string
+		RemindAt      time.Time
+		OffsetMinutes *int
+		Channel       string
+		Target        string
+	}
+	ReminderOutput struct {
+		Id            int    `json:"id"`
+		TaskId        int    `json:"task_id"`
+		RemindAt      string `json:"remind_at"`
+		OffsetMinutes *int   `json:"offset_minutes"`
+		Channel       string `json:"channel"`
+		Target        string `json:"target"`
+		SentAt        string `json:"sent_at,omitempty"`
+		Attempts      int    `json:"attempts"`
+		LastError     string `json:"last_error"`
+	}
+	// DigestSettingInput SendHour и Weekday (0 - воскресенье, используется только для weekly) в часовом поясе из профиля
+	DigestSettingInput struct {
+		Username  string
+		Enabled   bool
+		Email     string
+		Frequency string
+		SendHour  int
+		Weekday   int
+	}
+	// DigestSettingOutput Timezone - часовой пояс из профиля, меняется через профиль
+	DigestSettingOutput struct {
+		Enabled    bool   `json:"enabled"`
+		Email      string `json:"email"`
+		Timezone   string `json:"timezone"`
+		Frequency  string `json:"frequency"`
+		SendHour   int    `json:"send_hour"`
+		Weekday    int    `json:"weekday"`
+		NextSendAt string `json:"next_send_at,omitempty"`
+		LastSentAt string `json:"last_sent_at,omitempty"`
+	}
+	// DataExportOutput DownloadURL и ExpiresAt заполнены, когда архив готов (Status = ready).
+	// Ссылка подписана и действует без авторизации до ExpiresAt
+	DataExportOutput struct {
 		Id          int    `json:"id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		DueDate     string `json:"due_date"`
+		Status      string `json:"status"`
+		Error       string `json:"error,omitempty"`
+		DownloadURL string `json:"download_url,omitempty"`
+		ExpiresAt   string `json:"expires_at,omitempty"`
 		CreatedAt   string `json:"created_at"`
-		UpdatedAt   string `json:"updated_at"`
+	}
+	DataExportBoard struct {
+		BoardOutput
+		Columns []BoardColumnOutput `json:"columns"`
+	}
+	// TimeReportOutput TotalMinutes - сумма по группам, поэтому при группировке по тегам
+	// запись задачи с несколькими тегами входит в итог несколько раз
+	TimeReportOutput struct {
+		From         string                `json:"from"`
+		To           string                `json:"to"`
+		GroupBy      string                `json:"group_by"`
+		Rows         []TimeReportRowOutput `json:"rows"`
+		TotalMinutes int                   `json:"total_minutes"`
 	}
 )
 
 type Auth interface {
-	CreateToken(username string) (string, error)
+	CreateToken(ctx context.Context, username string) (string, error)
 	ParseToken(tokenString string) (*TokenClaims, error)
+	CreateMFAToken(ctx context.Context, username string) (string, error)
+	ParseMFAToken(tokenString string) (*TokenClaims, error)
+	JWKS() jwtkeys.Set
+	SetVerifyKeys(retired []jwtkeys.Key, acceptLegacy bool)
 }
 
 type User interface {
 	Create(ctx context.Context, input UserInput) error
 	VerifyPassword(ctx context.Context, input UserInput) (bool, error)
+	FindByUsername(ctx context.Context, username string) (UserOutput, error)
+	Update(ctx context.Context, input UserUpdateInput) (UserOutput, error)
+	ChangePassword(ctx context.Context, input UserPasswordInput) error
+	ChangeUsername(ctx context.Context, input UserRenameInput) error
+	Delete(ctx context.Context, input UserInput) (UserDeletionOutput, error)
+	CancelDeletion(ctx context.Context, username string) error
+	EraseScheduled(ctx context.Context) error
+	VerifySession(ctx context.Context, username string, issuedAt time.Time) error
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type SignIn interface {
+	Check(ctx context.Context, input SignInAttemptInput) (int, time.Duration, error)
+	Record(ctx context.Context, id int, input SignInAttemptInput)
+	Cancel(ctx context.Context, id int)
+	FindByUser(ctx context.Context, username string, limit int) ([]SignInAttemptOutput, error)
+}
+
+type Admin interface {
+	FindUsers(ctx context.Context, input AdminUserSearchInput) ([]AdminUserOutput, error)
+	FindUser(ctx context.Context, username string) (AdminUserOutput, error)
+	SetDisabled(ctx context.Context, actor, username string, disabled bool) (AdminUserOutput, error)
+	SetRoles(ctx context.Context, input AdminRolesInput) (AdminUserOutput, error)
+	ForcePasswordReset(ctx context.Context, username string) (AdminPasswordResetOutput, error)
+}
+
+type TwoFactor interface {
+	Enroll(ctx context.Context, input UserInput) (TwoFactorEnrollOutput, error)
+	QRCode(ctx context.Context, username string) ([]byte, error)
+	Confirm(ctx context.Context, username, code string) (TwoFactorCodesOutput, error)
+	Disable(ctx context.Context, input TwoFactorInput) error
+	RegenerateCodes(ctx context.Context, input TwoFactorInput) (TwoFactorCodesOutput, error)
+	Required(ctx context.Context, username string) (bool, error)
+	Verify(ctx context.Context, username, code string) error
+}
+
+type PersonalToken interface {
+	Create(ctx context.Context, input PersonalTokenInput) (PersonalTokenCreateOutput, error)
+	Find(ctx context.Context, username string) ([]PersonalTokenOutput, error)
+	Delete(ctx context.Context, id int, username string) error
+	Authenticate(ctx context.Context, token string) (PersonalTokenIdentity, error)
+}
+
+type OIDC interface {
+	Providers() []string
+	Begin(ctx context.Context, provider string) (OIDCLoginOutput, error)
+	Complete(ctx context.Context, input OIDCCallbackInput) (string, error)
 }
 
 type Task interface {
 	Create(ctx context.Context, input TaskCreateInput) (TaskOutput, error)
-	Find(ctx context.Context, username string) ([]TaskOutput, error)
+	Find(ctx context.Context, username, sort string) ([]TaskOutput, error)
 	FindById(ctx context.Context, id int, username string) (TaskOutput, error)
 	Update(ctx context.Context, input TaskUpdateInput) (TaskOutput, error)
 	Delete(ctx context.Context, id int, username string) error
+	Move(ctx context.Context, input TaskMoveInput) (TaskOutput, error)
+	AddDependency(ctx context.Context, input TaskDependencyInput) (TaskOutput, error)
+	RemoveDependency(ctx context.Context, input TaskDependencyInput) error
+	CriticalPath(ctx context.Context, id int, username string) ([]TaskOutput, error)
+	PreviewQuick(ctx context.Context, input TaskQuickAddInput) (TaskPreviewOutput, error)
+	CreateQuick(ctx context.Context, input TaskQuickAddInput) (TaskOutput, error)
+}
+
+type Board interface {
+	Create(ctx context.Context, input BoardInput) (BoardOutput, error)
+	Find(ctx context.Context, username string) ([]BoardOutput, error)
+	FindById(ctx context.Context, id int, username string) (BoardViewOutput, error)
+	Update(ctx context.Context, input BoardInput) (BoardOutput, error)
+	Delete(ctx context.Context, id int, username string) error
+	CreateColumn(ctx context.Context, input BoardColumnInput) (BoardColumnOutput, error)
+	UpdateColumn(ctx context.Context, input BoardColumnInput) (BoardColumnOutput, error)
+	DeleteColumn(ctx context.Context, boardId, columnId int, username string) error
+	ReorderColumns(ctx context.Context, input BoardReorderInput) ([]BoardColumnOutput, error)
+	MoveTask(ctx context.Context, input BoardMoveTaskInput) (TaskOutput, error)
+}
+
+type TimeEntry interface {
+	StartTimer(ctx context.Context, taskId int, username string) (TimeEntryOutput, error)
+	StopTimer(ctx context.Context, taskId int, username string) (TimeEntryOutput, error)
+	FindRunning(ctx context.Context, username string) (TimeEntryOutput, error)
+	Create(ctx context.Context, input TimeEntryInput) (TimeEntryOutput, error)
+	FindByTask(ctx context.Context, taskId int, username string) ([]TimeEntryOutput, error)
+	Delete(ctx context.Context, id int, username string) error
+	Report(ctx context.Context, input TimeReportInput) (TimeReportOutput, error)
+}
+
+type Reminder interface {
+	Create(ctx context.Context, input ReminderInput) (ReminderOutput, error)
+	FindByTask(ctx context.Context, taskId int, username string) ([]ReminderOutput, error)
+	Delete(ctx context.Context, id int, username string) error
+	SendDue(ctx context.Context) error
+}
+
+type Digest interface {
+	FindSetting(ctx context.Context, username string) (DigestSettingOutput, error)
+	SaveSetting(ctx context.Context, input DigestSettingInput) (DigestSettingOutput, error)
+	SendDue(ctx context.Context) error
+}
+
+type Export interface {
+	Create(ctx context.Context, username string) (DataExportOutput, error)
+	FindById(ctx context.Context, id int, username string) (DataExportOutput, error)
+	Download(ctx context.Context, id int, expires int64, signature string) ([]byte, error)
+	BuildPending(ctx context.Context) error
 }
 
 type (
 	Services struct {
-		Auth Auth
-		User User
-		Task Task
+		Auth          Auth
+		User          User
+		Admin         Admin
+		SignIn        SignIn
+		TwoFactor     TwoFactor
+		PersonalToken PersonalToken
+		OIDC          OIDC
+		Task          Task
+		Board         Board
+		TimeEntry     TimeEntry
+		Reminder      Reminder
+		Digest        Digest
+		Export        Export
 	}
 	ServicesDependencies struct {
 		Repos    *repo.Repositories
 		Hasher   hasher.Hasher
 		SignKey  string
 		TokenTTL time.Duration
+		// AuthKeys асимметричные ключи подписи токенов доступа, без них токены подписываются SignKey
+		AuthKeys  AuthKeys
+		Notifiers map[string]notifier.Notifier
+		// ExportTTL сколько готовая выгрузка данных доступна для скачивания
+		ExportTTL time.Duration
+		// ExportLinkKey подписывает ссылки на скачивание выгрузок
+		ExportLinkKey string
+		// DeletionGrace через сколько после запроса аккаунт удаляется окончательно
+		DeletionGrace time.Duration
+		// Mailer отправляет письма с токенами подтверждения email и сброса пароля, nil - почта не настроена
+		Mailer mailer.Mailer
+		// MailQueue очередь, через которую письма по запросам пользователей отправляются в фоне
+		MailQueue *worker.Queue
+		// AppURL адрес клиента, на который ведут ссылки из писем (/verify-email, /reset-password)
+		AppURL         string
+		VerifyTokenTTL time.Duration
+		ResetTokenTTL  time.Duration
+		// TOTPIssuer название сервиса в приложении-аутентификаторе
+		TOTPIssuer string
+		// Lockout блокировка входа после неудачных попыток
+		Lockout LockoutPolicy
+		// OIDCProviders провайдеры OpenID Connect по имени, OIDCLoginTTL - сколько действует начатый вход
+		OIDCProviders map[string]OIDCProvider
+		OIDCLoginTTL  time.Duration
+		// UsernameMinLength, UsernameMaxLength ограничения имени пользователя, по ним строятся имена при входе через провайдера
+		UsernameMinLength int
+		UsernameMaxLength int
+		// Metrics бизнес-счетчики, nil - счетчики не регистрируются
+		Metrics *Metrics
 	}
 )
 
 func NewServices(d *ServicesDependencies) *Services {
+	details := newTaskDetails(d.Repos.User, d.Repos.Dependency, d.Repos.TimeEntry)
+	mail := userMailOptions{
+		appURL:    strings.TrimSuffix(d.AppURL, "/"),
+		verifyTTL: d.VerifyTokenTTL,
+		resetTTL:  d.ResetTokenTTL,
+	}
+
+	user := newUserService(d.Repos.User, d.Repos.UserToken, d.Repos.Digest, d.Hasher, d.Mailer, d.MailQueue, mail, d.DeletionGrace)
+	metrics := d.Metrics
+	if metrics == nil {
+		metrics = NewMetrics(nil)
+	}
+
 	return &Services{
-		Auth: newAuthService(d.SignKey, d.TokenTTL),
-		User: newUserService(d.Repos.User, d.Hasher),
-		Task: newTaskService(d.Repos.Task),
+		Auth:          newAuthService(d.Repos.User, d.SignKey, d.TokenTTL, d.AuthKeys),
+		User:          user,
+		Admin:         newAdminService(d.Repos.User, d.Repos.Task, user),
+		SignIn:        newSignInService(d.Repos.SignInAttempt, d.Repos.User, d.Lockout, metrics),
+		TwoFactor:     newTwoFactorService(d.Repos.User, d.Repos.RecoveryCode, d.Hasher, d.TOTPIssuer),
+		PersonalToken: newPersonalTokenService(d.Repos.PersonalToken, d.Repos.User),
+		OIDC:          newOIDCService(d.Repos.User, d.Repos.OIDC, d.Hasher, d.OIDCProviders, d.OIDCLoginTTL, d.UsernameMinLength, d.UsernameMaxLength),
+		Task:          newTaskService(d.Repos.Task, d.Repos.Dependency, d.Repos.User, details, metrics),
+		Board:         newBoardService(d.Repos.Board, d.Repos.Task, details, metrics),
+		TimeEntry:     newTimeEntryService(d.Repos.TimeEntry, d.Repos.Task, d.Repos.User),
+		Reminder:      newReminderService(d.Repos.Reminder, d.Repos.Task, d.Repos.User, d.Notifiers),
+		Digest:        newDigestService(d.Repos.Digest, d.Repos.User, d.Repos.Task, d.Notifiers[dbmodel.ReminderChannelEmail]),
+		Export:        newExportService(d.Repos, details, d.ExportLinkKey, d.ExportTTL),
 	}
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	signInServicePrefixLog = "/service/sign_in"
+)
+
+// Способы входа в истории попыток
+const (
+	SignInMethodPassword  = dbmodel.SignInMethodPassword
+	SignInMethodTwoFactor = dbmodel.SignInMethodTwoFactor
+	SignInMethodOIDC      = dbmodel.SignInMethodOIDC
+)
+
+// LockoutPolicy после MaxAttempts неудачных попыток подряд под одним именем (или IPMaxAttempts с одного IP) вход
+// блокируется на Base от последней неудачи, каждая следующая неудача удваивает блокировку, но не больше Max.
+// Неудачи учитываются за Window, история попыток хранится HistoryTTL
+type LockoutPolicy struct {
+	MaxAttempts   int
+	IPMaxAttempts int
+	Base          time.Duration
+	Max           time.Duration
+	Window        time.Duration
+	HistoryTTL    time.Duration
+}
+
+type signInService struct {
+	attempt repo.SignInAttempt
+	user    repo.User
+	policy  LockoutPolicy
+	metrics *Metrics
+}
+
+func newSignInService(attempt repo.SignInAttempt, user repo.User, policy LockoutPolicy, metrics *Metrics) *signInService {
+	return &signInService{
+		attempt: attempt,
+		user:    user,
+		policy:  policy,
+		metrics: metrics,
+	}
+}
+
+// Check резервирует попытку входа input как неудачную и возвращает ее id и сколько осталось ждать до следующей попытки
+// под тем же именем с того же IP. Решение принимается по неудачам, накопленным до резерва, поэтому параллельные попытки
+// не проходят проверку все разом. При блокировке резерв снимается, попытка не учитывается, а id == 0.
+// Исход разрешенной попытки передается в Record, если попытку не нужно учитывать - в Cancel
+func (s *signInService) Check(ctx context.Context, input SignInAttemptInput) (int, time.Duration, error) {
+	attempt := dbmodel.SignInAttempt{
+		Username:  input.Username,
+		IP:        input.IP,
+		UserAgent: input.UserAgent,
+		Method:    input.Method,
+	}
+	now := time.Now()
+	byUser, byIP, err := s.attempt.Reserve(ctx, &attempt, now.Add(-s.policy.Window), now.Add(-s.policy.HistoryTTL))
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Check error reserve attempt: %s", signInServicePrefixLog, err)
+		return 0, 0, err
+	}
+
+	wait := max(s.lockout(byUser, s.policy.MaxAttempts, now), s.lockout(byIP, s.policy.IPMaxAttempts, now))
+	if wait > 0 {
+		s.Cancel(ctx, attempt.Id)
+		return 0, wait, nil
+	}
+	return attempt.Id, 0, nil
+}
+
+// Record сохраняет исход попытки входа. id - попытка, зарезервированная Check, 0 - попытка без проверки блокировки
+// (например, вход через провайдера OpenID Connect), она записывается заново.
+// Ошибка только логируется: из-за нее вход не должен падать
+func (s *signInService) Record(ctx context.Context, id int, input SignInAttemptInput) {
+	s.metrics.signIn(input.Method, input.Success)
+
+	var err error
+	switch {
+	case id == 0:
+		err = s.attempt.Create(ctx, dbmodel.SignInAttempt{
+			Username:  input.Username,
+			IP:        input.IP,
+			UserAgent: input.UserAgent,
+			Method:    input.Method,
+			Success:   input.Success,
+		}, time.Now().Add(-s.policy.HistoryTTL))
+	case input.Success:
+		err = s.attempt.SetSuccess(ctx, id)
+	}
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Record error save attempt: %s", signInServicePrefixLog, err)
+	}
+}
+
+// Cancel снимает резерв попытки, исход которой не учитывается: например, пароль верный, но нужен второй фактор.
+// Ошибка только логируется, неснятый резерв считается неудачей
+func (s *signInService) Cancel(ctx context.Context, id int) {
+	if id == 0 {
+		return
+	}
+	if err := s.attempt.Delete(context.WithoutCancel(ctx), id); err != nil {
+		logger.From(ctx).Errorf("%s/Cancel error delete attempt: %s", signInServicePrefixLog, err)
+	}
+}
+
+// FindByUser последние limit попыток входа под именем пользователя. Попытки до регистрации пользователя не показываются:
+// они могли относиться к прежнему владельцу имени
+func (s *signInService) FindByUser(ctx context.Context, username string, limit int) ([]SignInAttemptOutput, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindByUser error find user: %s", signInServicePrefixLog, err)
+		return nil, err
+	}
+
+	attempts, err := s.attempt.FindByUsername(ctx, username, u.CreatedAt, limit)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindByUser error find attempts: %s", signInServicePrefixLog, err)
+		return nil, err
+	}
+	output := make([]SignInAttemptOutput, 0, len(attempts))
+	for _, a := range attempts {
+		output = append(output, newSignInAttemptOutput(a))
+	}
+	return output, nil
+}
+
+func newSignInAttemptOutput(a dbmodel.SignInAttempt) SignInAttemptOutput {
+	return SignInAttemptOutput{
+		IP:        a.IP,
+		UserAgent: a.UserAgent,
+		Method:    a.Method,
+		Success:   a.Success,
+		CreatedAt: a.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// lockout сколько еще действует блокировка после failures неудач при пороге threshold
+func (s *signInService) lockout(failures dbmodel.SignInFailures, threshold int, now time.Time) time.Duration {
+	if threshold <= 0 || failures.Count < threshold || failures.LastAt == nil {
+		return 0
+	}
+	d := s.policy.Base
+	for i := threshold; i < failures.Count && d < s.policy.Max; i++ {
+		d *= 2
+	}
+	return max(failures.LastAt.Add(min(d, s.policy.Max)).Sub(now), 0)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/mailer"
+	"todolist_api/pkg/notifier"
+)
+
+// Заглушки репозиториев для тестов сервисов. Встроенный интерфейс остается nil: вызов метода, который заглушка
+// не переопределяет, роняет тест
+
+// stubPersonalTokenRepo хранит токены по хешу. Истекшие и отозванные токены репозиторий не находит, как и настоящий
+type stubPersonalTokenRepo struct {
+	repo.PersonalToken
+	tokens map[string]dbmodel.PersonalToken
+}
+
+func (r *stubPersonalTokenRepo) Use(_ context.Context, tokenHash string, _ time.Time) (dbmodel.PersonalToken, error) {
+	t, ok := r.tokens[tokenHash]
+	if !ok {
+		return dbmodel.PersonalToken{}, pgerrs.ErrNotFound
+	}
+	return t, nil
+}
+
+type stubUserRepo struct {
+	repo.User
+	users map[string]dbmodel.User
+}
+
+func (r *stubUserRepo) FindByUsername(_ context.Context, username string) (dbmodel.User, error) {
+	u, ok := r.users[username]
+	if !ok {
+		return dbmodel.User{}, pgerrs.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *stubUserRepo) FindByEmail(_ context.Context, email string) (dbmodel.User, error) {
+	for _, u := range r.users {
+		if u.EmailVerified && strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return dbmodel.User{}, pgerrs.ErrNotFound
+}
+
+func (r *stubUserRepo) Update(_ context.Context, u dbmodel.User) error {
+	r.users[u.Username] = u
+	return nil
+}
+
+type stubUserTokenRepo struct {
+	repo.UserToken
+}
+
+func (r *stubUserTokenRepo) Create(_ context.Context, _ *dbmodel.UserToken) error {
+	return nil
+}
+
+// stubMailer запоминает письма. Письма отправляются в фоне, поэтому доступ под мьютексом
+type stubMailer struct {
+	mu    sync.Mutex
+	mails []mailer.Mail
+}
+
+func (m *stubMailer) Send(_ context.Context, mail mailer.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// sentTo адреса отправленных писем
+func (m *stubMailer) sentTo() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	to := make([]string, 0, len(m.mails))
+	for _, mail := range m.mails {
+		to = append(to, mail.To)
+	}
+	return to
+}
+
+// stubReminderRepo запоминает созданные напоминания и результат отправки
+type stubReminderRepo struct {
+	repo.Reminder
+	created []dbmodel.Reminder
+	sent    []int
+	failed  map[int]string
+}
+
+func (r *stubReminderRepo) Create(_ context.Context, reminder *dbmodel.Reminder) error {
+	r.created = append(r.created, *reminder)
+	return nil
+}
+
+func (r *stubReminderRepo) MarkSent(_ context.Context, id int, _ time.Time) error {
+	r.sent = append(r.sent, id)
+	return nil
+}
+
+func (r *stubReminderRepo) MarkFailed(_ context.Context, id int, lastError string, _ time.Time) error {
+	if r.failed == nil {
+		r.failed = make(map[int]string)
+	}
+	r.failed[id] = lastError
+	return nil
+}
+
+type stubDigestRepo struct {
+	repo.Digest
+	settings map[string]dbmodel.DigestSetting
+}
+
+func (r *stubDigestRepo) SaveSetting(_ context.Context, s *dbmodel.DigestSetting) error {
+	r.settings[s.Username] = *s
+	return nil
+}
+
+// stubNotifier запоминает адреса отправленных сообщений
+type stubNotifier struct {
+	to []string
+}
+
+func (n *stubNotifier) Notify(_ context.Context, msg notifier.Message) error {
+	n.to = append(n.to, msg.To)
+	return nil
+}
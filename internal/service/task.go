@@ -3,123 +3,592 @@ package service
 import (
 	"context"
 	"errors"
-	log "github.com/sirupsen/logrus"
+	"strings"
 	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo"
 	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/lexorank"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/quickadd"
 )
 
 const (
 	taskServicePrefixLog = "/service/task"
+
+	defaultTaskPriority = 4
+	// после перемещений ключи позиций растут, при превышении длины позиции пользователя перебалансируются
+	maxTaskPositionLength = 16
 )
 
 type taskService struct {
-	task repo.Task
+	task       repo.Task
+	dependency repo.Dependency
+	user       repo.User
+	details    *taskDetails
+	metrics    *Metrics
 }
 
-func newTaskService(task repo.Task) *taskService {
-	return &taskService{task: task}
+func newTaskService(task repo.Task, dependency repo.Dependency, user repo.User, details *taskDetails, metrics *Metrics) *taskService {
+	return &taskService{
+		task:       task,
+		dependency: dependency,
+		user:       user,
+		details:    details,
+		metrics:    metrics,
+	}
 }
 
 func (s *taskService) Create(ctx context.Context, input TaskCreateInput) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.Create")
+	defer span.End()
+
+	last, err := s.task.FindLastPosition(ctx, input.Username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Create error find last task position: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	position, err := lexorank.Between(last, "")
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Create error generate task position: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+
 	task := &dbmodel.Task{
-		Username:    input.Username,
-		Title:       input.Title,
-		Description: input.Description,
-		DueDate:     input.DueDate,
+		Username:         input.Username,
+		Title:            input.Title,
+		Description:      input.Description,
+		DueDate:          taskDueDate(input.DueDate, input.AllDay),
+		AllDay:           input.AllDay,
+		Priority:         taskPriority(input.Priority),
+		Position:         position,
+		Status:           taskStatus(input.Status),
+		Tags:             taskTags(input.Tags),
+		EstimatedMinutes: input.EstimatedMinutes,
+		Recurrence:       input.Recurrence,
 	}
-	if err := s.task.Create(ctx, task); err != nil {
+	if err = s.task.Create(ctx, task); err != nil {
 		if errors.Is(err, pgerrs.ErrForeignKey) {
 			return TaskOutput{}, ErrUserNotFound
 		}
-		log.Errorf("%s/Create error create task: %s", taskServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/Create error create task: %s", taskServicePrefixLog, err)
 		return TaskOutput{}, err
 	}
-	return TaskOutput{
-		Id:          task.Id,
-		Title:       task.Title,
-		Description: task.Description,
-		DueDate:     task.DueDate.Format(time.RFC3339),
-		CreatedAt:   task.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   task.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	s.metrics.tasksCreated.Inc()
+	s.rebalanceIfNeeded(ctx, input.Username, position)
+
+	output, err := s.details.outputs(ctx, input.Username, *task)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Create error find task details: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	return output[0], nil
 }
 
-func (s *taskService) Find(ctx context.Context, username string) ([]TaskOutput, error) {
-	tasks, err := s.task.Find(ctx, username)
+func (s *taskService) Find(ctx context.Context, username, sort string) ([]TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.Find")
+	defer span.End()
+
+	tasks, err := s.task.Find(ctx, username, sort)
 	if err != nil {
-		log.Errorf("%s/Find error find user tasks: %s", taskServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/Find error find user tasks: %s", taskServicePrefixLog, err)
 		return nil, err
 	}
 
-	result := make([]TaskOutput, 0)
-	for _, t := range tasks {
-		result = append(result, TaskOutput{
-			Id:          t.Id,
-			Title:       t.Title,
-			Description: t.Description,
-			DueDate:     t.DueDate.Format(time.RFC3339),
-			CreatedAt:   t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
-		})
+	result, err := s.details.outputs(ctx, username, tasks...)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Find error find task details: %s", taskServicePrefixLog, err)
+		return nil, err
 	}
 	return result, nil
 }
 
 func (s *taskService) FindById(ctx context.Context, id int, username string) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.FindById")
+	defer span.End()
+
 	task, err := s.task.FindById(ctx, id, username)
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
 			return TaskOutput{}, ErrTaskNotFound
 		}
-		log.Errorf("%s/FindById error find task by id: %s", taskServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/FindById error find task by id: %s", taskServicePrefixLog, err)
 		return TaskOutput{}, err
 	}
-	return TaskOutput{
-		Id:          id,
-		Title:       task.Title,
-		Description: task.Description,
-		DueDate:     task.DueDate.Format(time.RFC3339),
-		CreatedAt:   task.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   task.UpdatedAt.Format(time.RFC3339),
-	}, nil
+
+	output, err := s.details.outputs(ctx, username, task)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindById error find task details: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	return output[0], nil
 }
 
 func (s *taskService) Update(ctx context.Context, input TaskUpdateInput) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.Update")
+	defer span.End()
+
+	// прежний статус нужен только чтобы посчитать выполнение задачи
+	var before string
+	if input.Status == dbmodel.TaskStatusDone {
+		prev, err := s.task.FindById(ctx, input.Id, input.Username)
+		if err != nil {
+			if errors.Is(err, pgerrs.ErrNotFound) {
+				return TaskOutput{}, ErrTaskNotFound
+			}
+			logger.From(ctx).Errorf("%s/Update error find task by id: %s", taskServicePrefixLog, err)
+			return TaskOutput{}, err
+		}
+		before = prev.Status
+	}
+
 	task := &dbmodel.Task{
-		Id:          input.Id,
-		Username:    input.Username,
-		Title:       input.Title,
-		Description: input.Description,
-		DueDate:     input.DueDate,
+		Id:               input.Id,
+		Username:         input.Username,
+		Title:            input.Title,
+		Description:      input.Description,
+		DueDate:          taskDueDate(input.DueDate, input.AllDay),
+		AllDay:           input.AllDay,
+		Priority:         taskPriority(input.Priority),
+		Status:           input.Status,
+		Tags:             taskTags(input.Tags),
+		EstimatedMinutes: input.EstimatedMinutes,
+		Recurrence:       input.Recurrence,
 	}
 
 	if err := s.task.Update(ctx, task); err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
 			return TaskOutput{}, ErrTaskNotFound
 		}
-		log.Errorf("%s/Update error update user task: %s", taskServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/Update error update user task: %s", taskServicePrefixLog, err)
 		return TaskOutput{}, err
 	}
+	if input.Status == dbmodel.TaskStatusDone {
+		s.metrics.taskCompleted(before, task.Status)
+	}
 
-	return TaskOutput{
-		Id:          task.Id,
-		Title:       task.Title,
-		Description: task.Description,
-		DueDate:     task.DueDate.Format(time.RFC3339),
-		CreatedAt:   task.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   task.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	output, err := s.details.outputs(ctx, input.Username, *task)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Update error find task details: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	return output[0], nil
 }
 
 func (s *taskService) Delete(ctx context.Context, id int, username string) error {
+	ctx, span := tracer.Start(ctx, "taskService.Delete")
+	defer span.End()
+
 	if err := s.task.Delete(ctx, id, username); err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
 			return ErrTaskNotFound
 		}
-		log.Errorf("%s/Delete error delete user task: %s", userServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/Delete error delete user task: %s", userServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// Move ставит задачу перед (или после) другой задачей пользователя.
+// Новая позиция вычисляется между соседями, поэтому обновляется только перемещаемая задача
+func (s *taskService) Move(ctx context.Context, input TaskMoveInput) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.Move")
+	defer span.End()
+
+	if (input.BeforeId == 0) == (input.AfterId == 0) {
+		return TaskOutput{}, ErrTaskMoveTarget
+	}
+	anchorId := input.BeforeId + input.AfterId
+	if anchorId == input.Id {
+		return TaskOutput{}, ErrTaskMoveItself
+	}
+
+	position, err := s.movePosition(ctx, input, anchorId)
+	if errors.Is(err, lexorank.ErrInvalidRange) {
+		// у соседних задач совпали позиции (например, созданы одновременно), перебалансируем и пробуем еще раз
+		if err = s.task.Rebalance(ctx, input.Username); err != nil {
+			logger.From(ctx).Errorf("%s/Move error rebalance task positions: %s", taskServicePrefixLog, err)
+			return TaskOutput{}, err
+		}
+		position, err = s.movePosition(ctx, input, anchorId)
+	}
+	if err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			return TaskOutput{}, err
+		}
+		logger.From(ctx).Errorf("%s/Move error calculate task position: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+
+	if err = s.task.UpdatePosition(ctx, input.Id, input.Username, position); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TaskOutput{}, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/Move error update task position: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	s.rebalanceIfNeeded(ctx, input.Username, position)
+
+	return s.FindById(ctx, input.Id, input.Username)
+}
+
+func (s *taskService) movePosition(ctx context.Context, input TaskMoveInput, anchorId int) (string, error) {
+	anchor, err := s.task.FindById(ctx, anchorId, input.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return "", ErrTaskNotFound
+		}
+		return "", err
+	}
+
+	var prev, next string
+	if input.BeforeId != 0 {
+		next = anchor.Position
+		prev, err = s.task.FindPrevPosition(ctx, input.Username, anchor.Position, input.Id)
+	} else {
+		prev = anchor.Position
+		next, err = s.task.FindNextPosition(ctx, input.Username, anchor.Position, input.Id)
+	}
+	if err != nil {
+		return "", err
+	}
+	return lexorank.Between(prev, next)
+}
+
+// AddDependency помечает задачу TaskId заблокированной задачей BlockedById. Циклы зависимостей запрещены
+func (s *taskService) AddDependency(ctx context.Context, input TaskDependencyInput) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.AddDependency")
+	defer span.End()
+
+	if input.TaskId == input.BlockedById {
+		return TaskOutput{}, ErrDependencyItself
+	}
+
+	if err := s.dependency.Create(ctx, input.TaskId, input.BlockedById, input.Username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TaskOutput{}, ErrTaskNotFound
+		}
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return TaskOutput{}, ErrDependencyAlreadyExists
+		}
+		if errors.Is(err, pgerrs.ErrCycle) {
+			return TaskOutput{}, ErrDependencyCycle
+		}
+		logger.From(ctx).Errorf("%s/AddDependency error create task dependency: %s", taskServicePrefixLog, err)
+		return TaskOutput{}, err
+	}
+	return s.FindById(ctx, input.TaskId, input.Username)
+}
+
+func (s *taskService) RemoveDependency(ctx context.Context, input TaskDependencyInput) error {
+	ctx, span := tracer.Start(ctx, "taskService.RemoveDependency")
+	defer span.End()
+
+	if err := s.dependency.Delete(ctx, input.TaskId, input.BlockedById, input.Username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrDependencyNotFound
+		}
+		logger.From(ctx).Errorf("%s/RemoveDependency error delete task dependency: %s", taskServicePrefixLog, err)
 		return err
 	}
 	return nil
 }
+
+// CriticalPath возвращает самую длинную цепочку незавершенных задач, блокирующих задачу id,
+// начиная с задачи, которую нужно сделать первой, и заканчивая самой задачей id.
+// Из цепочек одинаковой длины выбирается та, в которой ближайший блокирующий срок позже
+func (s *taskService) CriticalPath(ctx context.Context, id int, username string) ([]TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.CriticalPath")
+	defer span.End()
+
+	task, err := s.task.FindById(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/CriticalPath error find task by id: %s", taskServicePrefixLog, err)
+		return nil, err
+	}
+
+	edges, err := s.dependency.FindBlockers(ctx, id)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/CriticalPath error find task blockers: %s", taskServicePrefixLog, err)
+		return nil, err
+	}
+	blockedBy := make(map[int][]int)
+	ids := make([]int, 0, len(edges))
+	for _, e := range edges {
+		blockedBy[e.TaskId] = append(blockedBy[e.TaskId], e.BlockedById)
+		ids = append(ids, e.BlockedById)
+	}
+
+	blockers, err := s.task.FindByIds(ctx, ids, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/CriticalPath error find blocker tasks: %s", taskServicePrefixLog, err)
+		return nil, err
+	}
+	tasks := map[int]dbmodel.Task{id: task}
+	for _, t := range blockers {
+		tasks[t.Id] = t
+	}
+
+	var (
+		paths    = make(map[int][]int)
+		visiting = make(map[int]bool)
+		longest  func(id int) []int
+	)
+	longest = func(id int) []int {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		visiting[id] = true
+
+		var best []int
+		for _, b := range blockedBy[id] {
+			blocker, ok := tasks[b]
+			if !ok || blocker.Status == dbmodel.TaskStatusDone || visiting[b] {
+				continue
+			}
+			path := longest(b)
+			if len(path) > len(best) ||
+				len(path) == len(best) && blocker.DueDate.After(tasks[best[len(best)-1]].DueDate) {
+				best = path
+			}
+		}
+
+		visiting[id] = false
+		paths[id] = append(append(make([]int, 0, len(best)+1), best...), id)
+		return paths[id]
+	}
+
+	path := make([]dbmodel.Task, 0)
+	for _, taskId := range longest(id) {
+		path = append(path, tasks[taskId])
+	}
+	result, err := s.details.outputs(ctx, username, path...)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/CriticalPath error find task details: %s", taskServicePrefixLog, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// PreviewQuick разбирает быструю запись задачи (см. pkg/quickadd) в часовом поясе пользователя, не создавая задачу
+func (s *taskService) PreviewQuick(ctx context.Context, input TaskQuickAddInput) (TaskPreviewOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.PreviewQuick")
+	defer span.End()
+
+	parsed, err := s.parseQuick(ctx, input)
+	if err != nil {
+		return TaskPreviewOutput{}, err
+	}
+
+	output := TaskPreviewOutput{
+		Title:      parsed.Title,
+		AllDay:     parsed.AllDay,
+		Priority:   taskPriority(parsed.Priority),
+		Tags:       taskTags(parsed.Tags),
+		Recurrence: parsed.Recurrence,
+	}
+	if parsed.DueDate != nil {
+		output.DueDate = parsed.DueDate.Format(time.RFC3339)
+		if parsed.AllDay {
+			output.DueDate = parsed.DueDate.Format(time.DateOnly)
+		}
+	}
+	return output, nil
+}
+
+// CreateQuick создает задачу из быстрой записи. Срок обязателен, как и при обычном создании
+func (s *taskService) CreateQuick(ctx context.Context, input TaskQuickAddInput) (TaskOutput, error) {
+	ctx, span := tracer.Start(ctx, "taskService.CreateQuick")
+	defer span.End()
+
+	parsed, err := s.parseQuick(ctx, input)
+	if err != nil {
+		return TaskOutput{}, err
+	}
+	if parsed.DueDate == nil {
+		return TaskOutput{}, ErrQuickAddDueDate
+	}
+
+	return s.Create(ctx, TaskCreateInput{
+		Username:   input.Username,
+		Title:      parsed.Title,
+		DueDate:    *parsed.DueDate,
+		AllDay:     parsed.AllDay,
+		Priority:   parsed.Priority,
+		Tags:       parsed.Tags,
+		Recurrence: parsed.Recurrence,
+	})
+}
+
+func (s *taskService) parseQuick(ctx context.Context, input TaskQuickAddInput) (quickadd.Result, error) {
+	loc, err := userLocation(ctx, s.user, input.Username)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return quickadd.Result{}, err
+		}
+		logger.From(ctx).Errorf("%s/parseQuick error find user timezone: %s", taskServicePrefixLog, err)
+		return quickadd.Result{}, err
+	}
+
+	parsed, err := quickadd.Parse(input.Text, time.Now().In(loc))
+	if err != nil {
+		return quickadd.Result{}, ErrQuickAddTitle
+	}
+	return parsed, nil
+}
+
+func (s *taskService) rebalanceIfNeeded(ctx context.Context, username, position string) {
+	if len(position) <= maxTaskPositionLength {
+		return
+	}
+	// позиция уже сохранена и корректна, поэтому ошибку перебалансировки только логируем
+	if err := s.task.Rebalance(ctx, username); err != nil {
+		logger.From(ctx).Errorf("%s/rebalanceIfNeeded error rebalance task positions: %s", taskServicePrefixLog, err)
+	}
+}
+
+func taskPriority(priority int) int {
+	if priority == 0 {
+		return defaultTaskPriority
+	}
+	return priority
+}
+
+func taskStatus(status string) string {
+	if status == "" {
+		return dbmodel.TaskStatusTodo
+	}
+	return status
+}
+
+// taskDueDate у задачи на весь день оставляет только дату (в том поясе, в котором она пришла) и хранит ее как полночь UTC
+func taskDueDate(dueDate time.Time, allDay bool) time.Time {
+	if !allDay {
+		return dueDate
+	}
+	return time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// taskDeadline момент, к которому задача должна быть выполнена, в часовом поясе loc.
+// Задача на весь день должна быть выполнена до конца своего дня по местному времени
+func taskDeadline(t dbmodel.Task, loc *time.Location) time.Time {
+	if !t.AllDay {
+		return t.DueDate
+	}
+	return time.Date(t.DueDate.Year(), t.DueDate.Month(), t.DueDate.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// taskTags приводит теги к нижнему регистру и убирает пустые и повторяющиеся
+func taskTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// taskDetails дополняет задачи данными из связанных таблиц: зависимостями и отслеженным временем
+type taskDetails struct {
+	user       repo.User
+	dependency repo.Dependency
+	timeEntry  repo.TimeEntry
+}
+
+func newTaskDetails(user repo.User, dependency repo.Dependency, timeEntry repo.TimeEntry) *taskDetails {
+	return &taskDetails{
+		user:       user,
+		dependency: dependency,
+		timeEntry:  timeEntry,
+	}
+}
+
+// outputs возвращает задачи с датами в часовом поясе пользователя username и заполненными деталями (см. fill)
+func (d *taskDetails) outputs(ctx context.Context, username string, tasks ...dbmodel.Task) ([]TaskOutput, error) {
+	result := make([]TaskOutput, 0, len(tasks))
+	if len(tasks) == 0 {
+		return result, nil
+	}
+	loc, err := userLocation(ctx, d.user, username)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		result = append(result, newTaskOutput(t, loc))
+	}
+	if err = d.fill(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fill заполняет у задач списки blocked (кем задача заблокирована), blocking (кого она блокирует)
+// и суммарное отслеженное по задаче время
+func (d *taskDetails) fill(ctx context.Context, tasks []TaskOutput) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(tasks))
+	ids := make([]int, 0, len(tasks))
+	for i, t := range tasks {
+		index[t.Id] = i
+		ids = append(ids, t.Id)
+	}
+
+	edges, err := d.dependency.FindByTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, e := range edges {
+		if i, ok := index[e.TaskId]; ok {
+			tasks[i].Blocked = append(tasks[i].Blocked, e.BlockedById)
+		}
+		if i, ok := index[e.BlockedById]; ok {
+			tasks[i].Blocking = append(tasks[i].Blocking, e.TaskId)
+		}
+	}
+
+	tracked, err := d.timeEntry.SumByTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for taskId, duration := range tracked {
+		tasks[index[taskId]].TrackedMinutes = int(duration / time.Minute)
+	}
+	return nil
+}
+
+func newTaskOutput(t dbmodel.Task, loc *time.Location) TaskOutput {
+	dueDate := t.DueDate.In(loc).Format(time.RFC3339)
+	if t.AllDay {
+		dueDate = t.DueDate.Format(time.DateOnly)
+	}
+	return TaskOutput{
+		Id:               t.Id,
+		Title:            t.Title,
+		Description:      t.Description,
+		DueDate:          dueDate,
+		AllDay:           t.AllDay,
+		Priority:         t.Priority,
+		Position:         t.Position,
+		Status:           t.Status,
+		Tags:             t.Tags,
+		Blocked:          make([]int, 0),
+		Blocking:         make([]int, 0),
+		CreatedAt:        t.CreatedAt.In(loc).Format(time.RFC3339),
+		UpdatedAt:        t.UpdatedAt.In(loc).Format(time.RFC3339),
+		EstimatedMinutes: t.EstimatedMinutes,
+		Recurrence:       t.Recurrence,
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/logger"
+)
+
+const (
+	timeEntryServicePrefixLog = "/service/time_entry"
+)
+
+type timeEntryService struct {
+	timeEntry repo.TimeEntry
+	task      repo.Task
+	user      repo.User
+}
+
+func newTimeEntryService(timeEntry repo.TimeEntry, task repo.Task, user repo.User) *timeEntryService {
+	return &timeEntryService{
+		timeEntry: timeEntry,
+		task:      task,
+		user:      user,
+	}
+}
+
+// StartTimer запускает таймер по задаче. У пользователя одновременно может быть запущен только один таймер
+func (s *timeEntryService) StartTimer(ctx context.Context, taskId int, username string) (TimeEntryOutput, error) {
+	entry := &dbmodel.TimeEntry{
+		TaskId:    taskId,
+		Username:  username,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := s.timeEntry.Create(ctx, entry); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TimeEntryOutput{}, ErrTaskNotFound
+		}
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return TimeEntryOutput{}, ErrTimerAlreadyRunning
+		}
+		logger.From(ctx).Errorf("%s/StartTimer error create time entry: %s", timeEntryServicePrefixLog, err)
+		return TimeEntryOutput{}, err
+	}
+	return newTimeEntryOutput(*entry), nil
+}
+
+func (s *timeEntryService) StopTimer(ctx context.Context, taskId int, username string) (TimeEntryOutput, error) {
+	entry, err := s.timeEntry.Stop(ctx, taskId, username, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TimeEntryOutput{}, ErrTimerNotRunning
+		}
+		logger.From(ctx).Errorf("%s/StopTimer error stop time entry: %s", timeEntryServicePrefixLog, err)
+		return TimeEntryOutput{}, err
+	}
+	return newTimeEntryOutput(entry), nil
+}
+
+func (s *timeEntryService) FindRunning(ctx context.Context, username string) (TimeEntryOutput, error) {
+	entry, err := s.timeEntry.FindRunning(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TimeEntryOutput{}, ErrTimerNotRunning
+		}
+		logger.From(ctx).Errorf("%s/FindRunning error find running time entry: %s", timeEntryServicePrefixLog, err)
+		return TimeEntryOutput{}, err
+	}
+	return newTimeEntryOutput(entry), nil
+}
+
+func (s *timeEntryService) Create(ctx context.Context, input TimeEntryInput) (TimeEntryOutput, error) {
+	if input.EndedAt.Before(input.StartedAt) {
+		return TimeEntryOutput{}, ErrTimeEntryInvalidRange
+	}
+	endedAt := input.EndedAt.UTC()
+
+	entry := &dbmodel.TimeEntry{
+		TaskId:    input.TaskId,
+		Username:  input.Username,
+		StartedAt: input.StartedAt.UTC(),
+		EndedAt:   &endedAt,
+		Note:      input.Note,
+	}
+	if err := s.timeEntry.Create(ctx, entry); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TimeEntryOutput{}, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/Create error create time entry: %s", timeEntryServicePrefixLog, err)
+		return TimeEntryOutput{}, err
+	}
+	return newTimeEntryOutput(*entry), nil
+}
+
+func (s *timeEntryService) FindByTask(ctx context.Context, taskId int, username string) ([]TimeEntryOutput, error) {
+	if _, err := s.task.FindById(ctx, taskId, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindByTask error find task by id: %s", timeEntryServicePrefixLog, err)
+		return nil, err
+	}
+
+	entries, err := s.timeEntry.FindByTask(ctx, taskId, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/FindByTask error find task time entries: %s", timeEntryServicePrefixLog, err)
+		return nil, err
+	}
+
+	result := make([]TimeEntryOutput, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, newTimeEntryOutput(e))
+	}
+	return result, nil
+}
+
+func (s *timeEntryService) Delete(ctx context.Context, id int, username string) error {
+	if err := s.timeEntry.Delete(ctx, id, username); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrTimeEntryNotFound
+		}
+		logger.From(ctx).Errorf("%s/Delete error delete time entry: %s", timeEntryServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// Report суммирует время пользователя за период с группировкой по дню (по часовому поясу пользователя), проекту (доске) или тегу
+func (s *timeEntryService) Report(ctx context.Context, input TimeReportInput) (TimeReportOutput, error) {
+	if !input.To.After(input.From) {
+		return TimeReportOutput{}, ErrTimeReportRange
+	}
+	groupBy := input.GroupBy
+	if groupBy == "" {
+		groupBy = dbmodel.TimeReportByDay
+	}
+
+	loc, err := userLocation(ctx, s.user, input.Username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Report error find user timezone: %s", timeEntryServicePrefixLog, err)
+		return TimeReportOutput{}, err
+	}
+
+	rows, err := s.timeEntry.Report(ctx, input.Username, input.From.UTC(), input.To.UTC(), groupBy, loc.String())
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Report error build time report: %s", timeEntryServicePrefixLog, err)
+		return TimeReportOutput{}, err
+	}
+
+	result := TimeReportOutput{
+		From:    input.From.Format(time.RFC3339),
+		To:      input.To.Format(time.RFC3339),
+		GroupBy: groupBy,
+		Rows:    make([]TimeReportRowOutput, 0, len(rows)),
+	}
+	var total time.Duration
+	for _, r := range rows {
+		result.Rows = append(result.Rows, TimeReportRowOutput{
+			Key:     r.Key,
+			Minutes: int(r.Duration / time.Minute),
+			Entries: r.Entries,
+		})
+		total += r.Duration
+	}
+	// общий итог считается по сумме длительностей, а не минут групп, чтобы не накапливать ошибку округления
+	result.TotalMinutes = int(total / time.Minute)
+	return result, nil
+}
+
+func newTimeEntryOutput(e dbmodel.TimeEntry) TimeEntryOutput {
+	output := TimeEntryOutput{
+		Id:        e.Id,
+		TaskId:    e.TaskId,
+		StartedAt: e.StartedAt.Format(time.RFC3339),
+		Running:   e.EndedAt == nil,
+		Note:      e.Note,
+	}
+	endedAt := time.Now().UTC()
+	if e.EndedAt != nil {
+		endedAt = *e.EndedAt
+		output.EndedAt = endedAt.Format(time.RFC3339)
+	}
+	output.Minutes = int(endedAt.Sub(e.StartedAt) / time.Minute)
+	return output
+}
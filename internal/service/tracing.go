@@ -0,0 +1,8 @@
+package service
+
+import (
+	"go.opentelemetry.io/otel"
+)
+
+// tracer spans методов сервисов. Провайдер глобальный: пока трассировка не настроена, spans ничего не записывают
+var tracer = otel.Tracer("todolist_api/internal/service")
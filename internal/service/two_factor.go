@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/internal/repo"
+	"todolist_api/internal/repo/pgerrs"
+	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/totp"
+)
+
+const (
+	twoFactorServicePrefixLog = "/service/two_factor"
+
+	// totpSkew сколько соседних шагов принимать, чтобы небольшое расхождение часов телефона не мешало входу
+	totpSkew = 1
+
+	recoveryCodesCount = 10
+	recoveryCodeLength = 10
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+type twoFactorService struct {
+	user     repo.User
+	recovery repo.RecoveryCode
+	hasher   hasher.Hasher
+	issuer   string
+}
+
+// newTwoFactorService issuer - название сервиса, под которым аккаунт показывается в приложении-аутентификаторе
+func newTwoFactorService(user repo.User, recovery repo.RecoveryCode, hasher hasher.Hasher, issuer string) *twoFactorService {
+	return &twoFactorService{
+		user:     user,
+		recovery: recovery,
+		hasher:   hasher,
+		issuer:   issuer,
+	}
+}
+
+// Enroll после проверки пароля создает новый секрет TOTP. Двухфакторная аутентификация включается только после Confirm
+func (s *twoFactorService) Enroll(ctx context.Context, input UserInput) (TwoFactorEnrollOutput, error) {
+	u, err := s.findUser(ctx, input.Username)
+	if err != nil {
+		return TwoFactorEnrollOutput{}, err
+	}
+	if !s.hasher.Verify(input.Password, u.Password) {
+		return TwoFactorEnrollOutput{}, ErrUserWrongPassword
+	}
+	if u.TOTPEnabled {
+		return TwoFactorEnrollOutput{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Enroll error generate secret: %s", twoFactorServicePrefixLog, err)
+		return TwoFactorEnrollOutput{}, err
+	}
+	if err = s.user.SetTOTP(ctx, input.Username, secret, false); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return TwoFactorEnrollOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Enroll error save secret: %s", twoFactorServicePrefixLog, err)
+		return TwoFactorEnrollOutput{}, err
+	}
+	return TwoFactorEnrollOutput{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, input.Username, secret),
+	}, nil
+}
+
+// QRCode возвращает PNG с QR-кодом секрета, подключение которого еще не подтверждено
+func (s *twoFactorService) QRCode(ctx context.Context, username string) ([]byte, error) {
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPSecret == "" || u.TOTPEnabled {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	png, err := totp.QR(totp.URI(s.issuer, username, u.TOTPSecret))
+	if err != nil {
+		logger.From(ctx).Errorf("%s/QRCode error encode qr code: %s", twoFactorServicePrefixLog, err)
+		return nil, err
+	}
+	return png, nil
+}
+
+// Confirm включает двухфакторную аутентификацию, если code совпадает с кодом из приложения, и возвращает коды восстановления
+func (s *twoFactorService) Confirm(ctx context.Context, username, code string) (TwoFactorCodesOutput, error) {
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return TwoFactorCodesOutput{}, err
+	}
+	if u.TOTPSecret == "" || u.TOTPEnabled {
+		return TwoFactorCodesOutput{}, ErrTwoFactorNotPending
+	}
+	step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return TwoFactorCodesOutput{}, ErrTwoFactorCode
+	}
+
+	if err = s.user.SetTOTP(ctx, username, u.TOTPSecret, true); err != nil {
+		logger.From(ctx).Errorf("%s/Confirm error enable totp: %s", twoFactorServicePrefixLog, err)
+		return TwoFactorCodesOutput{}, err
+	}
+	if err = s.user.UseTOTPStep(ctx, username, step); err != nil && !errors.Is(err, pgerrs.ErrNotFound) {
+		logger.From(ctx).Errorf("%s/Confirm error save totp step: %s", twoFactorServicePrefixLog, err)
+		return TwoFactorCodesOutput{}, err
+	}
+	return s.newRecoveryCodes(ctx, username)
+}
+
+// Disable выключает двухфакторную аутентификацию. Требует пароль и код из приложения или код восстановления
+func (s *twoFactorService) Disable(ctx context.Context, input TwoFactorInput) error {
+	if err := s.reauthenticate(ctx, input); err != nil {
+		return err
+	}
+
+	if err := s.user.SetTOTP(ctx, input.Username, "", false); err != nil {
+		logger.From(ctx).Errorf("%s/Disable error disable totp: %s", twoFactorServicePrefixLog, err)
+		return err
+	}
+	if err := s.recovery.Replace(ctx, input.Username, nil); err != nil {
+		logger.From(ctx).Errorf("%s/Disable error delete recovery codes: %s", twoFactorServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// RegenerateCodes заменяет коды восстановления новыми. Требует пароль и код из приложения или код восстановления
+func (s *twoFactorService) RegenerateCodes(ctx context.Context, input TwoFactorInput) (TwoFactorCodesOutput, error) {
+	if err := s.reauthenticate(ctx, input); err != nil {
+		return TwoFactorCodesOutput{}, err
+	}
+	return s.newRecoveryCodes(ctx, input.Username)
+}
+
+// Required сообщает, нужен ли пользователю второй фактор при входе
+func (s *twoFactorService) Required(ctx context.Context, username string) (bool, error) {
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return u.TOTPEnabled, nil
+}
+
+// Verify проверяет второй фактор при входе: код из приложения или неиспользованный код восстановления.
+// Каждый код принимается только один раз
+func (s *twoFactorService) Verify(ctx context.Context, username, code string) error {
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return ErrTwoFactorDisabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), totpSkew); ok {
+		if err = s.user.UseTOTPStep(ctx, username, step); err != nil {
+			if errors.Is(err, pgerrs.ErrNotFound) {
+				return ErrTwoFactorCode
+			}
+			logger.From(ctx).Errorf("%s/Verify error save totp step: %s", twoFactorServicePrefixLog, err)
+			return err
+		}
+		return nil
+	}
+
+	codes, err := s.recovery.FindUnused(ctx, username)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Verify error find recovery codes: %s", twoFactorServicePrefixLog, err)
+		return err
+	}
+	normalized := normalizeRecoveryCode(code)
+	for _, c := range codes {
+		if !s.hasher.Verify(normalized, c.CodeHash) {
+			continue
+		}
+		if err = s.recovery.Use(ctx, c.Id, time.Now().UTC()); err != nil {
+			if errors.Is(err, pgerrs.ErrNotFound) {
+				return ErrTwoFactorCode
+			}
+			logger.From(ctx).Errorf("%s/Verify error use recovery code: %s", twoFactorServicePrefixLog, err)
+			return err
+		}
+		return nil
+	}
+	return ErrTwoFactorCode
+}
+
+// reauthenticate проверяет пароль и второй фактор перед изменением настроек двухфакторной аутентификации
+func (s *twoFactorService) reauthenticate(ctx context.Context, input TwoFactorInput) error {
+	u, err := s.findUser(ctx, input.Username)
+	if err != nil {
+		return err
+	}
+	if !s.hasher.Verify(input.Password, u.Password) {
+		return ErrUserWrongPassword
+	}
+	return s.Verify(ctx, input.Username, input.Code)
+}
+
+func (s *twoFactorService) newRecoveryCodes(ctx context.Context, username string) (TwoFactorCodesOutput, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			logger.From(ctx).Errorf("%s/newRecoveryCodes error generate code: %s", twoFactorServicePrefixLog, err)
+			return TwoFactorCodesOutput{}, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(b)[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, s.hasher.Hash(code))
+	}
+
+	if err := s.recovery.Replace(ctx, username, hashes); err != nil {
+		logger.From(ctx).Errorf("%s/newRecoveryCodes error save codes: %s", twoFactorServicePrefixLog, err)
+		return TwoFactorCodesOutput{}, err
+	}
+	return TwoFactorCodesOutput{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) findUser(ctx context.Context, username string) (dbmodel.User, error) {
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return dbmodel.User{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/findUser error find user: %s", twoFactorServicePrefixLog, err)
+		return dbmodel.User{}, err
+	}
+	return u, nil
+}
+
+// normalizeRecoveryCode коды восстановления показываются как xxxxx-xxxxx, но принимаются без дефиса и в любом регистре
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
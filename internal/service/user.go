@@ -3,11 +3,16 @@ package service
 import (
 	"context"
 	"errors"
-	log "github.com/sirupsen/logrus"
+	"github.com/google/uuid"
+	"strings"
+	"time"
 	"todolist_api/internal/model/dbmodel"
 	"todolist_api/internal/repo"
 	"todolist_api/internal/repo/pgerrs"
 	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/logger"
+	"todolist_api/pkg/mailer"
+	"todolist_api/pkg/worker"
 )
 
 const (
@@ -16,41 +21,359 @@ const (
 
 type userService struct {
 	user   repo.User
+	token  repo.UserToken
+	digest repo.Digest
 	hasher hasher.Hasher
+	mailer mailer.Mailer
+	// mailQueue письма, которые отправляются в фоне
+	mailQueue *worker.Queue
+	mail      userMailOptions
+	grace     time.Duration
+	// dummyPassword хеш, с которым сверяется пароль несуществующего пользователя
+	dummyPassword string
 }
 
-func newUserService(user repo.User, hasher hasher.Hasher) *userService {
+// userMailOptions ссылки в письмах ведут на appURL, verifyTTL и resetTTL - сколько действуют токены
+// подтверждения email и сброса пароля
+type userMailOptions struct {
+	appURL    string
+	verifyTTL time.Duration
+	resetTTL  time.Duration
+}
+
+// newUserService mailer == nil, если отправка почты не настроена: тогда письма с токенами не отправляются.
+// grace - через сколько после запроса аккаунт удаляется окончательно
+func newUserService(user repo.User, token repo.UserToken, digest repo.Digest, hasher hasher.Hasher, mailer mailer.Mailer, mailQueue *worker.Queue, mail userMailOptions, grace time.Duration) *userService {
 	return &userService{
-		user:   user,
-		hasher: hasher,
+		user:      user,
+		token:     token,
+		digest:    digest,
+		hasher:    hasher,
+		mailer:    mailer,
+		mailQueue: mailQueue,
+		mail:      mail,
+		grace:     grace,
+
+		dummyPassword: hasher.Hash(uuid.NewString()),
 	}
 }
 
+// Create регистрирует пользователя. Если email уже подтвердил другой пользователь, аккаунт создается без email,
+// а владельцу email уходит письмо о попытке регистрации: ответ не выдает, есть ли аккаунт с таким email.
+// Неподтвержденный чужой email регистрации не мешает. Письма отправляются в фоне, чтобы время ответа
+// тоже не зависело от того, занят ли email
 func (s *userService) Create(ctx context.Context, input UserInput) error {
-	err := s.user.Create(ctx, dbmodel.User{
+	ctx, span := tracer.Start(ctx, "userService.Create")
+	defer span.End()
+
+	emailTaken, err := s.emailTaken(ctx, input.Username, input.Email)
+	if err != nil {
+		logger.From(ctx).Errorf("%s/Create error find user by email: %s", userServicePrefixLog, err)
+		return err
+	}
+	u := dbmodel.User{
 		Username: input.Username,
 		Password: s.hasher.Hash(input.Password),
-	})
+		Email:    input.Email,
+	}
+	if emailTaken {
+		u.Email = ""
+	}
 
-	if err != nil {
+	if err = s.user.Create(ctx, u); err != nil {
 		if errors.Is(err, pgerrs.ErrAlreadyExists) {
 			return ErrUserAlreadyExists
 		}
-		log.Errorf("%s/Create error create user: %s", userServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/Create error create user: %s", userServicePrefixLog, err)
 		return err
 	}
+	s.sendEmailMail(ctx, input.Username, input.Email, emailTaken)
 	return nil
 }
 
+// VerifyPassword для несуществующего пользователя возвращает false, как для неверного пароля, и так же проверяет
+// пароль (по dummyPassword), чтобы ни ответ, ни время ответа не выдавали, есть ли такой пользователь
 func (s *userService) VerifyPassword(ctx context.Context, input UserInput) (bool, error) {
+	ctx, span := tracer.Start(ctx, "userService.VerifyPassword")
+	defer span.End()
+
 	u, err := s.user.FindByUsername(ctx, input.Username)
 
 	if err != nil {
 		if errors.Is(err, pgerrs.ErrNotFound) {
-			return false, ErrUserNotFound
+			s.hasher.Verify(input.Password, s.dummyPassword)
+			return false, nil
 		}
-		log.Errorf("%s/VerifyPassword error find user: %s", userServicePrefixLog, err)
+		logger.From(ctx).Errorf("%s/VerifyPassword error find user: %s", userServicePrefixLog, err)
 		return false, err
 	}
 	return s.hasher.Verify(input.Password, u.Password), nil
 }
+
+func (s *userService) FindByUsername(ctx context.Context, username string) (UserOutput, error) {
+	ctx, span := tracer.Start(ctx, "userService.FindByUsername")
+	defer span.End()
+
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return UserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/FindByUsername error find user: %s", userServicePrefixLog, err)
+		return UserOutput{}, err
+	}
+	return newUserOutput(u), nil
+}
+
+// Update меняет профиль пользователя, не переданные (nil) поля остаются прежними. Email, который уже подтвердил
+// другой пользователь, не сохраняется, а его владельцу уходит письмо, как при регистрации. Ответ при этом такой же,
+// как при смене email на свободный, чтобы по нему нельзя было перебирать зарегистрированные адреса
+func (s *userService) Update(ctx context.Context, input UserUpdateInput) (UserOutput, error) {
+	ctx, span := tracer.Start(ctx, "userService.Update")
+	defer span.End()
+
+	u, err := s.user.FindByUsername(ctx, input.Username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return UserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Update error find user: %s", userServicePrefixLog, err)
+		return UserOutput{}, err
+	}
+
+	if input.DisplayName != nil {
+		u.DisplayName = *input.DisplayName
+	}
+	emailChanged, emailTaken, previousEmail := false, false, u.Email
+	if input.Email != nil {
+		emailChanged = !strings.EqualFold(u.Email, *input.Email)
+		u.Email = *input.Email
+	}
+	if emailChanged {
+		if emailTaken, err = s.emailTaken(ctx, u.Username, u.Email); err != nil {
+			logger.From(ctx).Errorf("%s/Update error find user by email: %s", userServicePrefixLog, err)
+			return UserOutput{}, err
+		}
+	}
+	var loc *time.Location
+	if input.Timezone != nil && *input.Timezone != u.Timezone {
+		// пустое название и Local LoadLocation принимает, но это не пояс пользователя
+		if *input.Timezone == "" || *input.Timezone == "Local" {
+			return UserOutput{}, ErrUserTimezone
+		}
+		if loc, err = time.LoadLocation(*input.Timezone); err != nil {
+			return UserOutput{}, ErrUserTimezone
+		}
+		u.Timezone = *input.Timezone
+	}
+	if input.Preferences != nil {
+		u.Preferences = input.Preferences
+	}
+
+	saved := u
+	if emailTaken {
+		saved.Email = previousEmail
+	}
+	if err = s.user.Update(ctx, saved); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return UserOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Update error update user: %s", userServicePrefixLog, err)
+		return UserOutput{}, err
+	}
+	if loc != nil {
+		// сводка считается в поясе профиля. Ошибка только логируется: профиль уже сохранен, а расписание
+		// поправится после ближайшей отправки
+		if err = rescheduleDigest(ctx, s.digest, u.Username, loc); err != nil {
+			logger.From(ctx).Errorf("%s/Update error reschedule digest: %s", userServicePrefixLog, err)
+		}
+	}
+	if emailChanged {
+		u.EmailVerified = false
+		s.sendEmailMail(ctx, u.Username, u.Email, emailTaken)
+	}
+	return newUserOutput(u), nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Выданные ранее токены перестают действовать, а персональные
+// токены удаляются, поэтому текущей сессии нужно получить новый токен
+func (s *userService) ChangePassword(ctx context.Context, input UserPasswordInput) error {
+	ctx, span := tracer.Start(ctx, "userService.ChangePassword")
+	defer span.End()
+
+	if err := s.checkPassword(ctx, input.Username, input.Password); err != nil {
+		return err
+	}
+
+	err := s.user.UpdatePassword(ctx, input.Username, s.hasher.Hash(input.NewPassword), sessionsValidAfter())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/ChangePassword error update password: %s", userServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// ChangeUsername переименовывает пользователя после проверки пароля. Токены со старым именем перестают действовать
+func (s *userService) ChangeUsername(ctx context.Context, input UserRenameInput) error {
+	ctx, span := tracer.Start(ctx, "userService.ChangeUsername")
+	defer span.End()
+
+	if err := s.checkPassword(ctx, input.Username, input.Password); err != nil {
+		return err
+	}
+
+	err := s.user.UpdateUsername(ctx, input.Username, input.NewUsername, sessionsValidAfter())
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrAlreadyExists) {
+			return ErrUserAlreadyExists
+		}
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/ChangeUsername error update username: %s", userServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// Delete после проверки пароля назначает удаление аккаунта через grace. До этого момента удаление можно отменить,
+// затем аккаунт удаляется вместе со всеми задачами, досками и прочими данными пользователя (см. EraseScheduled)
+func (s *userService) Delete(ctx context.Context, input UserInput) (UserDeletionOutput, error) {
+	ctx, span := tracer.Start(ctx, "userService.Delete")
+	defer span.End()
+
+	if err := s.checkPassword(ctx, input.Username, input.Password); err != nil {
+		return UserDeletionOutput{}, err
+	}
+
+	deleteAt := time.Now().UTC().Add(s.grace)
+	if err := s.user.ScheduleDeletion(ctx, input.Username, &deleteAt); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return UserDeletionOutput{}, ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/Delete error schedule deletion: %s", userServicePrefixLog, err)
+		return UserDeletionOutput{}, err
+	}
+	return UserDeletionOutput{DeleteAt: deleteAt.Format(time.RFC3339)}, nil
+}
+
+func (s *userService) CancelDeletion(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx, "userService.CancelDeletion")
+	defer span.End()
+
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/CancelDeletion error find user: %s", userServicePrefixLog, err)
+		return err
+	}
+	if u.DeleteAt == nil {
+		return ErrUserNotScheduled
+	}
+
+	if err = s.user.ScheduleDeletion(ctx, username, nil); err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		logger.From(ctx).Errorf("%s/CancelDeletion error cancel deletion: %s", userServicePrefixLog, err)
+		return err
+	}
+	return nil
+}
+
+// EraseScheduled удаляет аккаунты, срок удаления которых наступил. Вызывается воркером периодически
+func (s *userService) EraseScheduled(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "userService.EraseScheduled")
+	defer span.End()
+
+	usernames, err := s.user.DeleteScheduled(ctx, time.Now().UTC())
+	if err != nil {
+		logger.From(ctx).Errorf("%s/EraseScheduled error delete users: %s", userServicePrefixLog, err)
+		return err
+	}
+	if len(usernames) > 0 {
+		logger.From(ctx).Infof("%s/EraseScheduled erased %d users", userServicePrefixLog, len(usernames))
+	}
+	return nil
+}
+
+// VerifySession проверяет, что токен, выданный в issuedAt, не отозван сменой пароля, имени или ролей
+// и его владелец все еще существует. Возвращает ErrUserDisabled, если аккаунт отключен
+func (s *userService) VerifySession(ctx context.Context, username string, issuedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "userService.VerifySession")
+	defer span.End()
+
+	u, err := s.user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return ErrSessionExpired
+		}
+		logger.From(ctx).Errorf("%s/VerifySession error find user: %s", userServicePrefixLog, err)
+		return err
+	}
+	if u.DisabledAt != nil {
+		return ErrUserDisabled
+	}
+	if issuedAt.Before(u.SessionsValidAfter) {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+func (s *userService) checkPassword(ctx context.Context, username, password string) error {
+	ok, err := s.VerifyPassword(ctx, UserInput{Username: username, Password: password})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserWrongPassword
+	}
+	return nil
+}
+
+// sessionsValidAfter момент, с которого токены снова действительны. iat токена хранится с точностью до секунды,
+// поэтому время округляется вниз, чтобы токен, выданный сразу после изменения, уже был действителен
+func sessionsValidAfter() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// userLocation возвращает часовой пояс пользователя, в котором ему показываются и группируются даты
+func userLocation(ctx context.Context, user repo.User, username string) (*time.Location, error) {
+	u, err := user.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgerrs.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		// часовой пояс проверяется при сохранении, сюда попадаем, только если он пропал из базы tzdata
+		logger.From(ctx).Errorf("%s/userLocation error load timezone %s: %s", userServicePrefixLog, u.Timezone, err)
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+func newUserOutput(u dbmodel.User) UserOutput {
+	output := UserOutput{
+		Username:         u.Username,
+		DisplayName:      u.DisplayName,
+		Email:            u.Email,
+		EmailVerified:    u.EmailVerified,
+		TwoFactorEnabled: u.TOTPEnabled,
+		Roles:            u.Roles,
+		Timezone:         u.Timezone,
+		Preferences:      u.Preferences,
+		CreatedAt:        u.CreatedAt.Format(time.RFC3339),
+	}
+	if u.DeleteAt != nil {
+		output.DeleteAt = u.DeleteAt.Format(time.RFC3339)
+	}
+	return output
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"todolist_api/internal/model/dbmodel"
+	"todolist_api/pkg/hasher"
+	"todolist_api/pkg/worker"
+)
+
+// newMailUserService сервис с очередью писем. Очередь останавливается до проверок, чтобы все письма были отправлены
+func newMailUserService(users *stubUserRepo) (*userService, *stubMailer, *worker.Queue) {
+	m := &stubMailer{}
+	queue := worker.NewQueue(10, 1, time.Second)
+	return newUserService(users, &stubUserTokenRepo{}, nil, hasher.NewHasher("secret"), m, queue, userMailOptions{}, 0), m, queue
+}
+
+func TestUserService_Update_EmailTaken(t *testing.T) {
+	users := &stubUserRepo{users: map[string]dbmodel.User{
+		"vasya": {Username: "vasya", Email: "vasya@example.com", EmailVerified: true, Timezone: "UTC"},
+		"petya": {Username: "petya", Email: "petya@example.com", EmailVerified: true, Timezone: "UTC"},
+		"kolya": {Username: "kolya", Email: "shared@example.com", Timezone: "UTC"},
+	}}
+	s, m, queue := newMailUserService(users)
+
+	update := func(username, email string) UserOutput {
+		output, err := s.Update(context.Background(), UserUpdateInput{Username: username, Email: &email})
+		require.NoError(t, err)
+		return output
+	}
+
+	// ответ такой же, как при свободном email, но чужой подтвержденный email не сохраняется
+	taken := update("vasya", "PETYA@example.com")
+	free := update("petya", "petr@example.com")
+	assert.Equal(t, "PETYA@example.com", taken.Email)
+	assert.Equal(t, free.EmailVerified, taken.EmailVerified)
+	assert.Equal(t, "vasya@example.com", users.users["vasya"].Email)
+	assert.Equal(t, "petr@example.com", users.users["petya"].Email)
+
+	// неподтвержденный чужой email не мешает
+	update("vasya", "shared@example.com")
+	assert.Equal(t, "shared@example.com", users.users["vasya"].Email)
+
+	// владельцу занятого email уходит уведомление, на свободные - письма для подтверждения
+	require.NoError(t, queue.Shutdown())
+	assert.ElementsMatch(t, []string{"PETYA@example.com", "petr@example.com", "shared@example.com"}, m.sentTo())
+}
+
+func TestUserService_ForgotPassword(t *testing.T) {
+	users := &stubUserRepo{users: map[string]dbmodel.User{
+		"vasya": {Username: "vasya", Email: "vasya@example.com", EmailVerified: true},
+		"kolya": {Username: "kolya", Email: "kolya@example.com"},
+	}}
+	s, m, queue := newMailUserService(users)
+
+	for _, email := range []string{"vasya@example.com", "kolya@example.com", "unknown@example.com"} {
+		require.NoError(t, s.ForgotPassword(context.Background(), email))
+	}
+
+	// письмо уходит только на подтвержденный email
+	require.NoError(t, queue.Shutdown())
+	assert.Equal(t, []string{"vasya@example.com"}, m.sentTo())
+}
@@ -0,0 +1,27 @@
+alter table digest_setting
+    drop constraint if exists digest_setting_username_fkey,
+    add constraint digest_setting_username_fkey foreign key (username) references public.user (username);
+
+alter table reminder
+    drop constraint if exists reminder_username_fkey,
+    add constraint reminder_username_fkey foreign key (username) references public.user (username);
+
+alter table time_entry
+    drop constraint if exists time_entry_username_fkey,
+    add constraint time_entry_username_fkey foreign key (username) references public.user (username);
+
+alter table board
+    drop constraint if exists board_username_fkey,
+    add constraint board_username_fkey foreign key (username) references public.user (username);
+
+alter table task
+    drop constraint if exists task_username_fkey,
+    add constraint task_username_fkey foreign key (username) references public.user (username);
+
+drop index if exists user_email_idx;
+
+alter table public.user
+    drop column if exists sessions_valid_after,
+    drop column if exists preferences,
+    drop column if exists email,
+    drop column if exists display_name;
@@ -0,0 +1,38 @@
+-- preferences - произвольные настройки клиента, сервером не интерпретируются.
+-- Токены, выданные раньше sessions_valid_after, недействительны: так после смены пароля завершаются остальные сессии
+alter table public.user
+    add column if not exists display_name         varchar     not null default '',
+    add column if not exists email                varchar     not null default '',
+    add column if not exists preferences          jsonb       not null default '{}',
+    add column if not exists sessions_valid_after timestamptz not null default date_trunc('second', now());
+
+update public.user
+set sessions_valid_after = date_trunc('second', created_at);
+
+create unique index if not exists user_email_idx on public.user (lower(email)) where email <> '';
+
+-- смена username каскадно переносится на данные пользователя, удаление пользователя удаляет их
+alter table task
+    drop constraint if exists task_username_fkey,
+    add constraint task_username_fkey foreign key (username) references public.user (username)
+        on update cascade on delete cascade;
+
+alter table board
+    drop constraint if exists board_username_fkey,
+    add constraint board_username_fkey foreign key (username) references public.user (username)
+        on update cascade on delete cascade;
+
+alter table time_entry
+    drop constraint if exists time_entry_username_fkey,
+    add constraint time_entry_username_fkey foreign key (username) references public.user (username)
+        on update cascade on delete cascade;
+
+alter table reminder
+    drop constraint if exists reminder_username_fkey,
+    add constraint reminder_username_fkey foreign key (username) references public.user (username)
+        on update cascade on delete cascade;
+
+alter table digest_setting
+    drop constraint if exists digest_setting_username_fkey,
+    add constraint digest_setting_username_fkey foreign key (username) references public.user (username)
+        on update cascade on delete cascade;
@@ -0,0 +1,6 @@
+drop index if exists user_delete_at_idx;
+
+alter table public.user
+    drop column if exists delete_at;
+
+drop table if exists data_export;
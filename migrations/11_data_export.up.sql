@@ -0,0 +1,24 @@
+-- выгрузка данных пользователя. Архив собирает воркер: pending -> ready (или failed),
+-- locked_until - до какого момента выгрузка захвачена воркером. Готовый архив доступен до expires_at
+create table if not exists data_export
+(
+    id           bigserial primary key,
+    username     varchar     not null references public.user (username) on update cascade on delete cascade,
+    status       varchar     not null default 'pending' check (status in ('pending', 'ready', 'failed')),
+    archive      bytea,
+    last_error   text        not null default '',
+    locked_until timestamptz,
+    expires_at   timestamptz,
+    created_at   timestamptz not null default now()
+);
+
+create index if not exists data_export_pending_idx on data_export (created_at) where status = 'pending';
+-- у пользователя одновременно собирается не больше одной выгрузки
+create unique index if not exists data_export_username_pending_idx on data_export (username) where status = 'pending';
+create index if not exists data_export_username_idx on data_export (username);
+
+-- delete_at - когда аккаунт будет удален, null - удаление не запрошено
+alter table public.user
+    add column if not exists delete_at timestamptz;
+
+create index if not exists user_delete_at_idx on public.user (delete_at) where delete_at is not null;
@@ -0,0 +1,4 @@
+drop table if exists user_token;
+
+alter table public.user
+    drop column if exists email_verified;
@@ -0,0 +1,19 @@
+-- email_verified - подтвержден ли текущий email, при смене email сбрасывается
+alter table public.user
+    add column if not exists email_verified boolean not null default false;
+
+-- одноразовые токены подтверждения email и сброса пароля. Хранится только sha256 от токена,
+-- email - адрес, на который отправлен токен подтверждения (для сброса пароля пустой)
+create table if not exists user_token
+(
+    id         bigserial primary key,
+    username   varchar     not null references public.user (username) on update cascade on delete cascade,
+    purpose    varchar     not null check (purpose in ('verify_email', 'reset_password')),
+    token_hash varchar     not null unique,
+    email      varchar     not null default '',
+    expires_at timestamptz not null,
+    used_at    timestamptz,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists user_token_username_idx on user_token (username, purpose) where used_at is null;
@@ -0,0 +1,6 @@
+drop table if exists recovery_code;
+
+alter table public.user
+    drop column if exists totp_last_step,
+    drop column if exists totp_enabled,
+    drop column if exists totp_secret;
@@ -0,0 +1,18 @@
+-- totp_secret - секрет TOTP, задается при подключении и действует после подтверждения (totp_enabled).
+-- totp_last_step - последний использованный шаг, коды этого и прошлых шагов повторно не принимаются
+alter table public.user
+    add column if not exists totp_secret    varchar not null default '',
+    add column if not exists totp_enabled   boolean not null default false,
+    add column if not exists totp_last_step bigint  not null default 0;
+
+-- одноразовые коды восстановления для входа без приложения-аутентификатора, хранятся хешированными
+create table if not exists recovery_code
+(
+    id         bigserial primary key,
+    username   varchar     not null references public.user (username) on update cascade on delete cascade,
+    code_hash  varchar     not null,
+    used_at    timestamptz,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists recovery_code_username_idx on recovery_code (username) where used_at is null;
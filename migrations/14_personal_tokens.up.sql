@@ -0,0 +1,15 @@
+-- персональные токены доступа для скриптов и интеграций. Хранится только sha256 от токена,
+-- prefix - начало токена, по которому пользователь узнает его в списке
+create table if not exists personal_token
+(
+    id           bigserial primary key,
+    username     varchar     not null references public.user (username) on update cascade on delete cascade,
+    name         varchar     not null,
+    prefix       varchar     not null,
+    token_hash   varchar     not null unique,
+    scopes       text[]      not null default '{}',
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+    created_at   timestamptz not null default now(),
+    unique (username, name)
+);
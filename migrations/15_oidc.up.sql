@@ -0,0 +1,24 @@
+-- незавершенные входы через OpenID Connect: state из ссылки на провайдера, nonce ID токена и секрет PKCE.
+-- Запись удаляется при возврате пользователя, поэтому state нельзя использовать дважды
+create table if not exists oidc_login
+(
+    state         varchar primary key,
+    provider      varchar     not null,
+    nonce         varchar     not null,
+    code_verifier varchar     not null,
+    expires_at    timestamptz not null,
+    created_at    timestamptz not null default now()
+);
+
+-- внешние аккаунты, связанные с пользователем: subject - идентификатор пользователя у провайдера (claim sub)
+create table if not exists user_identity
+(
+    provider   varchar     not null,
+    subject    varchar     not null,
+    username   varchar     not null references public.user (username) on update cascade on delete cascade,
+    email      varchar     not null default '',
+    created_at timestamptz not null default now(),
+    primary key (provider, subject)
+);
+
+create index if not exists user_identity_username_idx on user_identity (username);
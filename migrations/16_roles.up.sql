@@ -0,0 +1,5 @@
+-- roles - роли пользователя, права ролей задаются в приложении.
+-- disabled_at - когда администратор отключил аккаунт, отключенный пользователь не может войти и его токены не принимаются
+alter table public.user
+    add column if not exists roles       text[] not null default '{user}',
+    add column if not exists disabled_at timestamptz;
@@ -0,0 +1,17 @@
+-- попытки входа для блокировки подбора пароля и истории входов. username без внешнего ключа:
+-- неудачные попытки с несуществующими именами тоже учитываются. При удалении аккаунта история удаляется вместе
+-- с пользователем (UserRepo.DeleteScheduled)
+create table if not exists sign_in_attempt
+(
+    id         bigserial primary key,
+    username   varchar     not null,
+    ip         varchar     not null,
+    user_agent varchar     not null default '',
+    method     varchar     not null,
+    success    boolean     not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists sign_in_attempt_username_idx on sign_in_attempt (username, created_at);
+create index if not exists sign_in_attempt_ip_idx on sign_in_attempt (ip, created_at) where not success;
+create index if not exists sign_in_attempt_created_at_idx on sign_in_attempt (created_at);
@@ -0,0 +1,10 @@
+-- корзины токенов ограничения частоты запросов, общие для всех реплик. allowed - решение по последнему запросу
+create table if not exists rate_limit_bucket
+(
+    key        varchar primary key,
+    tokens     double precision not null,
+    allowed    boolean          not null,
+    updated_at timestamptz      not null default now()
+);
+
+create index if not exists rate_limit_bucket_updated_at_idx on rate_limit_bucket (updated_at);
@@ -0,0 +1,7 @@
+alter table digest_setting
+    add column if not exists timezone varchar not null default 'UTC';
+
+update digest_setting d
+set timezone = u.timezone
+from public.user u
+where u.username = d.username;
@@ -0,0 +1,9 @@
+-- часовой пояс сводки теперь берется из профиля, чтобы сроки задач и границы дней считались в одном поясе.
+-- Пояс, заданный для сводки, переносится в профиль
+update public.user u
+set timezone = d.timezone
+from digest_setting d
+where d.username = u.username;
+
+alter table digest_setting
+    drop column if exists timezone;
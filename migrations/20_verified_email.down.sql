@@ -0,0 +1,15 @@
+-- неподтвержденные адреса, которые повторяют чужой email, удаляются, иначе прежний индекс не создать
+update public.user u
+set email = ''
+where not u.email_verified
+  and u.email <> ''
+  and exists (
+    select 1 from public.user o
+    where lower(o.email) = lower(u.email)
+      and o.id <> u.id
+      and (o.email_verified or o.id < u.id)
+  );
+
+drop index if exists user_email_idx;
+
+create unique index if not exists user_email_idx on public.user (lower(email)) where email <> '';
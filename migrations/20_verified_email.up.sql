@@ -0,0 +1,5 @@
+-- email уникален только среди подтвержденных: неподтвержденный адрес, указанный кем угодно, не мешает владельцу
+-- зарегистрироваться с ним и подтвердить его
+drop index if exists user_email_idx;
+
+create unique index if not exists user_email_idx on public.user (lower(email)) where email_verified;
@@ -0,0 +1,5 @@
+drop index if exists task_username_position_idx;
+
+alter table task
+    drop column if exists position,
+    drop column if exists priority;
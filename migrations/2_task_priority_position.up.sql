@@ -0,0 +1,12 @@
+alter table task
+    add column priority smallint not null default 4 check (priority between 1 and 4),
+    add column position varchar collate "C" not null default '';
+
+-- существующие задачи получают позиции в порядке создания
+update task
+set position = ranked.position
+from (select id, lpad(to_hex(row_number() over (partition by username order by id)), 8, '0') || 'i' as position
+      from task) as ranked
+where task.id = ranked.id;
+
+create index if not exists task_username_position_idx on task (username, position);
@@ -0,0 +1,10 @@
+drop index if exists task_column_id_idx;
+
+alter table task
+    drop column if exists column_id;
+
+drop table if exists board_column;
+drop table if exists board;
+
+alter table task
+    drop column if exists status;
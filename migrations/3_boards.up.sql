@@ -0,0 +1,31 @@
+alter table task
+    add column status varchar not null default 'todo' check (status in ('todo', 'in_progress', 'done'));
+
+create table if not exists board
+(
+    id         bigserial primary key,
+    username   varchar references public.user (username),
+    name       varchar   not null,
+    created_at timestamp not null default now()
+);
+
+-- status пустой для произвольных колонок, иначе задачи при перемещении в колонку получают этот статус.
+-- wip_limit = 0 означает отсутствие ограничения
+create table if not exists board_column
+(
+    id         bigserial primary key,
+    board_id   bigint    not null references board (id) on delete cascade,
+    name       varchar   not null,
+    wip_limit  integer   not null default 0 check (wip_limit >= 0),
+    status     varchar   not null default '' check (status in ('', 'todo', 'in_progress', 'done')),
+    position   integer   not null,
+    created_at timestamp not null default now()
+);
+
+create index if not exists board_username_idx on board (username);
+create index if not exists board_column_board_id_idx on board_column (board_id);
+
+alter table task
+    add column column_id bigint references board_column (id) on delete set null;
+
+create index if not exists task_column_id_idx on task (column_id);
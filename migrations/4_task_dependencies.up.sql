@@ -0,0 +1,11 @@
+-- task_id не может начаться, пока не завершена blocked_by_id
+create table if not exists task_dependency
+(
+    task_id       bigint    not null references task (id) on delete cascade,
+    blocked_by_id bigint    not null references task (id) on delete cascade,
+    created_at    timestamp not null default now(),
+    primary key (task_id, blocked_by_id),
+    check (task_id <> blocked_by_id)
+);
+
+create index if not exists task_dependency_blocked_by_id_idx on task_dependency (blocked_by_id);
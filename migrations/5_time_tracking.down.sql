@@ -0,0 +1,7 @@
+drop table if exists time_entry;
+
+drop index if exists task_tags_idx;
+
+alter table task
+    drop column if exists estimated_minutes,
+    drop column if exists tags;
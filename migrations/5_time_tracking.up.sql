@@ -0,0 +1,23 @@
+alter table task
+    add column tags              varchar[] not null default '{}',
+    add column estimated_minutes integer   not null default 0 check (estimated_minutes >= 0);
+
+create index if not exists task_tags_idx on task using gin (tags);
+
+-- ended_at пустой, пока таймер запущен
+create table if not exists time_entry
+(
+    id         bigserial primary key,
+    task_id    bigint    not null references task (id) on delete cascade,
+    username   varchar   not null references public.user (username),
+    started_at timestamp not null,
+    ended_at   timestamp,
+    note       text      not null default '',
+    created_at timestamp not null default now(),
+    check (ended_at is null or ended_at >= started_at)
+);
+
+-- у пользователя может быть запущен только один таймер
+create unique index if not exists time_entry_running_idx on time_entry (username) where ended_at is null;
+create index if not exists time_entry_task_id_idx on time_entry (task_id);
+create index if not exists time_entry_username_started_at_idx on time_entry (username, started_at);
@@ -0,0 +1,5 @@
+drop trigger if exists reschedule_reminders_trigger on task;
+
+drop function if exists reschedule_reminders();
+
+drop table if exists reminder;
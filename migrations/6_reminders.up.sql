@@ -0,0 +1,44 @@
+-- напоминания с offset_minutes заданы относительно due_date задачи, остальные - на абсолютное время remind_at.
+-- locked_until - до какого момента напоминание захвачено воркером (или отложено после неудачной отправки)
+create table if not exists reminder
+(
+    id             bigserial primary key,
+    task_id        bigint    not null references task (id) on delete cascade,
+    username       varchar   not null references public.user (username),
+    remind_at      timestamp not null,
+    offset_minutes integer check (offset_minutes >= 0),
+    channel        varchar   not null check (channel in ('email', 'webhook')),
+    target         varchar   not null,
+    sent_at        timestamp,
+    attempts       integer   not null default 0,
+    locked_until   timestamp,
+    last_error     text      not null default '',
+    created_at     timestamp not null default now()
+);
+
+create index if not exists reminder_pending_idx on reminder (remind_at) where sent_at is null;
+create index if not exists reminder_task_id_idx on reminder (task_id);
+
+-- при изменении срока задачи относительные напоминания переносятся и снова ждут отправки
+create or replace function reschedule_reminders()
+    returns trigger as
+$$
+begin
+    update reminder
+    set remind_at    = new.due_date - make_interval(mins => offset_minutes),
+        sent_at      = null,
+        attempts     = 0,
+        locked_until = null,
+        last_error   = ''
+    where task_id = new.id
+      and offset_minutes is not null;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger reschedule_reminders_trigger
+    after update of due_date
+    on task
+    for each row
+    when (old.due_date is distinct from new.due_date)
+execute function reschedule_reminders();
@@ -0,0 +1,17 @@
+-- настройки сводки по задачам. send_hour и weekday (0 - воскресенье, только для weekly) заданы в часовом поясе timezone,
+-- next_send_at хранится в UTC и пересчитывается после каждой отправки
+create table if not exists digest_setting
+(
+    username     varchar primary key references public.user (username),
+    enabled      boolean   not null default false,
+    email        varchar   not null default '',
+    timezone     varchar   not null default 'UTC',
+    frequency    varchar   not null default 'daily' check (frequency in ('daily', 'weekly')),
+    send_hour    smallint  not null default 8 check (send_hour between 0 and 23),
+    weekday      smallint  not null default 1 check (weekday between 0 and 6),
+    next_send_at timestamp,
+    last_sent_at timestamp,
+    updated_at   timestamp not null default now()
+);
+
+create index if not exists digest_setting_next_send_at_idx on digest_setting (next_send_at) where enabled;
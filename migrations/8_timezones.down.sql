@@ -0,0 +1,34 @@
+alter table digest_setting
+    alter column next_send_at type timestamp using next_send_at at time zone 'UTC',
+    alter column last_sent_at type timestamp using last_sent_at at time zone 'UTC',
+    alter column updated_at type timestamp using updated_at at time zone 'UTC';
+
+alter table reminder
+    alter column remind_at type timestamp using remind_at at time zone 'UTC',
+    alter column sent_at type timestamp using sent_at at time zone 'UTC',
+    alter column locked_until type timestamp using locked_until at time zone 'UTC',
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table time_entry
+    alter column started_at type timestamp using started_at at time zone 'UTC',
+    alter column ended_at type timestamp using ended_at at time zone 'UTC',
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table task_dependency
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table board_column
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table board
+    alter column created_at type timestamp using created_at at time zone 'UTC';
+
+alter table task
+    drop column if exists all_day,
+    alter column due_date type timestamp using due_date at time zone 'UTC',
+    alter column created_at type timestamp using created_at at time zone 'UTC',
+    alter column updated_at type timestamp using updated_at at time zone 'UTC';
+
+alter table public.user
+    drop column if exists timezone,
+    alter column created_at type timestamp using created_at at time zone 'UTC';
@@ -0,0 +1,37 @@
+-- до этой миграции время хранилось в timestamp без часового пояса и записывалось в UTC,
+-- поэтому существующие значения интерпретируются как UTC
+alter table public.user
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    add column if not exists timezone varchar not null default 'UTC';
+
+-- all_day - задача на весь день: в due_date хранится полночь UTC календарной даты, время не учитывается
+alter table task
+    alter column due_date type timestamptz using due_date at time zone 'UTC',
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC',
+    add column if not exists all_day boolean not null default false;
+
+alter table board
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table board_column
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table task_dependency
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table time_entry
+    alter column started_at type timestamptz using started_at at time zone 'UTC',
+    alter column ended_at type timestamptz using ended_at at time zone 'UTC',
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table reminder
+    alter column remind_at type timestamptz using remind_at at time zone 'UTC',
+    alter column sent_at type timestamptz using sent_at at time zone 'UTC',
+    alter column locked_until type timestamptz using locked_until at time zone 'UTC',
+    alter column created_at type timestamptz using created_at at time zone 'UTC';
+
+alter table digest_setting
+    alter column next_send_at type timestamptz using next_send_at at time zone 'UTC',
+    alter column last_sent_at type timestamptz using last_sent_at at time zone 'UTC',
+    alter column updated_at type timestamptz using updated_at at time zone 'UTC';
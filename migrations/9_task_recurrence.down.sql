@@ -0,0 +1,2 @@
+alter table task
+    drop column if exists recurrence;
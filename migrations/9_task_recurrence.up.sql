@@ -0,0 +1,3 @@
+-- правило повторения задачи в формате RRULE (RFC 5545), пустая строка - задача не повторяется
+alter table task
+    add column if not exists recurrence varchar not null default '';
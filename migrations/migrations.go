@@ -0,0 +1,9 @@
+// Package migrations содержит sql миграции схемы базы данных, встроенные в бинарник
+package migrations
+
+import "embed"
+
+// FS файлы миграций в формате golang-migrate: <версия>_<название>.up.sql и <версия>_<название>.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,36 @@
+// Package feature флаги функциональности. Список включенных флагов можно заменить во время работы
+package feature
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+type Flags struct {
+	enabled atomic.Pointer[[]string]
+}
+
+func NewFlags(enabled []string) *Flags {
+	f := &Flags{}
+	f.Set(enabled)
+	return f
+}
+
+// Set атомарно заменяет список включенных флагов
+func (f *Flags) Set(enabled []string) {
+	list := slices.Clone(enabled)
+	slices.Sort(list)
+	list = slices.Compact(list)
+	f.enabled.Store(&list)
+}
+
+// Enabled включен ли флаг name
+func (f *Flags) Enabled(name string) bool {
+	_, ok := slices.BinarySearch(*f.enabled.Load(), name)
+	return ok
+}
+
+// List включенные флаги по алфавиту
+func (f *Flags) List() []string {
+	return slices.Clone(*f.enabled.Load())
+}
@@ -0,0 +1,20 @@
+package feature
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	f := NewFlags([]string{"kanban_v2", "ai_summary", "kanban_v2"})
+	assert.True(t, f.Enabled("kanban_v2"))
+	assert.False(t, f.Enabled("dark_mode"))
+	assert.Equal(t, []string{"ai_summary", "kanban_v2"}, f.List())
+
+	f.Set([]string{"dark_mode"})
+	assert.True(t, f.Enabled("dark_mode"))
+	assert.False(t, f.Enabled("kanban_v2"))
+
+	f.Set(nil)
+	assert.Empty(t, f.List())
+}
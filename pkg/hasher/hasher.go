@@ -2,6 +2,7 @@ package hasher
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"github.com/google/uuid"
@@ -27,9 +28,12 @@ func (h *hasher) Hash(password string) string {
 	return fmt.Sprintf("%x:%s", res, salt)
 }
 
+// Verify сравнивает хеши за постоянное время, чтобы время ответа не подсказывало, насколько пароль близок к верному
 func (h *hasher) Verify(password, hashedPassword string) bool {
-	data := strings.Split(hashedPassword, ":")
-	key, salt := data[0], data[1]
+	key, salt, ok := strings.Cut(hashedPassword, ":")
+	if !ok {
+		return false
+	}
 	res := sha256.Sum256([]byte(salt + h.secret + password))
-	return key == fmt.Sprintf("%x", res)
+	return subtle.ConstantTimeCompare([]byte(key), []byte(fmt.Sprintf("%x", res))) == 1
 }
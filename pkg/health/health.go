@@ -0,0 +1,212 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	defaultTimeout = 2 * time.Second
+	// reportTTL столько переиспользуется результат проверок, чтобы частые запросы готовности не нагружали базу
+	reportTTL = time.Second
+)
+
+// ErrShuttingDown приложение останавливается и не принимает новый трафик
+var ErrShuttingDown = errors.New("application is shutting down")
+
+// Check проверяет компонент, nil - компонент исправен
+type Check func(ctx context.Context) error
+
+// Component состояние одного компонента. Error заполнен, если проверка не прошла
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report общее состояние: ok, только если исправны все компоненты и приложение не останавливается
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+type check struct {
+	run Check
+	// critical неисправность компонента снимает готовность приложения
+	critical bool
+}
+
+// Checker выполняет проверки готовности. Проверки идут параллельно, каждая не дольше timeout,
+// результат переиспользуется reportTTL
+type Checker struct {
+	timeout  time.Duration
+	ttl      time.Duration
+	mu       sync.RWMutex
+	checks   map[string]check
+	draining atomic.Bool
+
+	// runMu не дает одновременным запросам запускать проверки параллельно, второй дождется результата первого
+	runMu     sync.Mutex
+	last      Report
+	checkedAt time.Time
+	failures  string
+	onChange  func(Report)
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{
+		timeout: timeout,
+		ttl:     reportTTL,
+		checks:  make(map[string]check),
+	}
+}
+
+// Add регистрирует проверку компонента name, без которого приложение не готово принимать трафик
+func (c *Checker) Add(name string, run Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check{run: run, critical: true}
+}
+
+// AddNonCritical регистрирует проверку, которая попадает в отчет, но не влияет на общий статус. Так проверяются
+// фоновые воркеры: они одинаковы на всех репликах, и из-за одной долгой задачи балансировщик не должен терять все реплики
+func (c *Checker) AddNonCritical(name string, run Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check{run: run}
+}
+
+// OnChange задает f, которая вызывается, когда меняется общий статус или набор неисправных компонентов.
+// Подходит для записи подробностей в лог: снаружи отдается только статус
+func (c *Checker) OnChange(f func(Report)) {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+	c.onChange = f
+}
+
+// Drain переводит готовность в fail до конца работы приложения, чтобы балансировщик перестал слать трафик
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready проверяет все компоненты. Если последняя проверка была не раньше reportTTL назад, возвращается ее результат
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	if c.checkedAt.IsZero() || time.Since(c.checkedAt) >= c.ttl {
+		// результат достанется и другим запросам, поэтому отключение клиента не должно прерывать проверки
+		c.last = c.run(context.WithoutCancel(ctx))
+		c.checkedAt = time.Now()
+	}
+	report := Report{Status: c.last.Status, Components: make(map[string]Component, len(c.last.Components)+1)}
+	for name, component := range c.last.Components {
+		report.Components[name] = component
+	}
+	if c.draining.Load() {
+		report.Status = StatusFail
+		report.Components["shutdown"] = newComponent(ErrShuttingDown)
+	}
+
+	if failures := report.failures(); failures != c.failures {
+		c.failures = failures
+		if c.onChange != nil {
+			c.onChange(report)
+		}
+	}
+	return report
+}
+
+// Handler отдает подробный отчет Ready в JSON: 200, если приложение готово, иначе 503.
+// В отчете внутренние ошибки компонентов, поэтому его стоит отдавать только на внутреннем порту
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make(map[string]check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(checks)+1)}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			component := newComponent(run(ctx, check.run))
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = component
+			if component.Status != StatusOK && check.critical {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// failures общий статус и неисправные компоненты с ошибками одной строкой, чтобы замечать изменения
+func (r Report) failures() string {
+	items := []string{r.Status}
+	for name, component := range r.Components {
+		if component.Status != StatusOK {
+			items = append(items, name+": "+component.Error)
+		}
+	}
+	sort.Strings(items[1:])
+	return strings.Join(items, "\n")
+}
+
+// run не дает зависшей проверке задержать ответ дольше таймаута, даже если она не следит за контекстом
+func run(ctx context.Context, check Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newComponent(err error) Component {
+	if err != nil {
+		return Component{Status: StatusFail, Error: err.Error()}
+	}
+	return Component{Status: StatusOK}
+}
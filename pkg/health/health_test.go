@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	c := NewChecker(50 * time.Millisecond)
+	c.ttl = 0
+	c.Add("postgres", func(ctx context.Context) error { return nil })
+	assert.Equal(t, Report{
+		Status:     StatusOK,
+		Components: map[string]Component{"postgres": {Status: StatusOK}},
+	}, c.Ready(context.Background()))
+
+	c.Add("worker:reminder", func(ctx context.Context) error { return errors.New("worker is stopped") })
+	// проверка, которая не следит за контекстом, все равно ограничена таймаутом
+	c.Add("migrations", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := c.Ready(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, Component{Status: StatusOK}, report.Components["postgres"])
+	assert.Equal(t, Component{Status: StatusFail, Error: "worker is stopped"}, report.Components["worker:reminder"])
+	assert.Equal(t, Component{Status: StatusFail, Error: context.DeadlineExceeded.Error()}, report.Components["migrations"])
+}
+
+func TestChecker_Drain(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(ctx context.Context) error { return nil })
+	c.Drain()
+
+	report := c.Ready(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, Component{Status: StatusOK}, report.Components["postgres"])
+	assert.Equal(t, Component{Status: StatusFail, Error: ErrShuttingDown.Error()}, report.Components["shutdown"])
+}
+
+func TestChecker_NonCritical(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(ctx context.Context) error { return nil })
+	c.AddNonCritical("worker:digest", func(ctx context.Context) error { return errors.New("job is running for 10m0s") })
+
+	report := c.Ready(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, Component{Status: StatusFail, Error: "job is running for 10m0s"}, report.Components["worker:digest"])
+}
+
+func TestChecker_Cache(t *testing.T) {
+	var calls atomic.Int32
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, StatusOK, c.Ready(context.Background()).Status)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// остановка видна сразу, не дожидаясь новой проверки
+	c.Drain()
+	assert.Equal(t, StatusFail, c.Ready(context.Background()).Status)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestChecker_OnChange(t *testing.T) {
+	var (
+		err     error
+		reports []Report
+	)
+	c := NewChecker(time.Second)
+	c.ttl = 0
+	c.Add("postgres", func(ctx context.Context) error { return err })
+	c.OnChange(func(r Report) { reports = append(reports, r) })
+
+	c.Ready(context.Background())
+	c.Ready(context.Background())
+	err = errors.New("connection refused")
+	c.Ready(context.Background())
+	c.Ready(context.Background())
+	err = nil
+	c.Ready(context.Background())
+
+	if assert.Len(t, reports, 3) {
+		assert.Equal(t, StatusOK, reports[0].Status)
+		assert.Equal(t, Component{Status: StatusFail, Error: "connection refused"}, reports[1].Components["postgres"])
+		assert.Equal(t, StatusOK, reports[2].Status)
+	}
+}
+
+func TestChecker_Handler(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, Component{Status: StatusFail, Error: "connection refused"}, report.Components["postgres"])
+}
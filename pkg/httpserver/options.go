@@ -1,6 +1,9 @@
 package httpserver
 
-import "net"
+import (
+	"net"
+	"time"
+)
 
 type Option func(server *Server)
 
@@ -9,3 +12,21 @@ func Port(port string) Option {
 		s.server.Addr = net.JoinHostPort("", port)
 	}
 }
+
+func ReadTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.server.ReadTimeout = timeout
+	}
+}
+
+func WriteTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.server.WriteTimeout = timeout
+	}
+}
+
+func ShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
@@ -0,0 +1,162 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+	"os"
+)
+
+const minRSABits = 2048
+
+var ErrUnsupportedKey = errors.New("unsupported key: expected RSA or Ed25519 key in PEM")
+
+// Key ключ подписи токенов. ID - thumbprint открытого ключа (RFC 7638), передается в заголовке kid.
+// Private пустой у ключа, который только проверяет подпись
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// JWK открытый ключ в формате RFC 7517
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadFile читает ключ из PEM файла, см. Parse
+func LoadFile(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	key, err := Parse(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// Parse разбирает закрытый ключ (PKCS #8 или PKCS #1 для RSA) или открытый ключ (PKIX) в PEM.
+// RSA ключи подписывают RS256, Ed25519 - EdDSA
+func Parse(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, ErrUnsupportedKey
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, ErrUnsupportedKey
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	return NewKey(parsed)
+}
+
+// NewKey k - *rsa.PrivateKey, ed25519.PrivateKey или их открытые ключи
+func NewKey(k any) (Key, error) {
+	key := Key{}
+	switch v := k.(type) {
+	case *rsa.PrivateKey:
+		key.Private, key.Public = v, &v.PublicKey
+	case ed25519.PrivateKey:
+		key.Private, key.Public = v, v.Public()
+	case *rsa.PublicKey:
+		key.Public = v
+	case ed25519.PublicKey:
+		key.Public = v
+	default:
+		return Key{}, ErrUnsupportedKey
+	}
+
+	switch v := key.Public.(type) {
+	case *rsa.PublicKey:
+		if v.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("rsa key must be at least %d bits", minRSABits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = SigningMethodEdDSA
+	}
+	key.ID = key.JWK().thumbprint()
+	return key, nil
+}
+
+// JWK открытая часть ключа
+func (k Key) JWK() JWK {
+	enc := base64.RawURLEncoding
+	jwk := JWK{Kid: k.ID, Alg: k.Method.Alg(), Use: "sig"}
+	switch v := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(v.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(v.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(v)
+	}
+	return jwk
+}
+
+// thumbprint SHA-256 от обязательных полей ключа в лексикографическом порядке (RFC 7638)
+func (j JWK) thumbprint() string {
+	var members any
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewSet открытые ключи для /.well-known/jwks.json
+func NewSet(keys ...Key) Set {
+	set := Set{Keys: make([]JWK, 0, len(keys))}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, k.JWK())
+	}
+	return set
+}
@@ -0,0 +1,163 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewKey_Thumbprint(t *testing.T) {
+	// ключ из RFC 8037, приложение A.1, thumbprint из A.3
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	require.NoError(t, err)
+
+	key, err := NewKey(ed25519.NewKeyFromSeed(seed))
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", key.ID)
+	assert.Equal(t, "EdDSA", key.Method.Alg())
+	assert.Equal(t, JWK{
+		Kid: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		Kty: "OKP",
+		Alg: "EdDSA",
+		Use: "sig",
+		Crv: "Ed25519",
+		X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}, key.JWK())
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	encode := func(typ string, der []byte, err error) []byte {
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	edPKCS8, err2 := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err2)
+
+	testCases := []struct {
+		testName      string
+		pem           []byte
+		expectAlg     string
+		expectPrivate bool
+		expectErr     bool
+	}{
+		{
+			testName:      "RSA PKCS #8",
+			pem:           encode("PRIVATE KEY", rsaPKCS8, err),
+			expectAlg:     "RS256",
+			expectPrivate: true,
+		},
+		{
+			testName:      "RSA PKCS #1",
+			pem:           encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil),
+			expectAlg:     "RS256",
+			expectPrivate: true,
+		},
+		{
+			testName:  "RSA public key",
+			pem:       encode("PUBLIC KEY", mustMarshalPKIX(t, &rsaKey.PublicKey), nil),
+			expectAlg: "RS256",
+		},
+		{
+			testName:      "Ed25519 private key",
+			pem:           encode("PRIVATE KEY", edPKCS8, nil),
+			expectAlg:     "EdDSA",
+			expectPrivate: true,
+		},
+		{
+			testName:  "Ed25519 public key",
+			pem:       encode("PUBLIC KEY", mustMarshalPKIX(t, edPublic), nil),
+			expectAlg: "EdDSA",
+		},
+		{
+			testName:  "Short RSA key",
+			pem:       encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallRSAKey), nil),
+			expectErr: true,
+		},
+		{
+			testName:  "Not a PEM",
+			pem:       []byte("secret"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		key, err := Parse(tc.pem)
+		assert.Equal(t, tc.expectErr, err != nil, tc.testName)
+		if tc.expectErr {
+			continue
+		}
+		assert.Equal(t, tc.expectAlg, key.Method.Alg(), tc.testName)
+		assert.Equal(t, tc.expectPrivate, key.Private != nil, tc.testName)
+		assert.NotEmpty(t, key.ID, tc.testName)
+	}
+
+	// у закрытого и открытого ключа одной пары один kid
+	private, err := Parse(encode("PRIVATE KEY", edPKCS8, nil))
+	require.NoError(t, err)
+	public, err := Parse(encode("PUBLIC KEY", mustMarshalPKIX(t, edPublic), nil))
+	require.NoError(t, err)
+	assert.Equal(t, private.ID, public.ID)
+}
+
+func TestSigningMethodEdDSA(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := NewKey(private)
+	require.NoError(t, err)
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signed, err := jwt.NewWithClaims(SigningMethodEdDSA, jwt.MapClaims{"sub": "vasya"}).SignedString(key.Private)
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, func(t *jwt.Token) (interface{}, error) {
+		return key.Public, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "vasya", token.Claims.(jwt.MapClaims)["sub"])
+
+	_, err = jwt.Parse(signed, func(t *jwt.Token) (interface{}, error) {
+		return otherPrivate.Public(), nil
+	})
+	assert.Error(t, err)
+}
+
+func TestNewSet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	first, err := NewKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	second, err := NewKey(edPrivate)
+	require.NoError(t, err)
+
+	set := NewSet(first, second)
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "RSA", set.Keys[0].Kty)
+	assert.Equal(t, "AQAB", set.Keys[0].E)
+	assert.Equal(t, first.ID, set.Keys[0].Kid)
+	assert.Equal(t, "OKP", set.Keys[1].Kty)
+	assert.Equal(t, second.ID, set.Keys[1].Kid)
+}
+
+func mustMarshalPKIX(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return der
+}
@@ -0,0 +1,97 @@
+package lexorank
+
+import (
+	"errors"
+	"strings"
+)
+
+// Ключи ранжирования - строки из символов алфавита, которые сравниваются лексикографически
+// (в бд колонка должна иметь collation "C"). Ключ воспринимается как дробь 0.k1k2k3... в 36-ричной системе,
+// поэтому между любыми двумя ключами всегда можно вставить еще один, не трогая остальные.
+// Сгенерированные ключи никогда не заканчиваются на минимальный символ, иначе "a" и "a0" были бы равны как дроби.
+
+const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(alphabet)
+
+var ErrInvalidRange = errors.New("lexorank: prev key must be less than next key")
+
+// Between возвращает ключ строго между prev и next.
+// Пустой prev означает начало списка, пустой next - конец
+func Between(prev, next string) (string, error) {
+	if !valid(prev) || !valid(next) {
+		return "", ErrInvalidRange
+	}
+	if next != "" && prev >= next {
+		return "", ErrInvalidRange
+	}
+
+	var (
+		result    strings.Builder
+		unbounded = next == ""
+	)
+	for i := 0; ; i++ {
+		lo := digit(prev, i)
+		hi := base
+		if !unbounded {
+			hi = digit(next, i)
+		}
+
+		if lo == hi {
+			result.WriteByte(alphabet[lo])
+			continue
+		}
+		if hi-lo > 1 {
+			result.WriteByte(alphabet[(lo+hi)/2])
+			return result.String(), nil
+		}
+		// соседние символы: берем нижний, а дальше верхняя граница уже не ограничивает
+		result.WriteByte(alphabet[lo])
+		unbounded = true
+	}
+}
+
+// Spread возвращает n равномерно распределенных возрастающих ключей минимальной длины.
+// Используется для перебалансировки, когда ключи становятся слишком длинными
+func Spread(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	width, capacity := 1, base
+	for capacity <= n {
+		width++
+		capacity *= base
+	}
+	step := capacity / (n + 1)
+
+	keys := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		keys = append(keys, encode(i*step, width))
+	}
+	return keys
+}
+
+func encode(value, width int) string {
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = alphabet[value%base]
+		value /= base
+	}
+	return strings.TrimRight(string(buf), alphabet[:1])
+}
+
+func digit(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return strings.IndexByte(alphabet, key[i])
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(alphabet, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, alphabet[:1])
+}
@@ -0,0 +1,108 @@
+package lexorank
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		prev      string
+		next      string
+		expect    string
+		expectErr error
+	}{
+		{
+			testName: "Empty list",
+			expect:   "i",
+		},
+		{
+			testName: "Append to the end",
+			prev:     "i",
+			expect:   "r",
+		},
+		{
+			testName: "Insert to the beginning",
+			next:     "i",
+			expect:   "9",
+		},
+		{
+			testName: "Wide gap",
+			prev:     "a",
+			next:     "c",
+			expect:   "b",
+		},
+		{
+			testName: "Adjacent keys",
+			prev:     "a",
+			next:     "b",
+			expect:   "ai",
+		},
+		{
+			testName: "Prev is prefix of next",
+			prev:     "a",
+			next:     "a1",
+			expect:   "a0i",
+		},
+		{
+			testName: "Last key",
+			prev:     "z",
+			expect:   "zi",
+		},
+		{
+			testName:  "Prev greater than next",
+			prev:      "b",
+			next:      "a",
+			expectErr: ErrInvalidRange,
+		},
+		{
+			testName:  "Equal keys",
+			prev:      "a",
+			next:      "a",
+			expectErr: ErrInvalidRange,
+		},
+		{
+			testName:  "Trailing min symbol",
+			prev:      "a0",
+			expectErr: ErrInvalidRange,
+		},
+		{
+			testName:  "Unknown symbol",
+			prev:      "A",
+			expectErr: ErrInvalidRange,
+		},
+	}
+
+	for _, tc := range testCases {
+		key, err := Between(tc.prev, tc.next)
+		assert.Equal(t, tc.expectErr, err, tc.testName)
+		assert.Equal(t, tc.expect, key, tc.testName)
+	}
+}
+
+func TestBetween_Repeated(t *testing.T) {
+	prev, next := "a", "b"
+	for i := 0; i < 100; i++ {
+		key, err := Between(prev, next)
+		assert.Nil(t, err)
+		assert.Less(t, prev, key)
+		assert.Less(t, key, next)
+		next = key
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 35, 36, 1000} {
+		keys := Spread(n)
+		assert.Len(t, keys, n)
+		assert.True(t, sort.StringsAreSorted(keys))
+		for i, k := range keys {
+			assert.True(t, valid(k))
+			if i > 0 {
+				assert.NotEqual(t, keys[i-1], k)
+			}
+		}
+	}
+}
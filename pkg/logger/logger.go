@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fieldsKey struct{}
+
+// With добавляет к контексту поля, которые попадут в каждую запись лога, сделанную через From.
+// Поля с теми же именами заменяются, исходный контекст не меняется
+func With(ctx context.Context, fields logrus.Fields) context.Context {
+	prev, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	merged := make(logrus.Fields, len(prev)+len(fields))
+	for k, v := range prev {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// From запись стандартного логгера logrus с полями из ctx (request_id, user, route) и trace_id, если запрос трассируется
+func From(ctx context.Context) *logrus.Entry {
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	entry := logrus.WithContext(ctx).WithFields(fields)
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		entry = entry.WithField("trace_id", sc.TraceID().String())
+	}
+	return entry
+}
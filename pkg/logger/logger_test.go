@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	ctx := With(context.Background(), logrus.Fields{"request_id": "req-1", "route": "/api/v1/tasks"})
+	userCtx := With(ctx, logrus.Fields{"user": "vasya", "route": "/api/v1/tasks/:id"})
+
+	From(userCtx).Errorf("%s/Create error create task: %s", "/service/task", "boom")
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "/service/task/Create error create task: boom", entry.Message)
+	assert.Equal(t, logrus.Fields{"request_id": "req-1", "user": "vasya", "route": "/api/v1/tasks/:id"}, entry.Data)
+
+	// родительский контекст не меняется
+	From(ctx).Info("access")
+	assert.Equal(t, logrus.Fields{"request_id": "req-1", "route": "/api/v1/tasks"}, hook.LastEntry().Data)
+
+	From(context.Background()).Info("no fields")
+	assert.Empty(t, hook.LastEntry().Data)
+}
+
+func TestFrom_TraceID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	From(ctx).Info("traced")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hook.LastEntry().Data["trace_id"])
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Stdout значение LOG_OUTPUT для вывода в стандартный поток, любое другое - путь к файлу
+const Stdout = "stdout"
+
+// Output вывод логов, который можно переключить на другой файл во время работы
+type Output struct {
+	mu   sync.RWMutex
+	name string
+	w    io.Writer
+	file *os.File
+}
+
+// OpenOutput открывает вывод name: stdout или файл, который дописывается
+func OpenOutput(name string) (*Output, error) {
+	o := &Output{}
+	if err := o.Reopen(name); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.w.Write(p)
+}
+
+// Name текущий вывод
+func (o *Output) Name() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.name
+}
+
+// Reopen переключает вывод на name. Если новый файл не открывается, остается прежний вывод.
+// Reopen с тем же именем открывает файл заново, например после ротации
+func (o *Output) Reopen(name string) error {
+	var (
+		w    io.Writer = os.Stdout
+		file *os.File
+	)
+	if name != Stdout {
+		var err error
+		file, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0755)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	o.mu.Lock()
+	prev := o.file
+	o.name, o.w, o.file = name, w, file
+	o.mu.Unlock()
+
+	if prev != nil {
+		return prev.Close()
+	}
+	return nil
+}
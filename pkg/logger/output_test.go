@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutput_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")
+
+	o, err := OpenOutput(first)
+	require.NoError(t, err)
+	_, err = o.Write([]byte("one\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, o.Reopen(second))
+	assert.Equal(t, second, o.Name())
+	_, err = o.Write([]byte("two\n"))
+	require.NoError(t, err)
+
+	// недоступный файл не ломает текущий вывод
+	assert.Error(t, o.Reopen(filepath.Join(dir, "missing", "third.json")))
+	assert.Equal(t, second, o.Name())
+	_, err = o.Write([]byte("three\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Equal(t, "one\n", string(data))
+	data, err = os.ReadFile(second)
+	require.NoError(t, err)
+	assert.Equal(t, "two\nthree\n", string(data))
+}
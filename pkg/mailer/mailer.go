@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"todolist_api/pkg/notifier"
+)
+
+// Mail письмо получателю To, HTML - необязательная HTML версия текста Body
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+type smtpMailer struct {
+	smtp notifier.Notifier
+}
+
+// NewSMTP отправляет письма через SMTP сервер host:port, параметры те же, что у notifier.NewSMTP
+func NewSMTP(host, port, username, password, from string) Mailer {
+	return &smtpMailer{smtp: notifier.NewSMTP(host, port, username, password, from)}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, mail Mail) error {
+	return m.smtp.Notify(ctx, notifier.Message{
+		To:      mail.To,
+		Subject: mail.Subject,
+		Body:    mail.Body,
+		HTML:    mail.HTML,
+	})
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory хранит отправленные письма в памяти вместо отправки. Используется в тестах
+type Memory struct {
+	mu    sync.Mutex
+	mails []Mail
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, mail Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// Mails возвращает копию отправленных писем в порядке отправки
+func (m *Memory) Mails() []Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Mail(nil), m.mails...)
+}
+
+// Last возвращает последнее письмо получателю to, ok == false, если писем ему не было
+func (m *Memory) Last(to string) (Mail, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.mails) - 1; i >= 0; i-- {
+		if m.mails[i].To == to {
+			return m.mails[i], true
+		}
+	}
+	return Mail{}, false
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMemory_Send(t *testing.T) {
+	m := NewMemory()
+
+	_, ok := m.Last("vasya@example.com")
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Send(context.Background(), Mail{To: "vasya@example.com", Subject: "first"}))
+	assert.Nil(t, m.Send(context.Background(), Mail{To: "petya@example.com", Subject: "second"}))
+	assert.Nil(t, m.Send(context.Background(), Mail{To: "vasya@example.com", Subject: "third"}))
+
+	mail, ok := m.Last("vasya@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "third", mail.Subject)
+	assert.Len(t, m.Mails(), 3)
+
+	// изменение результата Mails не меняет отправленные письма
+	mails := m.Mails()
+	mails[0].Subject = "changed"
+	assert.Equal(t, "first", m.Mails()[0].Subject)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, m.Send(ctx, Mail{To: "vasya@example.com"}))
+	assert.Len(t, m.Mails(), 3)
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_events_total", Help: "Test events"})
+	reg.MustRegister(counter)
+	counter.Add(3)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "test_events_total 3")
+	assert.Contains(t, string(body), "go_goroutines")
+
+	rec = httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPoolCollector(t *testing.T) {
+	// пока пула нет, метрики не отдаются, но сбор не падает
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewPoolCollector(func() *pgxpool.Stat { return nil }))
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, families)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// NewPoolCollector метрики пула соединений pgx. Статистика снимается при каждом сборе метрик.
+// Время ожидания соединения - pgxpool_acquire_duration_seconds_total, число ожиданий - pgxpool_empty_acquires_total
+func NewPoolCollector(stat func() *pgxpool.Stat) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, nil, nil)
+	}
+	return &poolCollector{
+		stat:            stat,
+		acquiredConns:   desc("acquired_conns", "Number of currently acquired connections in the pool"),
+		idleConns:       desc("idle_conns", "Number of currently idle connections in the pool"),
+		totalConns:      desc("total_conns", "Total number of connections currently in the pool"),
+		maxConns:        desc("max_conns", "Maximum size of the pool"),
+		acquires:        desc("acquires_total", "Number of successful connection acquires from the pool"),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent acquiring connections from the pool"),
+		emptyAcquires:   desc("empty_acquires_total", "Number of acquires that had to wait for a connection because the pool was empty"),
+		canceledAcquire: desc("canceled_acquires_total", "Number of acquires canceled by a context"),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquire
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	if s == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
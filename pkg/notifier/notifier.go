@@ -0,0 +1,16 @@
+package notifier
+
+import "context"
+
+// Message уведомление для получателя To: адрес почты для SMTP или URL для вебхука.
+// HTML - необязательная HTML версия текста Body
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSMTPTimeout = 10 * time.Second
+)
+
+type smtpNotifier struct {
+	host     string
+	addr     string
+	from     string
+	username string
+	password string
+	timeout  time.Duration
+}
+
+// NewSMTP отправляет уведомления письмами через SMTP сервер host:port.
+// Авторизация PLAIN используется, только если задан username, STARTTLS - если сервер его поддерживает
+func NewSMTP(host, port, username, password, from string) Notifier {
+	return &smtpNotifier{
+		host:     host,
+		addr:     net.JoinHostPort(host, port),
+		from:     from,
+		username: username,
+		password: password,
+		timeout:  defaultSMTPTimeout,
+	}
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("dial smtp server: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if n.username != "" {
+		if err = c.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err = c.Mail(n.from); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err = c.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err = w.Write(n.message(msg)); err != nil {
+		return fmt.Errorf("smtp write message: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("smtp close message: %w", err)
+	}
+	return c.Quit()
+}
+
+func (n *smtpNotifier) message(msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(msg.Body))
+		return []byte(b.String())
+	}
+
+	// письмо с HTML версией отправляется как multipart/alternative: почтовый клиент сам выбирает, что показать
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: msg.Body},
+		{contentType: "text/html; charset=utf-8", content: msg.HTML},
+	} {
+		pw, _ := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qw := quotedprintable.NewWriter(pw)
+		_, _ = qw.Write([]byte(crlf(part.content)))
+		_ = qw.Close()
+	}
+	_ = w.Close()
+
+	b.WriteString("Content-Type: multipart/alternative; boundary=" + w.Boundary() + "\r\n")
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return []byte(b.String())
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
@@ -0,0 +1,160 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpStandIn минимальный SMTP сервер, который принимает одно письмо и отдает его в канал
+type smtpStandIn struct {
+	listener net.Listener
+	mails    chan smtpMail
+}
+
+type smtpMail struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{listener: l, mails: make(chan smtpMail, 1)}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *smtpStandIn) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *smtpStandIn) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP stand-in")
+
+	var mail smtpMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			mail.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			mail.to = append(mail.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			s.mails <- mail
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifier_Notify(t *testing.T) {
+	server := newSMTPStandIn(t)
+	n := NewSMTP("127.0.0.1", server.port(), "", "", "todo@example.com")
+
+	err := n.Notify(context.Background(), Message{
+		To:      "vasya@example.com",
+		Subject: "Напоминание",
+		Body:    "Task \"Report\" is due\nsoon",
+	})
+	assert.Nil(t, err)
+
+	mail := <-server.mails
+	assert.Equal(t, "todo@example.com", mail.from)
+	assert.Equal(t, []string{"vasya@example.com"}, mail.to)
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(mail.data))).ReadMIMEHeader()
+	assert.Nil(t, err)
+	assert.Equal(t, "vasya@example.com", msg.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Get("Subject"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Напоминание", subject)
+	assert.True(t, strings.HasSuffix(mail.data, "Task \"Report\" is due\nsoon\n"))
+}
+
+func TestSMTPNotifier_NotifyUnavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	_ = l.Close()
+
+	n := NewSMTP("127.0.0.1", port, "", "", "todo@example.com")
+	err = n.Notify(context.Background(), Message{To: "vasya@example.com"})
+	assert.NotNil(t, err)
+}
+
+func TestSMTPNotifier_NotifyHTML(t *testing.T) {
+	server := newSMTPStandIn(t)
+	n := NewSMTP("127.0.0.1", server.port(), "", "", "todo@example.com")
+
+	err := n.Notify(context.Background(), Message{
+		To:      "vasya@example.com",
+		Subject: "Digest",
+		Body:    "1 task is overdue",
+		HTML:    "<p>1 task is <b>overdue</b></p>",
+	})
+	assert.Nil(t, err)
+
+	mail := <-server.mails
+	msg, err := netmail.ReadMessage(strings.NewReader(mail.data))
+	assert.Nil(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var parts []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Type")+": "+string(content))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=utf-8: 1 task is overdue",
+		"text/html; charset=utf-8: <p>1 task is <b>overdue</b></p>",
+	}, parts)
+}
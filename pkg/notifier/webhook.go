@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultWebhookTimeout = 5 * time.Second
+
+	// SignatureHeader подпись тела запроса HMAC-SHA256 секретом вебхука в hex, передается, если секрет задан
+	SignatureHeader = "X-Signature-256"
+)
+
+// ErrForbiddenAddress адрес вебхука во внутренней сети или на самом сервере
+var ErrForbiddenAddress = errors.New("webhook address is not a public ip")
+
+// sharedAddressSpace адреса carrier-grade NAT (RFC 6598), снаружи недоступны так же, как частные сети
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+type webhookNotifier struct {
+	client *http.Client
+	secret string
+}
+
+type webhookPayload struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// NewWebhook отправляет уведомления POST запросом с JSON телом на URL из Message.To.
+// Ответ с кодом не из 2xx считается ошибкой доставки, перенаправления не выполняются.
+// URL задает пользователь, поэтому соединения с адресами, для которых PublicAddr ложно, запрещены. Проверяется адрес,
+// с которым действительно устанавливается соединение, так что смена DNS записи после проверки URL не помогает
+func NewWebhook(secret string, timeout time.Duration) Notifier {
+	return newWebhook(secret, timeout, denyPrivateAddr)
+}
+
+func newWebhook(secret string, timeout time.Duration, control func(network, address string, c syscall.RawConn) error) Notifier {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// через прокси соединение шло бы с адресом прокси, и проверка адреса получателя теряла бы смысл
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &webhookNotifier{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// перенаправлением получатель мог бы отправить запрос на внутренний адрес, ответ 3xx считается ошибкой
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret: secret,
+	}
+}
+
+// PublicAddr ложно для адресов, по которым вебхук мог бы обратиться к самому серверу или внутренней сети:
+// loopback, частные сети, link-local (в том числе 169.254.169.254 метаданных облака), неуказанный адрес и multicast
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// denyPrivateAddr вызывается для каждого соединения уже после разрешения имени, address - ip:port
+func denyPrivateAddr(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(webhookPayload{Subject: msg.Subject, Body: msg.Body, HTML: msg.HTML})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.To, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		secret       string
+		status       int
+		expectErr    bool
+		expectSigned bool
+	}{
+		{
+			testName:  "Correct test",
+			status:    http.StatusNoContent,
+			expectErr: false,
+		},
+		{
+			testName:     "Signed payload",
+			secret:       "foobar",
+			status:       http.StatusOK,
+			expectErr:    false,
+			expectSigned: true,
+		},
+		{
+			testName:  "Receiver error",
+			status:    http.StatusInternalServerError,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		var (
+			payload   webhookPayload
+			signature string
+			body      []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &payload)
+			signature = r.Header.Get(SignatureHeader)
+			w.WriteHeader(tc.status)
+		}))
+
+		// тестовый сервер слушает loopback, поэтому проверка адреса отключена
+		n := newWebhook(tc.secret, 0, nil)
+		err := n.Notify(context.Background(), Message{To: server.URL, Subject: "Reminder", Body: "Task is due"})
+		server.Close()
+
+		assert.Equal(t, tc.expectErr, err != nil, tc.testName)
+		assert.Equal(t, webhookPayload{Subject: "Reminder", Body: "Task is due"}, payload, tc.testName)
+		if tc.expectSigned {
+			mac := hmac.New(sha256.New, []byte(tc.secret))
+			mac.Write(body)
+			assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signature, tc.testName)
+		} else {
+			assert.Empty(t, signature, tc.testName)
+		}
+	}
+}
+
+func TestWebhookNotifier_ForbiddenAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	err := NewWebhook("", 0).Notify(context.Background(), Message{To: server.URL, Subject: "Reminder"})
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.False(t, called)
+}
+
+func TestWebhookNotifier_Redirect(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	err := newWebhook("", 0, nil).Notify(context.Background(), Message{To: server.URL, Subject: "Reminder"})
+	assert.Error(t, err)
+	assert.False(t, redirected)
+}
+
+func TestPublicAddr(t *testing.T) {
+	testCases := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.public, PublicAddr(netip.MustParseAddr(tc.addr)), tc.addr)
+	}
+}
@@ -0,0 +1,339 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	discoveryPath  = "/.well-known/openid-configuration"
+	maxBodySize    = 1 << 20
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrUnknownKey     = errors.New("id token is signed with unknown key")
+	ErrNonceMismatch  = errors.New("id token nonce mismatch")
+)
+
+// Config ClientSecret пустой у публичного клиента, тогда client_id передается в теле запроса токена.
+// Scopes без openid, он добавляется всегда
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims данные пользователя из проверенного ID токена
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider клиент провайдера OpenID Connect для входа по authorization code с PKCE.
+// Документ discovery загружается при первом обращении, ключи JWKS - заново, когда токен подписан неизвестным ключом
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu   sync.RWMutex
+	doc  *discovery
+	keys map[string]any
+}
+
+func NewProvider(cfg Config, opts ...Option) *Provider {
+	p := &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// AuthCodeURL адрес страницы входа провайдера. state и nonce проверяются при возврате пользователя,
+// verifier - секрет PKCE, который передается в Exchange
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.scopes(), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange обменивает code на токены и возвращает данные пользователя из проверенного ID токена
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, fmt.Errorf("create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return Claims{}, err
+	}
+	if status != http.StatusOK {
+		return Claims{}, fmt.Errorf("token endpoint responded %d: %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return Claims{}, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify проверяет подпись ID токена ключом из JWKS, издателя, получателя, срок действия и nonce
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	token, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, doc.JWKSURI, kid)
+	})
+	if err != nil {
+		var vErr *jwt.ValidationError
+		if errors.As(err, &vErr) && errors.Is(vErr.Inner, ErrUnknownKey) {
+			return Claims{}, ErrUnknownKey
+		}
+		return Claims{}, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	if iss, _ := claims["iss"].(string); iss != doc.Issuer {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !audienceContains(claims["aud"], p.cfg.ClientID) {
+		return Claims{}, fmt.Errorf("%w: token is issued for another client", ErrInvalidIDToken)
+	}
+	if _, ok = claims["exp"]; !ok {
+		return Claims{}, fmt.Errorf("%w: token has no exp", ErrInvalidIDToken)
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return Claims{}, ErrNonceMismatch
+	}
+
+	result := Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	result.Name, _ = claims["name"].(string)
+	// некоторые провайдеры отдают email_verified строкой
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = v
+	case string:
+		result.EmailVerified = v == "true"
+	}
+	if result.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: token has no sub", ErrInvalidIDToken)
+	}
+	return result, nil
+}
+
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, s := range p.cfg.Scopes {
+		if s != "" && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// discover загружает документ discovery. Неудачная загрузка не кешируется, чтобы провайдер, недоступный при старте,
+// заработал без перезапуска
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.RLock()
+	doc := p.doc
+	p.mu.RUnlock()
+	if doc != nil {
+		return doc, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.cfg.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery request: %w", err)
+	}
+	doc = &discovery{}
+	status, err := p.doJSON(req, doc)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint responded %d", status)
+	}
+	// издатель из документа должен совпадать с настроенным, иначе ему нельзя доверять проверку токенов
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match configured %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery document misses required endpoints")
+	}
+
+	p.mu.Lock()
+	p.doc = doc
+	p.mu.Unlock()
+	return doc, nil
+}
+
+// key ищет ключ по kid, при промахе один раз перезагружает JWKS: провайдер мог сменить ключи.
+// Токен без kid принимается, только если у провайдера единственный ключ
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (any, error) {
+	p.mu.RLock()
+	k, ok := lookupKey(p.keys, kid)
+	p.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	keys, err := p.fetchKeys(ctx, jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if k, ok = lookupKey(keys, kid); ok {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func lookupKey(keys map[string]any, kid string) (any, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	k, ok := keys[kid]
+	return k, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create jwks request: %w", err)
+	}
+	var set jwkSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint responded %d", status)
+	}
+	return set.publicKeys(), nil
+}
+
+func (p *Provider) doJSON(req *http.Request, v any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return 0, err
+	}
+	if err = json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("decode %s response: %w", req.URL.Path, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func audienceContains(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Challenge code_challenge метода S256 для секрета PKCE verifier (RFC 7636)
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
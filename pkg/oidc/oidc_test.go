@@ -0,0 +1,302 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "todolist"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:8080/auth/oidc/mock/callback"
+)
+
+// mockProvider локальный провайдер OpenID Connect: discovery, JWKS и token endpoint.
+// Код авторизации выдается методом authorize, который заменяет страницу входа
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu     sync.Mutex
+	key    any
+	kid    string
+	method jwt.SigningMethod
+	claims jwt.MapClaims
+	codes  map[string]mockAuthorization
+	// signKid подменяет kid в заголовке выданных токенов
+	signKid string
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	m := &mockProvider{t: t, codes: make(map[string]mockAuthorization)}
+	m.rotateRSA("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) config() Config {
+	return Config{
+		Issuer:       m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"email", "profile"},
+	}
+}
+
+func (m *mockProvider) rotateRSA(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(m.t, err)
+	m.mu.Lock()
+	m.key, m.kid, m.method = key, kid, jwt.SigningMethodRS256
+	m.mu.Unlock()
+}
+
+func (m *mockProvider) rotateEC(kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(m.t, err)
+	m.mu.Lock()
+	m.key, m.kid, m.method = key, kid, jwt.SigningMethodES256
+	m.mu.Unlock()
+}
+
+// authorize имитирует вход пользователя: проверяет запрос авторизации и возвращает code и state
+func (m *mockProvider) authorize(authURL string) (string, string) {
+	u, err := url.Parse(authURL)
+	require.NoError(m.t, err)
+	q := u.Query()
+	assert.Equal(m.t, "code", q.Get("response_type"))
+	assert.Equal(m.t, testClientID, q.Get("client_id"))
+	assert.Equal(m.t, testRedirectURL, q.Get("redirect_uri"))
+	assert.Equal(m.t, "openid email profile", q.Get("scope"))
+	assert.Equal(m.t, "S256", q.Get("code_challenge_method"))
+
+	code := base64.RawURLEncoding.EncodeToString(big.NewInt(time.Now().UnixNano()).Bytes())
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	m.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enc := base64.RawURLEncoding
+	var key map[string]string
+	switch k := m.key.(type) {
+	case *rsa.PrivateKey:
+		key = map[string]string{
+			"kty": "RSA",
+			"n":   enc.EncodeToString(k.N.Bytes()),
+			"e":   enc.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		key = map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   enc.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+			"y":   enc.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+		}
+	}
+	key["kid"], key["use"] = m.kid, "sig"
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []any{key, map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"}},
+	})
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != testClientID || secret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	auth, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	if !ok || Challenge(r.PostFormValue("code_verifier")) != auth.challenge || r.PostFormValue("redirect_uri") != testRedirectURL {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            "248289761001",
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          auth.nonce,
+		"email":          "vasya@example.com",
+		"email_verified": true,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(m.method, claims)
+	token.Header["kid"] = m.kid
+	if m.signKid != "" {
+		token.Header["kid"] = m.signKid
+	}
+	signed, err := token.SignedString(m.key)
+	require.NoError(m.t, err)
+	_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": signed})
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		claims    jwt.MapClaims
+		verifier  string
+		nonce     string
+		expectErr error
+	}{
+		{
+			testName: "Correct test",
+		},
+		{
+			testName: "Audience list",
+			claims:   jwt.MapClaims{"aud": []string{"other", testClientID}},
+		},
+		{
+			testName:  "Another client",
+			claims:    jwt.MapClaims{"aud": "other"},
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			testName:  "Another issuer",
+			claims:    jwt.MapClaims{"iss": "https://evil.example.com"},
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			testName:  "Expired token",
+			claims:    jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()},
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			testName:  "Nonce mismatch",
+			nonce:     "other-nonce",
+			expectErr: ErrNonceMismatch,
+		},
+		{
+			testName:  "Wrong PKCE verifier",
+			verifier:  "other-verifier",
+			expectErr: assert.AnError,
+		},
+	}
+
+	m := newMockProvider(t)
+	p := NewProvider(m.config())
+	ctx := context.Background()
+
+	for _, tc := range testCases {
+		m.claims = tc.claims
+		authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+		require.NoError(t, err, tc.testName)
+		code, state := m.authorize(authURL)
+		assert.Equal(t, "state-1", state, tc.testName)
+
+		verifier, nonce := "verifier-1", "nonce-1"
+		if tc.verifier != "" {
+			verifier = tc.verifier
+		}
+		if tc.nonce != "" {
+			nonce = tc.nonce
+		}
+
+		claims, err := p.Exchange(ctx, code, verifier, nonce)
+		switch {
+		case tc.expectErr == nil:
+			assert.NoError(t, err, tc.testName)
+			assert.Equal(t, Claims{Subject: "248289761001", Email: "vasya@example.com", EmailVerified: true}, claims, tc.testName)
+		case tc.expectErr == assert.AnError:
+			assert.Error(t, err, tc.testName)
+		default:
+			assert.ErrorIs(t, err, tc.expectErr, tc.testName)
+		}
+	}
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	p := NewProvider(m.config())
+	ctx := context.Background()
+
+	login := func() error {
+		authURL, err := p.AuthCodeURL(ctx, "state", "nonce", "verifier")
+		require.NoError(t, err)
+		code, _ := m.authorize(authURL)
+		_, err = p.Exchange(ctx, code, "verifier", "nonce")
+		return err
+	}
+
+	assert.NoError(t, login())
+	// провайдер сменил ключ, JWKS перезагружается по новому kid
+	m.rotateEC("key-2")
+	assert.NoError(t, login())
+
+	// токен, подписанный ключом, которого нет в JWKS, не принимается
+	m.mu.Lock()
+	m.signKid = "key-unknown"
+	m.mu.Unlock()
+	assert.ErrorIs(t, login(), ErrUnknownKey)
+}
+
+func TestProvider_Discovery(t *testing.T) {
+	m := newMockProvider(t)
+
+	cfg := m.config()
+	cfg.Issuer = m.server.URL + "/other"
+	_, err := NewProvider(cfg).AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	assert.Error(t, err)
+
+	// издатель из discovery не совпадает с настроенным
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+	cfg.Issuer = server.URL
+	_, err = NewProvider(cfg).AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	assert.Error(t, err)
+}
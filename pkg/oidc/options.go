@@ -0,0 +1,13 @@
+package oidc
+
+import "net/http"
+
+type Option func(p *Provider)
+
+func HTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		if client != nil {
+			p.client = client
+		}
+	}
+}
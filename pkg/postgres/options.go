@@ -1,5 +1,10 @@
 package postgres
 
+import (
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
 type Option func(postgres *Postgres)
 
 func MaxPoolSize(size int) Option {
@@ -7,3 +12,24 @@ func MaxPoolSize(size int) Option {
 		postgres.maxPoolSize = size
 	}
 }
+
+// ConnAttempts сколько раз пытаться подключиться при создании пула
+func ConnAttempts(attempts int) Option {
+	return func(postgres *Postgres) {
+		postgres.connAttempts = attempts
+	}
+}
+
+// ConnTimeout пауза между попытками подключения
+func ConnTimeout(timeout time.Duration) Option {
+	return func(postgres *Postgres) {
+		postgres.connTimeout = timeout
+	}
+}
+
+// Tracer вызывается на каждый запрос пула, например для трассировки
+func Tracer(tracer pgx.QueryTracer) Option {
+	return func(postgres *Postgres) {
+		postgres.tracer = tracer
+	}
+}
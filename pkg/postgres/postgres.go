@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
 	"time"
@@ -23,12 +25,15 @@ type pgxPool interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	Begin(ctx context.Context) (pgx.Tx, error)
+	Stat() *pgxpool.Stat
+	Ping(ctx context.Context) error
 }
 
 type Postgres struct {
 	maxPoolSize  int
 	connAttempts int
 	connTimeout  time.Duration
+	tracer       pgx.QueryTracer
 	Builder      squirrel.StatementBuilderType // Генератор sql запросов, не является orm!
 	Pool         pgxPool
 }
@@ -51,6 +56,17 @@ func NewPG(url string, opts ...Option) (*Postgres, error) {
 		return nil, err
 	}
 	poolConfig.MaxConns = int32(pg.maxPoolSize)
+	poolConfig.ConnConfig.Tracer = pg.tracer
+	// по умолчанию pgx возвращает timestamptz в локальном поясе сервера, приводим к UTC,
+	// чтобы результат не зависел от окружения. Момент времени при этом не меняется
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		conn.TypeMap().RegisterType(&pgtype.Type{
+			Name:  "timestamptz",
+			OID:   pgtype.TimestamptzOID,
+			Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+		})
+		return nil
+	}
 	for pg.connAttempts > 0 {
 		pg.Pool, err = pgxpool.NewWithConfig(context.Background(), poolConfig)
 		if err == nil {
@@ -66,6 +82,22 @@ func NewPG(url string, opts ...Option) (*Postgres, error) {
 	return pg, err
 }
 
+// Ping проверяет, что пул может получить соединение и база отвечает
+func (p *Postgres) Ping(ctx context.Context) error {
+	if p.Pool == nil {
+		return errors.New("postgres pool is not initialized")
+	}
+	return p.Pool.Ping(ctx)
+}
+
+// Stat статистика пула соединений, nil если пул не создан
+func (p *Postgres) Stat() *pgxpool.Stat {
+	if p.Pool == nil {
+		return nil
+	}
+	return p.Pool.Stat()
+}
+
 func (p *Postgres) Close() {
 	if p.Pool != nil {
 		p.Pool.Close()
@@ -0,0 +1,444 @@
+package quickadd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Разбор быстрой записи задачи одной строкой, например "Pay rent tomorrow 9am #finance !p1 every month".
+// Из текста извлекаются срок, теги (#tag), приоритет (!p1 - !p4) и правило повторения, остальные слова
+// в исходном порядке составляют название. Относительные даты считаются от now в его часовом поясе.
+//
+// Срок:
+//   - today, tomorrow, monday ... sunday (ближайший такой день, включая сегодня), next monday (строго после сегодня),
+//     next week (понедельник следующей недели), next month (первое число следующего месяца);
+//   - in 3 days / in a week / in 2 months, а также in 2 hours / in 30 minutes - точное время от now;
+//   - 2024-09-15, sep 15, 15 september (если дата в этом году прошла - в следующем);
+//   - 9am, 9:30pm, 9 am, 18:00, noon. Время без даты - ближайшее такое время (сегодня или завтра);
+//   - перед датой и временем допустимы предлоги at, on, by.
+//
+// Дата без времени означает задачу на весь день.
+//
+// Повторение: daily, weekly, monthly, yearly, every day / week / month / year, every other week, every 3 days,
+// every monday, every weekday. Если дата не указана, срок - первое повторение начиная с сегодня.
+
+var ErrEmptyTitle = errors.New("quickadd: title is empty")
+
+type Result struct {
+	Title string
+	// DueDate срок в часовом поясе now, nil - срок не указан
+	DueDate *time.Time
+	// AllDay срок указан датой без времени
+	AllDay bool
+	Tags   []string
+	// Priority от 1 (наивысший) до 4, 0 - не указан
+	Priority int
+	// Recurrence правило повторения в формате RRULE (RFC 5545) из FREQ, INTERVAL и BYDAY, пустая строка - без повторения
+	Recurrence string
+}
+
+var (
+	priorityRe = regexp.MustCompile(`^!p([1-4])$`)
+	clock12Re  = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	clock24Re  = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	hourRe     = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?$`)
+	dayRe      = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// byDay коды дней недели RRULE
+var byDay = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// frequencies единицы повторения в единственном и множественном числе
+var frequencies = map[string]string{
+	"day": "DAILY", "days": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY",
+}
+
+var adverbs = map[string]string{
+	"daily":    "DAILY",
+	"weekly":   "WEEKLY",
+	"monthly":  "MONTHLY",
+	"yearly":   "YEARLY",
+	"annually": "YEARLY",
+}
+
+var prepositions = map[string]bool{"at": true, "on": true, "by": true}
+
+type clock struct {
+	hour, minute int
+}
+
+type parser struct {
+	now   time.Time
+	today time.Time
+	words []string
+	lower []string
+
+	date  *time.Time
+	clock *clock
+	exact *time.Time
+	// first возвращает первое повторение не раньше from, если повторение привязано к дням недели
+	first func(from time.Time) time.Time
+
+	result Result
+}
+
+// Parse разбирает text относительно момента now. Возвращает ErrEmptyTitle, если после разбора не осталось названия
+func Parse(text string, now time.Time) (Result, error) {
+	p := &parser{
+		now:   now,
+		today: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		words: strings.Fields(text),
+	}
+	for _, w := range p.words {
+		p.lower = append(p.lower, strings.ToLower(w))
+	}
+
+	title := make([]string, 0, len(p.words))
+	for i := 0; i < len(p.words); {
+		if n := p.match(i); n > 0 {
+			i += n
+			continue
+		}
+		title = append(title, p.words[i])
+		i++
+	}
+	if len(title) == 0 {
+		return Result{}, ErrEmptyTitle
+	}
+	p.result.Title = strings.Join(title, " ")
+	p.resolveDueDate()
+
+	return p.result, nil
+}
+
+// match распознает конструкцию, начинающуюся со слова i, и возвращает число занятых ею слов (0 - не распознано)
+func (p *parser) match(i int) int {
+	w := p.lower[i]
+	if len(w) > 1 && w[0] == '#' {
+		p.result.Tags = append(p.result.Tags, p.words[i][1:])
+		return 1
+	}
+	if m := priorityRe.FindStringSubmatch(w); m != nil {
+		p.result.Priority, _ = strconv.Atoi(m[1])
+		return 1
+	}
+	if n := p.matchRecurrence(i); n > 0 {
+		return n
+	}
+	if n := p.matchDate(i); n > 0 {
+		return n
+	}
+	if n := p.matchClock(i); n > 0 {
+		return n
+	}
+	if prepositions[w] && i+1 < len(p.words) {
+		if n := p.matchDate(i + 1); n > 0 {
+			return n + 1
+		}
+		if n := p.matchClock(i + 1); n > 0 {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+func (p *parser) matchDate(i int) int {
+	w, next := p.lower[i], p.word(i+1)
+
+	if d, err := time.ParseInLocation(time.DateOnly, w, p.now.Location()); err == nil {
+		p.setDate(d)
+		return 1
+	}
+	if wd, ok := weekdays[w]; ok {
+		p.setDate(nextWeekday(p.today, wd))
+		return 1
+	}
+
+	switch w {
+	case "today":
+		p.setDate(p.today)
+		return 1
+	case "tomorrow":
+		p.setDate(p.today.AddDate(0, 0, 1))
+		return 1
+	case "next":
+		if wd, ok := weekdays[next]; ok {
+			p.setDate(nextWeekday(p.today.AddDate(0, 0, 1), wd))
+			return 2
+		}
+		switch next {
+		case "week":
+			p.setDate(nextWeekday(p.today.AddDate(0, 0, 1), time.Monday))
+			return 2
+		case "month":
+			p.setDate(time.Date(p.today.Year(), p.today.Month()+1, 1, 0, 0, 0, 0, p.today.Location()))
+			return 2
+		}
+	case "in":
+		n, ok := count(next)
+		if !ok {
+			return 0
+		}
+		switch strings.TrimSuffix(p.word(i+2), "s") {
+		case "day":
+			p.setDate(p.today.AddDate(0, 0, n))
+		case "week":
+			p.setDate(p.today.AddDate(0, 0, 7*n))
+		case "month":
+			p.setDate(addMonths(p.today, n))
+		case "hour":
+			p.setExact(p.now.Add(time.Duration(n) * time.Hour))
+		case "minute", "min":
+			p.setExact(p.now.Add(time.Duration(n) * time.Minute))
+		default:
+			return 0
+		}
+		return 3
+	}
+
+	if month, ok := months[w]; ok {
+		if day, ok := p.day(next); ok {
+			return p.setMonthDay(month, day, 2)
+		}
+	}
+	if day, ok := p.day(w); ok {
+		if month, ok := months[next]; ok {
+			return p.setMonthDay(month, day, 2)
+		}
+	}
+	return 0
+}
+
+func (p *parser) matchClock(i int) int {
+	w := p.lower[i]
+	if w == "noon" {
+		p.clock = &clock{hour: 12}
+		return 1
+	}
+	if m := clock12Re.FindStringSubmatch(w); m != nil {
+		return p.setClock12(m[1], m[2], m[3], 1)
+	}
+	if next := p.word(i + 1); next == "am" || next == "pm" {
+		if m := hourRe.FindStringSubmatch(w); m != nil {
+			return p.setClock12(m[1], m[2], next, 2)
+		}
+	}
+	if m := clock24Re.FindStringSubmatch(w); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour > 23 || minute > 59 {
+			return 0
+		}
+		p.clock = &clock{hour: hour, minute: minute}
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) matchRecurrence(i int) int {
+	w, next := p.lower[i], p.word(i+1)
+	if freq, ok := adverbs[w]; ok {
+		p.setRecurrence("FREQ="+freq, nil)
+		return 1
+	}
+	if w != "every" {
+		return 0
+	}
+
+	if freq, ok := frequencies[next]; ok {
+		p.setRecurrence("FREQ="+freq, nil)
+		return 2
+	}
+	if wd, ok := weekdays[next]; ok {
+		p.setRecurrence("FREQ=WEEKLY;BYDAY="+byDay[wd], func(from time.Time) time.Time {
+			return nextWeekday(from, wd)
+		})
+		return 2
+	}
+	if next == "weekday" {
+		p.setRecurrence("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", nextWorkday)
+		return 2
+	}
+
+	interval, ok := count(next)
+	if next == "other" {
+		interval, ok = 2, true
+	}
+	if !ok || interval < 1 {
+		return 0
+	}
+	freq, ok := frequencies[p.word(i+2)]
+	if !ok {
+		return 0
+	}
+	rule := "FREQ=" + freq
+	if interval > 1 {
+		rule += fmt.Sprintf(";INTERVAL=%d", interval)
+	}
+	p.setRecurrence(rule, nil)
+	return 3
+}
+
+// resolveDueDate собирает срок из распознанных даты, времени и повторения
+func (p *parser) resolveDueDate() {
+	if p.exact != nil {
+		due := p.exact.Truncate(time.Minute)
+		p.result.DueDate = &due
+		return
+	}
+
+	date := p.date
+	if date == nil {
+		if p.clock == nil && p.result.Recurrence == "" {
+			return
+		}
+		from := p.today
+		// время без даты - ближайшее такое время: если сегодня оно уже прошло, то завтра
+		if p.clock != nil && !p.at(p.today, *p.clock).After(p.now) {
+			from = from.AddDate(0, 0, 1)
+		}
+		if p.first != nil {
+			from = p.first(from)
+		}
+		date = &from
+	}
+
+	if p.clock == nil {
+		p.result.DueDate = date
+		p.result.AllDay = true
+		return
+	}
+	due := p.at(*date, *p.clock)
+	p.result.DueDate = &due
+}
+
+func (p *parser) setDate(date time.Time) {
+	p.date = &date
+	p.exact = nil
+}
+
+func (p *parser) setExact(t time.Time) {
+	p.exact = &t
+}
+
+func (p *parser) setMonthDay(month time.Month, day, n int) int {
+	date := time.Date(p.today.Year(), month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Day() != day {
+		// 31 sep и подобные несуществующие даты
+		return 0
+	}
+	if date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	p.setDate(date)
+	return n
+}
+
+func (p *parser) setClock12(hour, minute, meridiem string, n int) int {
+	h, _ := strconv.Atoi(hour)
+	m := 0
+	if minute != "" {
+		m, _ = strconv.Atoi(minute)
+	}
+	if h < 1 || h > 12 || m > 59 {
+		return 0
+	}
+	h %= 12
+	if meridiem == "pm" {
+		h += 12
+	}
+	p.clock = &clock{hour: h, minute: m}
+	return n
+}
+
+func (p *parser) setRecurrence(rule string, first func(from time.Time) time.Time) {
+	p.result.Recurrence = rule
+	p.first = first
+}
+
+func (p *parser) at(date time.Time, c clock) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), c.hour, c.minute, 0, 0, date.Location())
+}
+
+// word возвращает слово i в нижнем регистре или пустую строку, если слов меньше
+func (p *parser) word(i int) string {
+	if i >= len(p.lower) {
+		return ""
+	}
+	return p.lower[i]
+}
+
+func (p *parser) day(w string) (int, bool) {
+	m := dayRe.FindStringSubmatch(w)
+	if m == nil {
+		return 0, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	return day, day >= 1 && day <= 31
+}
+
+// count разбирает количество: число или артикль a/an (единица)
+func count(w string) (int, bool) {
+	if w == "a" || w == "an" {
+		return 1, true
+	}
+	n, err := strconv.Atoi(w)
+	return n, err == nil && n >= 0
+}
+
+// addMonths from через n месяцев. Если в целевом месяце нет такого числа, берется его последний день:
+// 31 января через месяц - 29 февраля, а не 2 марта, как у AddDate
+func addMonths(from time.Time, n int) time.Time {
+	firstDay := time.Date(from.Year(), from.Month()+time.Month(n), 1, 0, 0, 0, 0, from.Location())
+	lastDay := firstDay.AddDate(0, 1, -1).Day()
+	return time.Date(firstDay.Year(), firstDay.Month(), min(from.Day(), lastDay),
+		from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+}
+
+// nextWeekday ближайший день недели wd, начиная с from включительно
+func nextWeekday(from time.Time, wd time.Weekday) time.Time {
+	return from.AddDate(0, 0, (int(wd)-int(from.Weekday())+7)%7)
+}
+
+// nextWorkday ближайший рабочий день (понедельник - пятница), начиная с from включительно
+func nextWorkday(from time.Time) time.Time {
+	switch from.Weekday() {
+	case time.Saturday:
+		return from.AddDate(0, 0, 2)
+	case time.Sunday:
+		return from.AddDate(0, 0, 1)
+	}
+	return from
+}
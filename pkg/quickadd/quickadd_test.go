@@ -0,0 +1,224 @@
+package quickadd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	// среда
+	now := time.Date(2024, 8, 28, 15, 0, 0, 0, loc)
+	at := func(month time.Month, day, hour, minute int) *time.Time {
+		d := time.Date(2024, month, day, hour, minute, 0, 0, loc)
+		return &d
+	}
+
+	testCases := []struct {
+		testName  string
+		text      string
+		expect    Result
+		expectErr error
+	}{
+		{
+			testName: "Full example",
+			text:     "Pay rent tomorrow 9am #finance !p1 every month",
+			expect: Result{
+				Title:      "Pay rent",
+				DueDate:    at(8, 29, 9, 0),
+				Tags:       []string{"finance"},
+				Priority:   1,
+				Recurrence: "FREQ=MONTHLY",
+			},
+		},
+		{
+			testName: "Title only",
+			text:     "  Buy   milk ",
+			expect:   Result{Title: "Buy milk"},
+		},
+		{
+			testName: "Weekday with preposition",
+			text:     "Call mom on Friday",
+			expect:   Result{Title: "Call mom", DueDate: at(8, 30, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Weekday is today",
+			text:     "Standup wednesday",
+			expect:   Result{Title: "Standup", DueDate: at(8, 28, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Next weekday",
+			text:     "Review next wednesday",
+			expect:   Result{Title: "Review", DueDate: at(9, 4, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Next week",
+			text:     "Plan sprint next week",
+			expect:   Result{Title: "Plan sprint", DueDate: at(9, 2, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Next month",
+			text:     "Pay taxes next month",
+			expect:   Result{Title: "Pay taxes", DueDate: at(9, 1, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Time later today",
+			text:     "Meeting at 17:30",
+			expect:   Result{Title: "Meeting", DueDate: at(8, 28, 17, 30)},
+		},
+		{
+			testName: "Time already passed today",
+			text:     "Breakfast 9am",
+			expect:   Result{Title: "Breakfast", DueDate: at(8, 29, 9, 0)},
+		},
+		{
+			testName: "Noon",
+			text:     "Lunch at noon",
+			expect:   Result{Title: "Lunch", DueDate: at(8, 29, 12, 0)},
+		},
+		{
+			testName: "Separate meridiem",
+			text:     "Dinner today 7:15 pm",
+			expect:   Result{Title: "Dinner", DueDate: at(8, 28, 19, 15)},
+		},
+		{
+			testName: "In hours",
+			text:     "Deploy in 2 hours",
+			expect:   Result{Title: "Deploy", DueDate: at(8, 28, 17, 0)},
+		},
+		{
+			testName: "In weeks",
+			text:     "Renew passport in 3 weeks",
+			expect:   Result{Title: "Renew passport", DueDate: at(9, 18, 0, 0), AllDay: true},
+		},
+		{
+			testName: "In a day",
+			text:     "Reply in a day",
+			expect:   Result{Title: "Reply", DueDate: at(8, 29, 0, 0), AllDay: true},
+		},
+		{
+			testName: "ISO date with time",
+			text:     "Trip 2024-09-15 7:45pm",
+			expect:   Result{Title: "Trip", DueDate: at(9, 15, 19, 45)},
+		},
+		{
+			testName: "Month and day",
+			text:     "Birthday by sep 5th",
+			expect:   Result{Title: "Birthday", DueDate: at(9, 5, 0, 0), AllDay: true},
+		},
+		{
+			testName: "Passed day goes to next year",
+			text:     "Anniversary 1 January",
+			expect: Result{
+				Title:   "Anniversary",
+				DueDate: func() *time.Time { d := time.Date(2025, 1, 1, 0, 0, 0, 0, loc); return &d }(),
+				AllDay:  true,
+			},
+		},
+		{
+			testName: "Every weekday of week with time",
+			text:     "Gym every monday 7 am",
+			expect:   Result{Title: "Gym", DueDate: at(9, 2, 7, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO"},
+		},
+		{
+			testName: "Every working day after time passed",
+			text:     "Report every weekday 10am",
+			expect:   Result{Title: "Report", DueDate: at(8, 29, 10, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		},
+		{
+			testName: "Every other day",
+			text:     "Water plants every other day",
+			expect:   Result{Title: "Water plants", DueDate: at(8, 28, 0, 0), AllDay: true, Recurrence: "FREQ=DAILY;INTERVAL=2"},
+		},
+		{
+			testName: "Every n weeks",
+			text:     "Sync every 2 weeks #work #team",
+			expect: Result{
+				Title:      "Sync",
+				DueDate:    at(8, 28, 0, 0),
+				AllDay:     true,
+				Tags:       []string{"work", "team"},
+				Recurrence: "FREQ=WEEKLY;INTERVAL=2",
+			},
+		},
+		{
+			testName: "Recurrence adverb",
+			text:     "Backup weekly",
+			expect:   Result{Title: "Backup", DueDate: at(8, 28, 0, 0), AllDay: true, Recurrence: "FREQ=WEEKLY"},
+		},
+		{
+			testName: "Words that look like dates stay in title",
+			text:     "Read 2 books in a box at home !p5",
+			expect:   Result{Title: "Read 2 books in a box at home !p5"},
+		},
+		{
+			testName: "Invalid time and date stay in title",
+			text:     "Fix 25:00 bug sep 31",
+			expect:   Result{Title: "Fix 25:00 bug sep 31"},
+		},
+		{
+			testName:  "Empty title",
+			text:      "tomorrow #home !p2",
+			expectErr: ErrEmptyTitle,
+		},
+	}
+
+	for _, tc := range testCases {
+		result, err := Parse(tc.text, now)
+		assert.Equal(t, tc.expectErr, err, tc.testName)
+		assert.Equal(t, tc.expect, result, tc.testName)
+	}
+}
+
+func TestParse_MonthEnd(t *testing.T) {
+	testCases := []struct {
+		testName string
+		now      time.Time
+		text     string
+		expect   time.Time
+	}{
+		{
+			testName: "Leap February",
+			now:      time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+			text:     "Report in 1 month",
+			expect:   time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testName: "February",
+			now:      time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC),
+			text:     "Report in a month",
+			expect:   time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testName: "Thirty day month",
+			now:      time.Date(2024, 8, 31, 10, 0, 0, 0, time.UTC),
+			text:     "Report in 1 month",
+			expect:   time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testName: "Next year",
+			now:      time.Date(2024, 12, 31, 10, 0, 0, 0, time.UTC),
+			text:     "Report in 2 months",
+			expect:   time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testName: "Day exists in target month",
+			now:      time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC),
+			text:     "Report in 3 months",
+			expect:   time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testName: "Next month from month end",
+			now:      time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+			text:     "Report next month",
+			expect:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		result, err := Parse(tc.text, tc.now)
+		assert.Nil(t, err, tc.testName)
+		assert.Equal(t, Result{Title: "Report", DueDate: &tc.expect, AllDay: true}, result, tc.testName)
+	}
+}
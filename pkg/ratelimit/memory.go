@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval как часто из памяти удаляются наполнившиеся корзины: они ничем не отличаются от новых
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// Memory хранит корзины в памяти процесса. Ограничения действуют в пределах одной реплики
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	now := m.now()
+	burst := float64(limit.Requests)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst}
+		m.buckets[key] = b
+	} else {
+		b.tokens = min(burst, b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate())
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.updatedAt = now
+
+	result := NewResult(limit, b.tokens, allowed)
+	b.fullAt = now.Add(result.Reset)
+	m.sweep(now)
+	return result, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if !b.fullAt.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
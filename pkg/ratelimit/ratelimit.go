@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit не больше Requests запросов за Period по алгоритму token bucket: корзина вмещает Requests токенов
+// и равномерно пополняется за Period. Нулевой Limit означает отсутствие ограничения
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled задано ли ограничение
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Rate скорость пополнения корзины, токенов в секунду
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit разбирает ограничение вида "<запросов>/<период>", например "300/1m". Пустая строка - без ограничения
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>, e.g. 300/1m", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result решение по запросу. Remaining - сколько запросов еще можно сделать сразу, Reset - через сколько
+// корзина наполнится полностью, RetryAfter - через сколько будет разрешен следующий запрос, если этот отклонен
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store хранит корзины токенов. Take атомарно пополняет корзину key по limit и забирает из нее токен, если он есть
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewResult решение по запросу при tokens токенах, оставшихся в корзине после него
+func NewResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.Rate()
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(max(s, 0) * float64(time.Second))
+}
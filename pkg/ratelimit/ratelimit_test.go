@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		input       string
+		expectLimit Limit
+		expectErr   bool
+	}{
+		{
+			testName:    "Requests per minute",
+			input:       "300/1m",
+			expectLimit: Limit{Requests: 300, Period: time.Minute},
+		},
+		{
+			testName:    "Spaces",
+			input:       " 5/10s ",
+			expectLimit: Limit{Requests: 5, Period: 10 * time.Second},
+		},
+		{
+			testName: "Empty disables limit",
+			input:    "",
+		},
+		{
+			testName:  "No period",
+			input:     "300",
+			expectErr: true,
+		},
+		{
+			testName:  "Zero requests",
+			input:     "0/1m",
+			expectErr: true,
+		},
+		{
+			testName:  "Bad period",
+			input:     "10/minute",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		limit, err := ParseLimit(tc.input)
+		assert.Equal(t, tc.expectErr, err != nil, tc.testName)
+		assert.Equal(t, tc.expectLimit, limit, tc.testName)
+	}
+}
+
+func TestMemory_Take(t *testing.T) {
+	now := time.Date(2024, 8, 29, 12, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+	ctx := context.Background()
+
+	r, err := m.Take(ctx, "vasya", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second}, r)
+
+	r, err = m.Take(ctx, "vasya", limit)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+
+	r, err = m.Take(ctx, "vasya", limit)
+	require.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 5*time.Second, r.RetryAfter)
+	assert.Equal(t, 10*time.Second, r.Reset)
+
+	// у другого ключа своя корзина
+	r, err = m.Take(ctx, "petya", limit)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+
+	// за 5 секунд корзина пополняется на один токен
+	now = now.Add(5 * time.Second)
+	r, err = m.Take(ctx, "vasya", limit)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+
+	// наполнившиеся корзины удаляются
+	now = now.Add(time.Hour)
+	_, err = m.Take(ctx, "kolya", limit)
+	require.NoError(t, err)
+	assert.Len(t, m.buckets, 1)
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// RFC 6238, приложение B: секрет "12345678901234567890", SHA1, 8 цифр
+func TestHOTP_RFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	testCases := []struct {
+		unix   int64
+		expect string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range testCases {
+		step := Step(time.Unix(tc.unix, 0))
+		assert.Equal(t, tc.expect, hotp(key, uint64(step), 8), tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+
+	now := time.Date(2024, 8, 28, 12, 0, 10, 0, time.UTC)
+	code, err := Code(secret, Step(now))
+	assert.Nil(t, err)
+
+	step, ok := Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// код прошлого шага принимается в пределах допуска
+	step, ok = Validate(secret, code, now.Add(Period), 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	_, ok = Validate(secret, code, now.Add(2*Period), 1)
+	assert.False(t, ok)
+	_, ok = Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+	_, ok = Validate("not base32!", code, now, 1)
+	assert.False(t, ok)
+
+	_, err = Code("", 1)
+	assert.Equal(t, ErrInvalidSecret, err)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("todolist", "vasya", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/todolist:vasya", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "todolist", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+
+	png, err := QR(uri)
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG")))
+}
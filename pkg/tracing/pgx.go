@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"unicode"
+)
+
+const pgxTracerName = "todolist_api/pkg/tracing/pgx"
+
+type querySpanKey struct{}
+
+// QueryTracer создает span на каждый запрос pgx. В span попадает текст запроса без литералов: значения
+// передаются параметрами ($1), а строки и числа, записанные прямо в sql, заменяются на ?
+type QueryTracer struct {
+	tracer trace.Tracer
+}
+
+// NewQueryTracer tracer запросов. Если provider nil, используется глобальный провайдер
+func NewQueryTracer(provider trace.TracerProvider) *QueryTracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &QueryTracer{tracer: provider.Tracer(pgxTracerName)}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	statement := SanitizeSQL(data.SQL)
+	operation := strings.ToUpper(strings.SplitN(statement, " ", 2)[0])
+	name := operation
+	if name == "" {
+		name = "query"
+	}
+
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", statement),
+		),
+	)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	// отсутствие строк - обычный результат поиска, а не сбой запроса
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// SanitizeSQL заменяет строковые и числовые литералы на ? и схлопывает пробелы. Параметры $1, $2 остаются как есть
+func SanitizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	runes := []rune(sql)
+	space := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		case space:
+			b.WriteByte(' ')
+			space = false
+		}
+
+		switch {
+		case r == '\'':
+			// строка до закрывающей кавычки, '' внутри строки - экранированная кавычка
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteString("'?'")
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isIdentRune может ли символ стоять перед цифрой внутри имени или параметра: t1, $1
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
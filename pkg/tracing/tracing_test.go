@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	testCases := []struct {
+		testName string
+		input    string
+		expect   string
+	}{
+		{
+			testName: "Placeholders are kept",
+			input:    "SELECT id FROM task WHERE username = $1 AND id = $2",
+			expect:   "SELECT id FROM task WHERE username = $1 AND id = $2",
+		},
+		{
+			testName: "String literals",
+			input:    "update task set status = coalesce(nullif($1, ''), status) where note = 'it''s secret'",
+			expect:   "update task set status = coalesce(nullif($1, '?'), status) where note = '?'",
+		},
+		{
+			testName: "Numbers outside identifiers",
+			input:    "select t1.id from task t1 where priority > 3 and ratio < 0.5 limit 10",
+			expect:   "select t1.id from task t1 where priority > ? and ratio < ? limit ?",
+		},
+		{
+			testName: "Whitespace is collapsed",
+			input:    "\n\tselect *\n\t\tfrom   task  \n",
+			expect:   "select * from task",
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expect, SanitizeSQL(tc.input), tc.testName)
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewQueryTracer(provider)
+
+	parentCtx, parent := provider.Tracer("test").Start(context.Background(), "taskService.Find")
+	ctx := tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{
+		SQL:  "select * from task where username = $1 and title <> 'secret'",
+		Args: []any{"vasya"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 2")})
+
+	ctx = tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{SQL: "delete from task where id = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+
+	ctx = tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{SQL: "select 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 4)
+
+	query := spans[0]
+	assert.Equal(t, "SELECT", query.Name)
+	assert.Equal(t, parent.SpanContext().TraceID(), query.SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent.SpanID())
+	assert.Contains(t, query.Attributes, attribute.String("db.statement", "select * from task where username = $1 and title <> '?'"))
+	assert.Contains(t, query.Attributes, attribute.Int64("db.rows_affected", 2))
+	assert.Equal(t, codes.Unset, query.Status.Code)
+
+	assert.Equal(t, "DELETE", spans[1].Name)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+
+	// ErrNoRows не считается ошибкой
+	assert.Equal(t, codes.Unset, spans[2].Status.Code)
+
+	// без начатого span'а запроса TraceQueryEnd не должен завершать чужой span
+	tracer.TraceQueryEnd(parentCtx, nil, pgx.TraceQueryEndData{})
+	assert.Len(t, exporter.GetSpans(), 4)
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
@@ -13,7 +13,9 @@ const (
 )
 
 var (
-	usernameRegex = regexp.MustCompile(fmt.Sprintf(`^[a-z0-9_]{%d,%d}$`, usernameMinLength, usernameMaxLength))
+	usernameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	// rruleRegex подмножество RRULE (RFC 5545): частота, интервал и дни недели
+	rruleRegex = regexp.MustCompile(`^FREQ=(DAILY|WEEKLY|MONTHLY|YEARLY)(;INTERVAL=[1-9][0-9]{0,2})?(;BYDAY=(MO|TU|WE|TH|FR|SA|SU)(,(MO|TU|WE|TH|FR|SA|SU))*)?$`)
 )
 
 type Validator interface {
@@ -21,38 +23,81 @@ type Validator interface {
 }
 
 type valid struct {
-	v *validator.Validate
+	v                 *validator.Validate
+	usernameMinLength int
+	usernameMaxLength int
 }
 
-func NewValidator() (Validator, error) {
-	v := validator.New()
+type Option func(v *valid)
 
-	if err := v.RegisterValidation("username", usernameValidate); err != nil {
+// UsernameLength допустимая длина имени пользователя, по умолчанию от 3 до 32 символов
+func UsernameLength(min, max int) Option {
+	return func(v *valid) {
+		v.usernameMinLength = min
+		v.usernameMaxLength = max
+	}
+}
+
+func NewValidator(opts ...Option) (Validator, error) {
+	vl := &valid{
+		v:                 validator.New(),
+		usernameMinLength: usernameMinLength,
+		usernameMaxLength: usernameMaxLength,
+	}
+	for _, option := range opts {
+		option(vl)
+	}
+	v := vl.v
+
+	if err := v.RegisterValidation("username", vl.usernameValidate); err != nil {
+		return nil, err
+	}
+	if err := v.RegisterValidation("rrule", rruleValidate); err != nil {
 		return nil, err
 	}
 
-	return &valid{v: v}, nil
+	return vl, nil
 }
 
 func (v *valid) Validate(i interface{}) error {
 	if err := v.v.Struct(i); err != nil {
-		return validateError(err.(validator.ValidationErrors)[0])
+		return v.validateError(err.(validator.ValidationErrors)[0])
 	}
 	return nil
 }
 
-func validateError(err validator.FieldError) error {
+func (v *valid) validateError(err validator.FieldError) error {
 	switch err.Tag() {
 	case "username":
-		return fmt.Errorf("field username can only consist of lower Latin characters, numbers and underscore symbol. Min length is 3, max: 32, your input: %s", err.Value())
+		return fmt.Errorf("field username can only consist of lower Latin characters, numbers and underscore symbol. Min length is %d, max: %d, your input: %s",
+			v.usernameMinLength, v.usernameMaxLength, err.Value())
+	case "rrule":
+		return fmt.Errorf("field %s must be a recurrence rule like FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH, your input: %v", err.Field(), err.Value())
+	case "min":
+		return fmt.Errorf("field %s must be at least %s, your input: %v", err.Field(), err.Param(), err.Value())
+	case "max":
+		return fmt.Errorf("field %s must be at most %s, your input: %v", err.Field(), err.Param(), err.Value())
+	case "oneof":
+		return fmt.Errorf("field %s must be one of [%s], your input: %v", err.Field(), err.Param(), err.Value())
+	case "email":
+		return fmt.Errorf("field %s must be a valid email address, your input: %v", err.Field(), err.Value())
 	default:
 		return fmt.Errorf("field %s is required", err.Field())
 	}
 }
 
-func usernameValidate(fl validator.FieldLevel) bool {
+func (v *valid) usernameValidate(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	username := fl.Field().String()
+	length := len(username)
+	return length >= v.usernameMinLength && length <= v.usernameMaxLength && usernameRegex.MatchString(username)
+}
+
+func rruleValidate(fl validator.FieldLevel) bool {
 	if fl.Field().Kind() != reflect.String {
 		return false
 	}
-	return usernameRegex.MatchString(fl.Field().String())
+	return rruleRegex.MatchString(fl.Field().String())
 }
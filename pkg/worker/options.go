@@ -0,0 +1,22 @@
+package worker
+
+import "time"
+
+type Option func(worker *Worker)
+
+func Interval(interval time.Duration) Option {
+	return func(w *Worker) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// StuckAfter через сколько выполняющийся Job считается зависшим
+func StuckAfter(d time.Duration) Option {
+	return func(w *Worker) {
+		if d > 0 {
+			w.stuckAfter = d
+		}
+	}
+}
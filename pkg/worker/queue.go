@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("queue is full")
+
+type queuedJob struct {
+	ctx context.Context
+	job Job
+}
+
+// Queue выполняет разовые задачи в фоне фиксированным числом горутин.
+// Очередь ограничена: если она заполнена, Add сразу возвращает ErrQueueFull и не ждет
+type Queue struct {
+	jobs            chan queuedJob
+	jobTimeout      time.Duration
+	shutdownTimeout time.Duration
+	// ctx отменяется, если при остановке задачи не успели выполниться за shutdownTimeout
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// mu защищает закрытие канала jobs от одновременного Add
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewQueue запускает workers горутин, которые разбирают очередь длиной size.
+// Каждая задача выполняется не дольше jobTimeout
+func NewQueue(size, workers int, jobTimeout time.Duration) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:            make(chan queuedJob, size),
+		jobTimeout:      jobTimeout,
+		shutdownTimeout: defaultShutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	q.wg.Add(workers)
+	for range workers {
+		go func() {
+			defer q.wg.Done()
+			for j := range q.jobs {
+				q.run(j)
+			}
+		}()
+	}
+	return q
+}
+
+// Add ставит задачу в очередь. Задача получит контекст без отмены от ctx, чтобы сохранить его значения
+// (логгер, трейсинг), но не зависеть от завершения запроса
+func (q *Queue) Add(ctx context.Context, job Job) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.stopped {
+		return ErrStopped
+	}
+	select {
+	case q.jobs <- queuedJob{ctx: context.WithoutCancel(ctx), job: job}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) run(j queuedJob) {
+	ctx, cancel := context.WithTimeout(j.ctx, q.jobTimeout)
+	defer cancel()
+	stop := context.AfterFunc(q.ctx, cancel)
+	defer stop()
+
+	j.job(ctx)
+}
+
+// Shutdown перестает принимать задачи и ждет, пока выполнятся уже поставленные, не дольше shutdownTimeout.
+// После таймаута контексты оставшихся задач отменяются
+func (q *Queue) Shutdown() error {
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(q.shutdownTimeout):
+		q.cancel()
+		return context.DeadlineExceeded
+	}
+}
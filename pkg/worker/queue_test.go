@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	release := make(chan struct{})
+	var done atomic.Int32
+	job := func(ctx context.Context) {
+		<-release
+		done.Add(1)
+	}
+
+	q := NewQueue(1, 1, time.Second)
+	// первая задача занимает единственную горутину, вторая ждет в очереди
+	require.NoError(t, q.Add(context.Background(), job))
+	require.Eventually(t, func() bool { return len(q.jobs) == 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, q.Add(context.Background(), job))
+	assert.ErrorIs(t, q.Add(context.Background(), job), ErrQueueFull)
+
+	// отмена контекста запроса не отменяет задачу
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	close(release)
+	require.Eventually(t, func() bool { return done.Load() == 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, q.Add(ctx, func(ctx context.Context) {
+		if ctx.Err() == nil {
+			done.Add(1)
+		}
+	}))
+
+	// Shutdown дожидается поставленных задач
+	require.NoError(t, q.Shutdown())
+	assert.Equal(t, int32(3), done.Load())
+	assert.ErrorIs(t, q.Add(context.Background(), job), ErrStopped)
+}
+
+func TestQueue_ShutdownTimeout(t *testing.T) {
+	q := NewQueue(1, 1, time.Hour)
+	q.shutdownTimeout = 20 * time.Millisecond
+
+	canceled := make(chan struct{})
+	require.NoError(t, q.Add(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	}))
+
+	assert.ErrorIs(t, q.Shutdown(), context.DeadlineExceeded)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("job context is not canceled after shutdown timeout")
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultInterval        = time.Minute
+	defaultShutdownTimeout = 10 * time.Second
+	defaultStuckAfter      = 15 * time.Minute
+)
+
+var ErrStopped = errors.New("worker is stopped")
+
+// Job выполняется воркером периодически. Контекст отменяется при остановке воркера
+type Job func(ctx context.Context)
+
+// Worker запускает Job сразу после старта и затем с интервалом interval.
+// Запуски не пересекаются: если Job выполнялся дольше интервала, следующий начнется сразу после него
+type Worker struct {
+	job             Job
+	interval        time.Duration
+	shutdownTimeout time.Duration
+	stuckAfter      time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	// начало текущего запуска Job в UnixNano, 0 - Job сейчас не выполняется
+	running atomic.Int64
+}
+
+func NewWorker(job Job, opts ...Option) *Worker {
+	w := &Worker{
+		job:             job,
+		interval:        defaultInterval,
+		shutdownTimeout: defaultShutdownTimeout,
+		stuckAfter:      defaultStuckAfter,
+		done:            make(chan struct{}),
+	}
+
+	for _, option := range opts {
+		option(w)
+	}
+
+	w.start()
+	return w
+}
+
+func (w *Worker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.running.Store(time.Now().UnixNano())
+			w.job(ctx)
+			w.running.Store(0)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown отменяет контекст текущего запуска и ждет его завершения не дольше shutdownTimeout
+func (w *Worker) Shutdown() error {
+	w.cancel()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-time.After(w.shutdownTimeout):
+		return context.DeadlineExceeded
+	}
+}
+
+// Check возвращает ошибку, если воркер остановлен или текущий запуск Job идет дольше stuckAfter
+func (w *Worker) Check(_ context.Context) error {
+	select {
+	case <-w.done:
+		return ErrStopped
+	default:
+	}
+	if started := w.running.Load(); started != 0 {
+		if d := time.Since(time.Unix(0, started)); d > w.stuckAfter {
+			return fmt.Errorf("job is running for %s, longer than %s", d.Round(time.Second), w.stuckAfter)
+		}
+	}
+	return nil
+}
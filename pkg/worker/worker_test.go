@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWorker_Check(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	w := NewWorker(func(ctx context.Context) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	}, Interval(time.Hour), StuckAfter(50*time.Millisecond))
+
+	<-started
+	assert.NoError(t, w.Check(context.Background()))
+
+	// запуск идет дольше StuckAfter
+	time.Sleep(60 * time.Millisecond)
+	assert.Error(t, w.Check(context.Background()))
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return w.Check(context.Background()) == nil
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, w.Shutdown())
+	assert.ErrorIs(t, w.Check(context.Background()), ErrStopped)
+}